@@ -0,0 +1,70 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock заменяет прямые вызовы time.Now(), чтобы объекты-значения и сервисы,
+// зависящие от времени, можно было проверять с фиксированным временем
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// System - часы операционной системы
+var System Clock = systemClock{}
+
+// Fixed всегда возвращает одно и то же время
+type Fixed time.Time
+
+func (f Fixed) Now() time.Time {
+	return time.Time(f)
+}
+
+// Func превращает функцию в Clock
+type Func func() time.Time
+
+func (f Func) Now() time.Time {
+	return f()
+}
+
+// Manual - часы, время которых переводится вручную, например в тестах таймаутов
+type Manual struct {
+	mutex sync.RWMutex
+	now   time.Time
+}
+
+func NewManual(now time.Time) *Manual {
+	return &Manual{
+		now: now,
+	}
+}
+
+func (m *Manual) Now() time.Time {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.now
+}
+
+// Advance переводит часы вперёд на duration
+func (m *Manual) Advance(duration time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.now = m.now.Add(duration)
+}
+
+// Set устанавливает текущее время
+func (m *Manual) Set(now time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.now = now
+}
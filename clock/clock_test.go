@@ -0,0 +1,36 @@
+package clock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/clock"
+)
+
+func TestManual(t *testing.T) {
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	manual := clock.NewManual(start)
+
+	if !manual.Now().Equal(start) {
+		t.Fatalf("Now() = %v, want %v", manual.Now(), start)
+	}
+	manual.Advance(90 * time.Minute)
+	if want := start.Add(90 * time.Minute); !manual.Now().Equal(want) {
+		t.Fatalf("Now() after Advance = %v, want %v", manual.Now(), want)
+	}
+	manual.Set(start)
+	if !manual.Now().Equal(start) {
+		t.Fatalf("Now() after Set = %v, want %v", manual.Now(), start)
+	}
+}
+
+func TestFixedAndFunc(t *testing.T) {
+	at := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	if now := clock.Fixed(at).Now(); !now.Equal(at) {
+		t.Fatalf("Fixed.Now() = %v", now)
+	}
+	if now := clock.Func(func() time.Time { return at }).Now(); !now.Equal(at) {
+		t.Fatalf("Func.Now() = %v", now)
+	}
+}
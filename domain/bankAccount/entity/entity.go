@@ -2,6 +2,8 @@ package domain
 
 import (
 	"errors"
+	"fmt"
+	value_objects "github.com/MaksimDzhangirov/PracticalDDD/value-objects"
 	"time"
 )
 
@@ -46,6 +48,26 @@ type Currency struct {
 	HtmlCode string
 }
 
+// NewCurrency заполняет поля валюты из справочника валют
+func NewCurrency(ID uint, code string) (Currency, error) {
+	currency, err := value_objects.CurrencyByCode(code)
+	if err != nil {
+		return Currency{}, err
+	}
+
+	htmlCode := currency.Symbol
+	if currency.HTML != 0 {
+		htmlCode = fmt.Sprintf("&#%d;", currency.HTML)
+	}
+
+	return Currency{
+		ID:       ID,
+		Code:     currency.Code,
+		Name:     currency.Name,
+		HtmlCode: htmlCode,
+	}, nil
+}
+
 type Person struct {
 	ID          uint
 	FirstName   string
@@ -1,7 +1,11 @@
 package repository
 
-import "github.com/MaksimDzhangirov/PracticalDDD/domain/order/entity"
+import (
+	"context"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/domain/order/entity"
+)
 
 type OrderRepository interface {
-	Create(order entity.Order) (*entity.Order, error)
+	Create(ctx context.Context, order entity.Order) (*entity.Order, error)
 }
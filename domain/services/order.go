@@ -1,11 +1,13 @@
 package services
 
 import (
+	"context"
+
 	"github.com/MaksimDzhangirov/PracticalDDD/domain/order/entity"
 	value_objects "github.com/MaksimDzhangirov/PracticalDDD/value-objects"
 )
 
 type OrderService interface {
-	Create(order entity.Order) (*entity.Order, error)
-	ChangeAddress(order entity.Order, address value_objects.Address)
+	Create(ctx context.Context, order entity.Order) (*entity.Order, error)
+	ChangeAddress(ctx context.Context, order entity.Order, address value_objects.Address) error
 }
@@ -0,0 +1,105 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/clock"
+)
+
+// BrokerMessage - сообщение брокера с темой и заголовками
+type BrokerMessage struct {
+	Subject string
+	Data    []byte
+	Headers map[string]string
+}
+
+// BrokerSubscription отменяет подписку на брокере
+type BrokerSubscription interface {
+	Unsubscribe() error
+}
+
+// Broker - брокер с темами в стиле NATS ("event.order.*", "event.>") или
+// AMQP topic exchange. Подписчики с одинаковым queueGroup делят сообщения
+// между собой, подписчики без группы получают все сообщения. Реализация
+// в памяти находится в пакете membroker
+type Broker interface {
+	Publish(ctx context.Context, message BrokerMessage) error
+	Subscribe(subject string, queueGroup string, handler func(ctx context.Context, message BrokerMessage) error) (BrokerSubscription, error)
+}
+
+// BrokerTransport публикует конверты событий в тему, совпадающую с именем
+// события, например "event.order.created"
+type BrokerTransport struct {
+	broker     Broker
+	registry   *Registry
+	clock      clock.Clock
+	subject    string
+	queueGroup string
+}
+
+// NewBrokerTransport получает события из тем subject ("event.>") в группе queueGroup
+func NewBrokerTransport(broker Broker, registry *Registry, clock clock.Clock, subject string, queueGroup string) *BrokerTransport {
+	return &BrokerTransport{
+		broker:     broker,
+		registry:   registry,
+		clock:      clock,
+		subject:    subject,
+		queueGroup: queueGroup,
+	}
+}
+
+func (t *BrokerTransport) Publish(ctx context.Context, events ...Event) error {
+	for _, event := range events {
+		envelope, err := t.registry.Seal(Wrap(ctx, event, t.clock))
+		if err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(envelope)
+		if err != nil {
+			return err
+		}
+
+		err = t.broker.Publish(ctx, BrokerMessage{
+			Subject: envelope.Name,
+			Data:    data,
+			Headers: map[string]string{
+				AttributeEventName:     envelope.Name,
+				AttributeSchemaVersion: strconv.Itoa(envelope.SchemaVersion),
+				AttributeAggregateType: envelope.AggregateType,
+				"Content-Type":         "application/json",
+				// Nats-Msg-Id позволяет JetStream отбросить повторную отправку
+				"Nats-Msg-Id": envelope.ID.String(),
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *BrokerTransport) Consume(ctx context.Context, handler Handler) error {
+	subscription, err := t.broker.Subscribe(t.subject, t.queueGroup, func(ctx context.Context, message BrokerMessage) error {
+		var envelope Envelope
+		if err := json.Unmarshal(message.Data, &envelope); err != nil {
+			return err
+		}
+
+		envelope, err := t.registry.Open(envelope)
+		if err != nil {
+			return err
+		}
+
+		return handler.Handle(ContextWithEnvelope(ctx, envelope), envelope.Event)
+	})
+	if err != nil {
+		return err
+	}
+
+	<-ctx.Done()
+	return subscription.Unsubscribe()
+}
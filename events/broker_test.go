@@ -0,0 +1,148 @@
+package events_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/clock"
+	"github.com/MaksimDzhangirov/PracticalDDD/events"
+	"github.com/MaksimDzhangirov/PracticalDDD/events/membroker"
+	"github.com/google/uuid"
+)
+
+// watchedBroker сообщает о каждой подписке, чтобы тест публиковал события
+// только после того, как Consume подписался
+type watchedBroker struct {
+	*membroker.Broker
+	subscribed chan struct{}
+}
+
+func newWatchedBroker() *watchedBroker {
+	return &watchedBroker{Broker: membroker.New(), subscribed: make(chan struct{}, 10)}
+}
+
+func (b *watchedBroker) Subscribe(subject string, queueGroup string, handler func(ctx context.Context, message events.BrokerMessage) error) (events.BrokerSubscription, error) {
+	subscription, err := b.Broker.Subscribe(subject, queueGroup, handler)
+	b.subscribed <- struct{}{}
+	return subscription, err
+}
+
+// startConsumers запускает Consume каждого транспорта и ждёт подписки
+func startConsumers(t *testing.T, broker *watchedBroker, transports []*events.BrokerTransport, handlers []*collector) (stop func()) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, len(transports))
+	for i, transport := range transports {
+		go func(transport *events.BrokerTransport, handler *collector) {
+			done <- transport.Consume(ctx, handler)
+		}(transport, handlers[i])
+	}
+	for range transports {
+		select {
+		case <-broker.subscribed:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Consume did not subscribe")
+		}
+	}
+
+	return func() {
+		cancel()
+		for range transports {
+			if err := <-done; err != nil {
+				t.Error(err)
+			}
+		}
+	}
+}
+
+func TestBrokerTransportDeliversBySubject(t *testing.T) {
+	broker := newWatchedBroker()
+	registry := events.NewDefaultRegistry(events.JSONCodec{})
+	publisher := events.NewBrokerTransport(broker, registry, clock.System, "", "")
+	orders := events.NewBrokerTransport(broker, registry, clock.System, "event.order.*", "")
+	everything := events.NewBrokerTransport(broker, registry, clock.System, "event.>", "")
+
+	orderHandler, allHandler := &collector{}, &collector{}
+	stop := startConsumers(t, broker, []*events.BrokerTransport{orders, everything}, []*collector{orderHandler, allHandler})
+
+	created := events.NewOrderCreated(uuid.New())
+	sent := events.NewEmailSent(uuid.New())
+	// "event.order.delivery.success" не подходит под "event.order.*"
+	delivered := events.NewOrderDelivered(uuid.New())
+	if err := publisher.Publish(context.Background(), created, sent, delivered); err != nil {
+		t.Fatal(err)
+	}
+	stop()
+
+	if received := orderHandler.received(); len(received) != 1 || received[0] != created {
+		t.Fatalf("event.order.* received %v", received)
+	}
+	if received := allHandler.received(); len(received) != 3 || received[1] != sent || received[2] != delivered {
+		t.Fatalf("event.> received %v", received)
+	}
+	if envelope := allHandler.envelopes[0]; envelope.Name != created.Name() || envelope.AggregateID != created.OrderID() {
+		t.Fatalf("envelope = %+v", envelope)
+	}
+
+	// после остановки Consume подписка отменена
+	if err := publisher.Publish(context.Background(), events.NewOrderCreated(uuid.New())); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(allHandler.received()); n != 3 {
+		t.Fatalf("stopped consumer received %d events, want 3", n)
+	}
+}
+
+func TestBrokerTransportQueueGroupSharesEvents(t *testing.T) {
+	broker := newWatchedBroker()
+	registry := events.NewDefaultRegistry(events.JSONCodec{})
+	publisher := events.NewBrokerTransport(broker, registry, clock.System, "", "")
+
+	transports := []*events.BrokerTransport{
+		events.NewBrokerTransport(broker, registry, clock.System, "event.>", "workers"),
+		events.NewBrokerTransport(broker, registry, clock.System, "event.>", "workers"),
+		events.NewBrokerTransport(broker, registry, clock.System, "event.>", ""),
+	}
+	handlers := []*collector{{}, {}, {}}
+	stop := startConsumers(t, broker, transports, handlers)
+
+	for i := 0; i < 10; i++ {
+		if err := publisher.Publish(context.Background(), events.NewOrderCreated(uuid.New())); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stop()
+
+	// воркеры группы делят события поровну, подписчик без группы получает все
+	first, second, all := len(handlers[0].received()), len(handlers[1].received()), len(handlers[2].received())
+	if first != 5 || second != 5 || all != 10 {
+		t.Fatalf("received %d, %d and %d events, want 5, 5 and 10", first, second, all)
+	}
+}
+
+func TestBrokerTransportReturnsHandlerErrors(t *testing.T) {
+	broker := newWatchedBroker()
+	registry := events.NewDefaultRegistry(events.JSONCodec{})
+	publisher := events.NewBrokerTransport(broker, registry, clock.System, "", "")
+	consumer := events.NewBrokerTransport(broker, registry, clock.System, "event.>", "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- consumer.Consume(ctx, events.HandlerFunc(func(ctx context.Context, event events.Event) error {
+			return errHandler
+		}))
+	}()
+	<-broker.subscribed
+
+	// membroker доставляет синхронно и возвращает ошибки обработчиков
+	if err := publisher.Publish(context.Background(), events.NewOrderCreated(uuid.New())); err == nil {
+		t.Fatal("Publish returned nil for a failing handler")
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
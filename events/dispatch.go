@@ -0,0 +1,216 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"log"
+	"runtime"
+	"sync/atomic"
+)
+
+var (
+	ErrQueueFull       = errors.New("event queue is full")
+	ErrPublisherClosed = errors.New("event publisher is shut down")
+)
+
+// DispatchMode определяет, в какой горутине вызываются обработчики
+type DispatchMode int
+
+const (
+	// DispatchSync вызывает обработчики в горутине Notify и возвращает их ошибки
+	DispatchSync DispatchMode = iota
+	// DispatchAsync ставит событие в очередь, которую разбирает пул воркеров;
+	// порядок обработки событий не гарантируется
+	DispatchAsync
+	// DispatchOrdered направляет события одного агрегата в один и тот же воркер,
+	// поэтому они обрабатываются в порядке публикации
+	DispatchOrdered
+)
+
+// Backpressure определяет поведение Notify при заполненной очереди
+type Backpressure int
+
+const (
+	// BackpressureBlock ждёт освобождения места в очереди или отмены ctx
+	BackpressureBlock Backpressure = iota
+	// BackpressureDrop отбрасывает событие и сообщает об этом обработчику ошибок
+	BackpressureDrop
+	// BackpressureError возвращает ErrQueueFull
+	BackpressureError
+)
+
+type PublisherOption func(*EventPublisher)
+
+func WithDispatchMode(mode DispatchMode) PublisherOption {
+	return func(e *EventPublisher) {
+		e.mode = mode
+	}
+}
+
+// WithWorkers задаёт количество воркеров для асинхронных режимов
+func WithWorkers(workers int) PublisherOption {
+	return func(e *EventPublisher) {
+		e.workers = workers
+	}
+}
+
+// WithQueueSize задаёт ёмкость очереди: в режиме DispatchAsync - одной
+// общей очереди всех воркеров, в DispatchOrdered - очереди каждого воркера
+func WithQueueSize(size int) PublisherOption {
+	return func(e *EventPublisher) {
+		e.queueSize = size
+	}
+}
+
+func WithBackpressure(backpressure Backpressure) PublisherOption {
+	return func(e *EventPublisher) {
+		e.backpressure = backpressure
+	}
+}
+
+// WithErrorHandler задаёт получателя ошибок асинхронных обработчиков
+// и отброшенных событий, по умолчанию они пишутся в лог
+func WithErrorHandler(handler func(event Event, err error)) PublisherOption {
+	return func(e *EventPublisher) {
+		e.onError = handler
+	}
+}
+
+// WithOrderingKey задаёт ключ агрегата для DispatchOrdered; события
+// с пустым ключом распределяются по воркерам без гарантии порядка
+func WithOrderingKey(key func(event Event) string) PublisherOption {
+	return func(e *EventPublisher) {
+		e.orderingKey = key
+	}
+}
+
+// orderIDKey упорядочивает события по заказу, к которому они относятся
+func orderIDKey(event Event) string {
+	if orderEvent, ok := event.(OrderEvent); ok {
+		return orderEvent.OrderID().String()
+	}
+	return ""
+}
+
+func logError(event Event, err error) {
+	log.Print(err)
+}
+
+func defaultWorkers() int {
+	return runtime.GOMAXPROCS(0)
+}
+
+type job struct {
+	ctx         context.Context
+	event       Event
+	subscribers []subscriber
+}
+
+func (e *EventPublisher) start() {
+	if e.mode == DispatchSync {
+		return
+	}
+	if e.workers <= 0 {
+		e.workers = defaultWorkers()
+	}
+	if e.queueSize < 0 {
+		e.queueSize = 0
+	}
+
+	// в режиме DispatchAsync все воркеры разбирают одну общую очередь
+	queues := 1
+	if e.mode == DispatchOrdered {
+		queues = e.workers
+	}
+	e.queues = make([]chan job, queues)
+	for i := range e.queues {
+		e.queues[i] = make(chan job, e.queueSize)
+	}
+
+	for i := 0; i < e.workers; i++ {
+		queue := e.queues[i%queues]
+		e.running.Add(1)
+		go func() {
+			defer e.running.Done()
+			for job := range queue {
+				if err := e.dispatch(job.ctx, job.event, job.subscribers); err != nil {
+					e.onError(job.event, err)
+				}
+			}
+		}()
+	}
+}
+
+// enqueue ставит событие в очередь воркера; ctx отвязывается от отмены,
+// потому что обработка продолжается после возврата из Notify
+func (e *EventPublisher) enqueue(ctx context.Context, event Event, subscribers []subscriber) error {
+	queue := e.queues[e.queueIndex(event)]
+	job := job{
+		ctx:         context.WithoutCancel(ctx),
+		event:       event,
+		subscribers: subscribers,
+	}
+
+	switch e.backpressure {
+	case BackpressureBlock:
+		select {
+		case queue <- job:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	default:
+		select {
+		case queue <- job:
+			return nil
+		default:
+		}
+	}
+
+	if e.backpressure == BackpressureDrop {
+		e.onError(event, ErrQueueFull)
+		return nil
+	}
+	return ErrQueueFull
+}
+
+func (e *EventPublisher) queueIndex(event Event) int {
+	if len(e.queues) == 1 {
+		return 0
+	}
+
+	key := e.orderingKey(event)
+	if key == "" {
+		return int(atomic.AddUint64(&e.nextQueue, 1) % uint64(len(e.queues)))
+	}
+
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return int(hash.Sum32() % uint32(len(e.queues)))
+}
+
+// Shutdown перестаёт принимать события и ждёт, пока будут обработаны
+// уже принятые, или отмены ctx
+func (e *EventPublisher) Shutdown(ctx context.Context) error {
+	e.mutex.Lock()
+	if !e.closed {
+		e.closed = true
+		go func() {
+			e.sending.Wait()
+			for _, queue := range e.queues {
+				close(queue)
+			}
+			e.running.Wait()
+			close(e.done)
+		}()
+	}
+	e.mutex.Unlock()
+
+	select {
+	case <-e.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
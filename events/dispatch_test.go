@@ -0,0 +1,279 @@
+package events_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/events"
+	"github.com/google/uuid"
+)
+
+func shutdown(t *testing.T, publisher *events.EventPublisher) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := publisher.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// blockingPublisher - издатель с одним воркером и очередью на одно событие,
+// обработчик которого ждёт release
+func blockingPublisher(t *testing.T, options ...events.PublisherOption) (publisher *events.EventPublisher, started <-chan struct{}, release func()) {
+	t.Helper()
+
+	options = append([]events.PublisherOption{
+		events.WithDispatchMode(events.DispatchAsync),
+		events.WithWorkers(1),
+		events.WithQueueSize(1),
+	}, options...)
+	publisher = events.NewEventPublisher(options...)
+
+	startedCh := make(chan struct{}, 10)
+	releaseCh := make(chan struct{})
+	if _, err := events.Subscribe(publisher, func(ctx context.Context, event events.OrderCreated) error {
+		startedCh <- struct{}{}
+		<-releaseCh
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var once sync.Once
+	return publisher, startedCh, func() {
+		once.Do(func() {
+			close(releaseCh)
+		})
+	}
+}
+
+func TestAsyncDispatchHandlesEveryEventBeforeShutdown(t *testing.T) {
+	publisher := events.NewEventPublisher(events.WithDispatchMode(events.DispatchAsync), events.WithWorkers(4))
+
+	var mutex sync.Mutex
+	handled := 0
+	if _, err := events.Subscribe(publisher, func(ctx context.Context, event events.OrderCreated) error {
+		time.Sleep(time.Millisecond)
+		mutex.Lock()
+		handled++
+		mutex.Unlock()
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 50; i++ {
+		if err := publisher.Notify(context.Background(), events.NewOrderCreated(uuid.New())); err != nil {
+			t.Fatal(err)
+		}
+	}
+	shutdown(t, publisher)
+
+	if handled != 50 {
+		t.Fatalf("%d events were handled before Shutdown returned, want 50", handled)
+	}
+	if err := publisher.Notify(context.Background(), events.NewOrderCreated(uuid.New())); !errors.Is(err, events.ErrPublisherClosed) {
+		t.Fatalf("Notify after Shutdown returned %v", err)
+	}
+}
+
+func TestOrderedDispatchKeepsOrderPerAggregate(t *testing.T) {
+	publisher := events.NewEventPublisher(events.WithDispatchMode(events.DispatchOrdered), events.WithWorkers(4))
+
+	var mutex sync.Mutex
+	received := map[uuid.UUID][]string{}
+	if _, err := events.Subscribe(publisher, func(ctx context.Context, event events.OrderEvent) error {
+		// первое событие обрабатывается дольше остальных
+		if _, ok := event.(events.OrderCreated); ok {
+			time.Sleep(5 * time.Millisecond)
+		}
+		mutex.Lock()
+		received[event.OrderID()] = append(received[event.OrderID()], event.Name())
+		mutex.Unlock()
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	orders := make([]uuid.UUID, 10)
+	for i := range orders {
+		orders[i] = uuid.New()
+	}
+	for _, orderID := range orders {
+		if err := publisher.Notify(context.Background(), events.NewOrderCreated(orderID)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, orderID := range orders {
+		for _, event := range []events.Event{events.NewOrderDispatched(orderID), events.NewOrderDelivered(orderID)} {
+			if err := publisher.Notify(context.Background(), event); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	shutdown(t, publisher)
+
+	want := []string{"event.order.created", "event.order.dispatched", "event.order.delivery.success"}
+	for _, orderID := range orders {
+		got := received[orderID]
+		if len(got) != len(want) {
+			t.Fatalf("order %s received %v", orderID, got)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("order %s received %v, want %v", orderID, got, want)
+			}
+		}
+	}
+}
+
+func TestAsyncHandlerErrorsGoToErrorHandler(t *testing.T) {
+	failure := errors.New("handler failed")
+	reported := make(chan error, 1)
+	publisher := events.NewEventPublisher(
+		events.WithDispatchMode(events.DispatchAsync),
+		events.WithErrorHandler(func(event events.Event, err error) {
+			reported <- err
+		}),
+	)
+	if _, err := events.Subscribe(publisher, func(ctx context.Context, event events.EmailSent) error {
+		return failure
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := publisher.Notify(context.Background(), events.NewEmailSent(uuid.New())); err != nil {
+		t.Fatalf("Notify returned %v, asynchronous errors must not be returned", err)
+	}
+	shutdown(t, publisher)
+
+	if err := <-reported; !errors.Is(err, failure) {
+		t.Fatalf("error handler received %v", err)
+	}
+}
+
+func TestBackpressureError(t *testing.T) {
+	publisher, started, release := blockingPublisher(t, events.WithBackpressure(events.BackpressureError))
+	defer shutdown(t, publisher)
+	defer release()
+
+	// первое событие занимает воркер, второе - очередь
+	if err := publisher.Notify(context.Background(), events.NewOrderCreated(uuid.New())); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	if err := publisher.Notify(context.Background(), events.NewOrderCreated(uuid.New())); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := publisher.Notify(context.Background(), events.NewOrderCreated(uuid.New())); !errors.Is(err, events.ErrQueueFull) {
+		t.Fatalf("Notify on a full queue returned %v", err)
+	}
+}
+
+func TestBackpressureDrop(t *testing.T) {
+	dropped := make(chan error, 1)
+	publisher, started, release := blockingPublisher(t,
+		events.WithBackpressure(events.BackpressureDrop),
+		events.WithErrorHandler(func(event events.Event, err error) {
+			dropped <- err
+		}),
+	)
+	defer shutdown(t, publisher)
+	defer release()
+
+	if err := publisher.Notify(context.Background(), events.NewOrderCreated(uuid.New())); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	if err := publisher.Notify(context.Background(), events.NewOrderCreated(uuid.New())); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := publisher.Notify(context.Background(), events.NewOrderCreated(uuid.New())); err != nil {
+		t.Fatalf("Notify returned %v, dropped events are reported to the error handler", err)
+	}
+	if err := <-dropped; !errors.Is(err, events.ErrQueueFull) {
+		t.Fatalf("error handler received %v", err)
+	}
+}
+
+func TestBackpressureBlock(t *testing.T) {
+	publisher, started, release := blockingPublisher(t, events.WithBackpressure(events.BackpressureBlock))
+	defer shutdown(t, publisher)
+	defer release()
+
+	if err := publisher.Notify(context.Background(), events.NewOrderCreated(uuid.New())); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	if err := publisher.Notify(context.Background(), events.NewOrderCreated(uuid.New())); err != nil {
+		t.Fatal(err)
+	}
+
+	// Notify ждёт места в очереди, пока не отменят ctx
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := publisher.Notify(ctx, events.NewOrderCreated(uuid.New())); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("blocked Notify returned %v", err)
+	}
+
+	// когда воркер освобождается, событие встаёт в очередь
+	result := make(chan error, 1)
+	go func() {
+		result <- publisher.Notify(context.Background(), events.NewOrderCreated(uuid.New()))
+	}()
+	release()
+	if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestShutdownWaitsForInFlightEvents(t *testing.T) {
+	publisher, started, release := blockingPublisher(t)
+
+	if err := publisher.Notify(context.Background(), events.NewOrderCreated(uuid.New())); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := publisher.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown with a busy handler returned %v", err)
+	}
+
+	release()
+	shutdown(t, publisher)
+}
+
+func TestConcurrentSubscribeAndNotify(t *testing.T) {
+	publisher := events.NewEventPublisher()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			subscription, err := events.Subscribe(publisher, func(ctx context.Context, event events.OrderCreated) error {
+				return nil
+			})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			subscription.Unsubscribe()
+		}()
+		go func() {
+			defer wg.Done()
+			if err := publisher.Notify(context.Background(), events.NewOrderCreated(uuid.New())); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
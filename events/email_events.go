@@ -1,7 +1,9 @@
 package events
 
 import (
+	"encoding/json"
 	"fmt"
+
 	"github.com/google/uuid"
 )
 
@@ -14,6 +16,12 @@ type EmailSent struct {
 	emailID uuid.UUID
 }
 
+func NewEmailSent(emailID uuid.UUID) EmailSent {
+	return EmailSent{
+		emailID: emailID,
+	}
+}
+
 func (e EmailSent) Name() string {
 	return "event.email.sent"
 }
@@ -22,6 +30,23 @@ func (e EmailSent) EmailID() uuid.UUID {
 	return e.emailID
 }
 
+type emailPayload struct {
+	EmailID uuid.UUID `json:"email_id"`
+}
+
+func (e EmailSent) MarshalJSON() ([]byte, error) {
+	return json.Marshal(emailPayload{EmailID: e.emailID})
+}
+
+func (e *EmailSent) UnmarshalJSON(data []byte) error {
+	var payload emailPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return err
+	}
+	e.emailID = payload.EmailID
+	return nil
+}
+
 type EmailHandler struct {
 	//
 	// какие-то поля
@@ -0,0 +1,194 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/clock"
+	"github.com/google/uuid"
+)
+
+const (
+	AggregateOrder = "order"
+	AggregateEmail = "email"
+)
+
+// Envelope - событие вместе с метаданными, которые нужны получателям
+// за пределами сервиса: что произошло, когда, с каким агрегатом и почему
+type Envelope struct {
+	ID               uuid.UUID
+	Name             string
+	OccurredAt       time.Time
+	AggregateType    string
+	AggregateID      uuid.UUID
+	AggregateVersion int
+	// CorrelationID общий для всей цепочки событий, вызванных одним запросом
+	CorrelationID uuid.UUID
+	// CausationID - ID события, которое вызвало это событие
+	CausationID   uuid.UUID
+	SchemaVersion int
+	Event         Event
+	// Payload - сериализованное событие, заполняется при чтении конверта
+	// и в Registry.Seal
+	Payload json.RawMessage
+	// ContentType - кодек Payload; пустой, если событие записано в JSON
+	ContentType string
+}
+
+// Versioned реализуют события, у которых сменилась схема данных
+type Versioned interface {
+	SchemaVersion() int
+}
+
+// NewEnvelope создаёт конверт для первого события в цепочке,
+// его CorrelationID совпадает с ID
+func NewEnvelope(event Event, clock clock.Clock) Envelope {
+	id := uuid.New()
+	aggregateType, aggregateID := aggregateOf(event)
+
+	return Envelope{
+		ID:            id,
+		Name:          event.Name(),
+		OccurredAt:    clock.Now().UTC(),
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		CorrelationID: id,
+		SchemaVersion: schemaVersionOf(event),
+		Event:         event,
+	}
+}
+
+// Caused создаёт конверт для события, вызванного событием e
+func (e Envelope) Caused(event Event, clock clock.Clock) Envelope {
+	envelope := NewEnvelope(event, clock)
+	envelope.CorrelationID = e.CorrelationID
+	envelope.CausationID = e.ID
+	return envelope
+}
+
+// WithAggregateVersion возвращает конверт с версией агрегата после события
+func (e Envelope) WithAggregateVersion(version int) Envelope {
+	e.AggregateVersion = version
+	return e
+}
+
+// AggregateEvent реализуют события агрегатов, для которых нет отдельного
+// интерфейса вроде OrderEvent
+type AggregateEvent interface {
+	Event
+	AggregateType() string
+	AggregateID() uuid.UUID
+}
+
+func aggregateOf(event Event) (string, uuid.UUID) {
+	switch actualEvent := event.(type) {
+	case AggregateEvent:
+		return actualEvent.AggregateType(), actualEvent.AggregateID()
+	case OrderEvent:
+		return AggregateOrder, actualEvent.OrderID()
+	case EmailEvent:
+		return AggregateEmail, actualEvent.EmailID()
+	default:
+		return "", uuid.Nil
+	}
+}
+
+type envelopeJSON struct {
+	ID               uuid.UUID       `json:"id"`
+	Name             string          `json:"name"`
+	OccurredAt       time.Time       `json:"occurred_at"`
+	AggregateType    string          `json:"aggregate_type,omitempty"`
+	AggregateID      uuid.UUID       `json:"aggregate_id"`
+	AggregateVersion int             `json:"aggregate_version,omitempty"`
+	CorrelationID    uuid.UUID       `json:"correlation_id"`
+	CausationID      uuid.UUID       `json:"causation_id"`
+	SchemaVersion    int             `json:"schema_version"`
+	ContentType      string          `json:"content_type,omitempty"`
+	Payload          json.RawMessage `json:"payload"`
+}
+
+func (e Envelope) MarshalJSON() ([]byte, error) {
+	// конверт после Registry.Seal уже содержит Payload в нужном кодеке
+	payload := e.Payload
+	if e.Event != nil && e.ContentType == "" {
+		data, err := json.Marshal(e.Event)
+		if err != nil {
+			return nil, err
+		}
+		payload = data
+	}
+
+	return json.Marshal(envelopeJSON{
+		ID:               e.ID,
+		Name:             e.Name,
+		OccurredAt:       e.OccurredAt,
+		AggregateType:    e.AggregateType,
+		AggregateID:      e.AggregateID,
+		AggregateVersion: e.AggregateVersion,
+		CorrelationID:    e.CorrelationID,
+		CausationID:      e.CausationID,
+		SchemaVersion:    e.SchemaVersion,
+		ContentType:      e.ContentType,
+		Payload:          payload,
+	})
+}
+
+// UnmarshalJSON читает метаданные и сохраняет Payload без декодирования,
+// потому что конкретный тип события определяется по Name
+func (e *Envelope) UnmarshalJSON(data []byte) error {
+	var row envelopeJSON
+	if err := json.Unmarshal(data, &row); err != nil {
+		return err
+	}
+
+	*e = Envelope{
+		ID:               row.ID,
+		Name:             row.Name,
+		OccurredAt:       row.OccurredAt,
+		AggregateType:    row.AggregateType,
+		AggregateID:      row.AggregateID,
+		AggregateVersion: row.AggregateVersion,
+		CorrelationID:    row.CorrelationID,
+		CausationID:      row.CausationID,
+		SchemaVersion:    row.SchemaVersion,
+		ContentType:      row.ContentType,
+		Payload:          row.Payload,
+	}
+	return nil
+}
+
+type envelopeKey struct{}
+
+// ContextWithEnvelope сохраняет конверт обрабатываемого события, чтобы
+// события, опубликованные обработчиком, унаследовали CorrelationID
+func ContextWithEnvelope(ctx context.Context, envelope Envelope) context.Context {
+	return context.WithValue(ctx, envelopeKey{}, envelope)
+}
+
+func EnvelopeFromContext(ctx context.Context) (Envelope, bool) {
+	envelope, ok := ctx.Value(envelopeKey{}).(Envelope)
+	return envelope, ok
+}
+
+// EnvelopeOf возвращает конверт из ctx, если в нём доставляется само event
+func EnvelopeOf(ctx context.Context, event Event) (Envelope, bool) {
+	envelope, ok := EnvelopeFromContext(ctx)
+	if !ok || envelope.Name != event.Name() || !reflect.DeepEqual(envelope.Event, event) {
+		return Envelope{}, false
+	}
+	return envelope, true
+}
+
+// Wrap создаёт конверт для события, учитывая конверт из ctx, если он есть.
+// Событие, которое сейчас доставляется, сохраняет свой конверт и ID
+func Wrap(ctx context.Context, event Event, clock clock.Clock) Envelope {
+	if envelope, ok := EnvelopeOf(ctx, event); ok {
+		return envelope
+	}
+	if parent, ok := EnvelopeFromContext(ctx); ok {
+		return parent.Caused(event, clock)
+	}
+	return NewEnvelope(event, clock)
+}
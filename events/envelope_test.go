@@ -0,0 +1,118 @@
+package events_test
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/clock"
+	"github.com/MaksimDzhangirov/PracticalDDD/events"
+	"github.com/google/uuid"
+)
+
+var occurredAt = clock.Fixed(time.Date(2024, time.May, 1, 15, 0, 0, 0, time.FixedZone("MSK", 3*60*60)))
+
+func TestEveryEventFitsInEnvelope(t *testing.T) {
+	orderID, emailID := uuid.New(), uuid.New()
+
+	tests := []struct {
+		event         events.Event
+		aggregateType string
+		aggregateID   uuid.UUID
+	}{
+		{events.NewOrderCreated(orderID), events.AggregateOrder, orderID},
+		{events.NewOrderDispatched(orderID), events.AggregateOrder, orderID},
+		{events.NewOrderDelivered(orderID), events.AggregateOrder, orderID},
+		{events.NewOrderDeliveryFailed(orderID), events.AggregateOrder, orderID},
+		{events.NewDeliveryAddressChanged(orderID), events.AggregateOrder, orderID},
+		{events.NewDeliveryAddressChangeFailed(orderID), events.AggregateOrder, orderID},
+		{events.NewEmailSent(emailID), events.AggregateEmail, emailID},
+		{events.GeneralError("failure"), "", uuid.Nil},
+	}
+	for _, test := range tests {
+		envelope := events.NewEnvelope(test.event, occurredAt)
+
+		if envelope.ID == uuid.Nil || envelope.CorrelationID != envelope.ID || envelope.CausationID != uuid.Nil {
+			t.Fatalf("%s: IDs = %+v", test.event.Name(), envelope)
+		}
+		if envelope.Name != test.event.Name() || envelope.AggregateType != test.aggregateType || envelope.AggregateID != test.aggregateID {
+			t.Fatalf("%s: aggregate = %s %s", test.event.Name(), envelope.AggregateType, envelope.AggregateID)
+		}
+		if !envelope.OccurredAt.Equal(occurredAt.Now()) || envelope.OccurredAt.Location() != time.UTC {
+			t.Fatalf("%s: OccurredAt = %v, want the clock time in UTC", test.event.Name(), envelope.OccurredAt)
+		}
+		if envelope.SchemaVersion != 1 {
+			t.Fatalf("%s: SchemaVersion = %d, want 1", test.event.Name(), envelope.SchemaVersion)
+		}
+
+		// полезная нагрузка сериализуется вместе с метаданными
+		data, err := json.Marshal(envelope.WithAggregateVersion(3))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded events.Envelope
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		payload, err := json.Marshal(test.event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(decoded.Payload) != string(payload) {
+			t.Fatalf("%s: payload = %s, want %s", test.event.Name(), decoded.Payload, payload)
+		}
+		decoded.Payload, envelope.Event = nil, nil
+		envelope.AggregateVersion = 3
+		if !decoded.OccurredAt.Equal(envelope.OccurredAt) {
+			t.Fatalf("%s: OccurredAt = %v", test.event.Name(), decoded.OccurredAt)
+		}
+		decoded.OccurredAt = envelope.OccurredAt
+		if !reflect.DeepEqual(decoded, envelope) {
+			t.Fatalf("%s: decoded %+v, want %+v", test.event.Name(), decoded, envelope)
+		}
+	}
+}
+
+func TestCausedKeepsCorrelation(t *testing.T) {
+	orderID := uuid.New()
+	created := events.NewEnvelope(events.NewOrderCreated(orderID), occurredAt)
+	dispatched := created.Caused(events.NewOrderDispatched(orderID), occurredAt)
+	delivered := dispatched.Caused(events.NewOrderDelivered(orderID), occurredAt)
+
+	if dispatched.CorrelationID != created.ID || dispatched.CausationID != created.ID {
+		t.Fatalf("dispatched = %+v", dispatched)
+	}
+	if delivered.CorrelationID != created.ID || delivered.CausationID != dispatched.ID {
+		t.Fatalf("delivered = %+v", delivered)
+	}
+	if delivered.ID == dispatched.ID {
+		t.Fatal("caused envelope reused the parent ID")
+	}
+}
+
+func TestWrapUsesEnvelopeFromContext(t *testing.T) {
+	orderID := uuid.New()
+	created := events.NewOrderCreated(orderID)
+
+	fresh := events.Wrap(context.Background(), created, occurredAt)
+	if fresh.CorrelationID != fresh.ID {
+		t.Fatalf("Wrap without context = %+v", fresh)
+	}
+
+	// доставляемое событие сохраняет свой конверт
+	ctx := events.ContextWithEnvelope(context.Background(), fresh)
+	if again := events.Wrap(ctx, created, occurredAt); again.ID != fresh.ID {
+		t.Fatalf("Wrap of the delivered event = %s, want %s", again.ID, fresh.ID)
+	}
+	if _, ok := events.EnvelopeOf(ctx, events.NewOrderCreated(uuid.New())); ok {
+		t.Fatal("EnvelopeOf matched another event with the same name")
+	}
+
+	// событие, опубликованное обработчиком, становится следствием
+	caused := events.Wrap(ctx, events.NewOrderDispatched(orderID), occurredAt)
+	if caused.ID == fresh.ID || caused.CorrelationID != fresh.ID || caused.CausationID != fresh.ID {
+		t.Fatalf("Wrap of a new event = %+v", caused)
+	}
+}
@@ -1,6 +1,10 @@
 package events
 
-import "github.com/google/uuid"
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
 
 // Интерфейс Event для описания События предметной области
 type Event interface {
@@ -24,11 +28,31 @@ type OrderEvent interface {
 	OrderID() uuid.UUID
 }
 
+// orderPayload - данные событий заказа в сериализованном виде
+type orderPayload struct {
+	OrderID uuid.UUID `json:"order_id"`
+}
+
+func unmarshalOrderPayload(data []byte, orderID *uuid.UUID) error {
+	var payload orderPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return err
+	}
+	*orderID = payload.OrderID
+	return nil
+}
+
 // Событие OrderDispatched
 type OrderDispatched struct {
 	orderID uuid.UUID
 }
 
+func NewOrderDispatched(orderID uuid.UUID) OrderDispatched {
+	return OrderDispatched{
+		orderID: orderID,
+	}
+}
+
 func (e OrderDispatched) Name() string {
 	return "event.order.dispatched"
 }
@@ -37,11 +61,25 @@ func (e OrderDispatched) OrderID() uuid.UUID {
 	return e.orderID
 }
 
+func (e OrderDispatched) MarshalJSON() ([]byte, error) {
+	return json.Marshal(orderPayload{OrderID: e.orderID})
+}
+
+func (e *OrderDispatched) UnmarshalJSON(data []byte) error {
+	return unmarshalOrderPayload(data, &e.orderID)
+}
+
 // Событие OrderDelivered
 type OrderDelivered struct {
 	orderID uuid.UUID
 }
 
+func NewOrderDelivered(orderID uuid.UUID) OrderDelivered {
+	return OrderDelivered{
+		orderID: orderID,
+	}
+}
+
 func (e OrderDelivered) Name() string {
 	return "event.order.delivery.success"
 }
@@ -50,11 +88,25 @@ func (e OrderDelivered) OrderID() uuid.UUID {
 	return e.orderID
 }
 
+func (e OrderDelivered) MarshalJSON() ([]byte, error) {
+	return json.Marshal(orderPayload{OrderID: e.orderID})
+}
+
+func (e *OrderDelivered) UnmarshalJSON(data []byte) error {
+	return unmarshalOrderPayload(data, &e.orderID)
+}
+
 // Событие OrderDeliveryFailed
 type OrderDeliveryFailed struct {
 	orderID uuid.UUID
 }
 
+func NewOrderDeliveryFailed(orderID uuid.UUID) OrderDeliveryFailed {
+	return OrderDeliveryFailed{
+		orderID: orderID,
+	}
+}
+
 func (e OrderDeliveryFailed) Name() string {
 	return "event.order.delivery.failed"
 }
@@ -63,6 +115,14 @@ func (e OrderDeliveryFailed) OrderID() uuid.UUID {
 	return e.orderID
 }
 
+func (e OrderDeliveryFailed) MarshalJSON() ([]byte, error) {
+	return json.Marshal(orderPayload{OrderID: e.orderID})
+}
+
+func (e *OrderDeliveryFailed) UnmarshalJSON(data []byte) error {
+	return unmarshalOrderPayload(data, &e.orderID)
+}
+
 type DeliveryAddressChangeFailed struct {
 	orderID uuid.UUID
 }
@@ -81,6 +141,14 @@ func (e DeliveryAddressChangeFailed) OrderID() uuid.UUID {
 	return e.orderID
 }
 
+func (e DeliveryAddressChangeFailed) MarshalJSON() ([]byte, error) {
+	return json.Marshal(orderPayload{OrderID: e.orderID})
+}
+
+func (e *DeliveryAddressChangeFailed) UnmarshalJSON(data []byte) error {
+	return unmarshalOrderPayload(data, &e.orderID)
+}
+
 type DeliveryAddressChanged struct {
 	orderID uuid.UUID
 }
@@ -92,13 +160,21 @@ func NewDeliveryAddressChanged(orderID uuid.UUID) DeliveryAddressChanged {
 }
 
 func (e DeliveryAddressChanged) Name() string {
-	return "event.order.delivery-address-change.failed"
+	return "event.order.delivery-address-change.success"
 }
 
 func (e DeliveryAddressChanged) OrderID() uuid.UUID {
 	return e.orderID
 }
 
+func (e DeliveryAddressChanged) MarshalJSON() ([]byte, error) {
+	return json.Marshal(orderPayload{OrderID: e.orderID})
+}
+
+func (e *DeliveryAddressChanged) UnmarshalJSON(data []byte) error {
+	return unmarshalOrderPayload(data, &e.orderID)
+}
+
 type OrderCreated struct {
 	orderID uuid.UUID
 }
@@ -110,9 +186,17 @@ func NewOrderCreated(orderID uuid.UUID) OrderCreated {
 }
 
 func (e OrderCreated) Name() string {
-	return "event.order.dispatched"
+	return "event.order.created"
 }
 
 func (e OrderCreated) OrderID() uuid.UUID {
 	return e.orderID
-}
\ No newline at end of file
+}
+
+func (e OrderCreated) MarshalJSON() ([]byte, error) {
+	return json.Marshal(orderPayload{OrderID: e.orderID})
+}
+
+func (e *OrderCreated) UnmarshalJSON(data []byte) error {
+	return unmarshalOrderPayload(data, &e.orderID)
+}
@@ -1,28 +1,301 @@
 package events
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/clock"
+)
+
+var ErrDuplicateEventName = errors.New("event name is already used by another type")
+
 // Интерфейс EventHandler, описывающий любой объект, который должен быть
 // уведомлен о каком-либо Event
 type EventHandler interface {
 	Notify(event Event)
 }
 
-// EventPublisher - основная структура, уведомляющая все EventHandler
+// Notifier публикует события, его реализуют EventPublisher и outbox.Outbox
+type Notifier interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+// subscriber - подписчик на события с именем name; подписчик без имени
+// получает все события, которые принимает matches
+type subscriber struct {
+	id      uint64
+	name    string
+	matches func(event Event) bool
+	handle  func(ctx context.Context, event Event) error
+}
+
+// EventPublisher - основная структура, уведомляющая подписчиков о событиях.
+// Каждое имя события принадлежит ровно одному типу, поэтому обработчик,
+// подписанный через Subscribe[T], всегда получает значение типа T
 type EventPublisher struct {
-	handlers map[string][]EventHandler
+	mutex     sync.RWMutex
+	types     map[string]reflect.Type
+	handlers  map[string][]subscriber
+	wildcards []subscriber
+	lastID    uint64
+
+	mode         DispatchMode
+	workers      int
+	queueSize    int
+	backpressure Backpressure
+	onError      func(event Event, err error)
+	orderingKey  func(event Event) string
+
+	retry         RetryPolicy
+	deadLetter    DeadLetterSink
+	recoverPanics bool
+	clock         clock.Clock
+
+	queues    []chan job
+	nextQueue uint64
+	closed    bool
+	sending   sync.WaitGroup
+	running   sync.WaitGroup
+	done      chan struct{}
 }
 
-// Метод Subscribe подписывает EventHandler на определённое событие (Event)
-func (e *EventPublisher) Subscribe(handler EventHandler, events ...Event) {
+// NewEventPublisher по умолчанию вызывает обработчики синхронно
+func NewEventPublisher(options ...PublisherOption) *EventPublisher {
+	publisher := &EventPublisher{
+		types:       map[string]reflect.Type{},
+		handlers:    map[string][]subscriber{},
+		queueSize:   100,
+		onError:     logError,
+		orderingKey: orderIDKey,
+		clock:       clock.System,
+		done:        make(chan struct{}),
+	}
+	for _, option := range options {
+		option(publisher)
+	}
+	publisher.start()
+
+	return publisher
+}
+
+// Subscription позволяет отменить подписку
+type Subscription struct {
+	publisher *EventPublisher
+	ids       []uint64
+	once      sync.Once
+}
+
+// Unsubscribe отменяет подписку, повторный вызов ничего не делает
+func (s *Subscription) Unsubscribe() {
+	s.once.Do(func() {
+		s.publisher.unsubscribe(s.ids...)
+	})
+}
+
+// Subscribe подписывает типизированный обработчик на события типа T.
+// Если T - интерфейс, например OrderEvent, обработчик получает все
+// события, которые его реализуют
+func Subscribe[T Event](publisher *EventPublisher, handler func(ctx context.Context, event T) error) (*Subscription, error) {
+	handle := func(ctx context.Context, event Event) error {
+		return handler(ctx, event.(T))
+	}
+
+	eventType := reflect.TypeOf((*T)(nil)).Elem()
+	if eventType.Kind() == reflect.Interface {
+		return publisher.subscribe(subscriber{
+			matches: func(event Event) bool {
+				_, ok := event.(T)
+				return ok
+			},
+			handle: handle,
+		})
+	}
+
+	event := zeroEvent(eventType)
+	if err := publisher.Register(event); err != nil {
+		return nil, err
+	}
+
+	return publisher.subscribe(subscriber{
+		name:   event.Name(),
+		handle: handle,
+	})
+}
+
+// Метод Register закрепляет имена событий за их типами; событие другого
+// типа с тем же именем вызовет ErrDuplicateEventName
+func (e *EventPublisher) Register(events ...Event) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
 	for _, event := range events {
-		handlers := e.handlers[event.Name()]
-		handlers = append(handlers, handler)
-		e.handlers[event.Name()] = handlers
+		if err := e.register(event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Метод Subscribe подписывает EventHandler на определённые события (Event)
+func (e *EventPublisher) Subscribe(handler EventHandler, events ...Event) (*Subscription, error) {
+	return e.SubscribeHandler(Adapt(handler), events...)
+}
+
+// Метод SubscribeHandler подписывает Handler на определённые события (Event)
+func (e *EventPublisher) SubscribeHandler(handler Handler, events ...Event) (*Subscription, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	subscription := &Subscription{publisher: e}
+	for _, event := range events {
+		if err := e.register(event); err != nil {
+			e.remove(subscription.ids...)
+			return nil, err
+		}
+
+		e.lastID++
+		e.handlers[event.Name()] = append(e.handlers[event.Name()], subscriber{
+			id:     e.lastID,
+			name:   event.Name(),
+			handle: handler.Handle,
+		})
+		subscription.ids = append(subscription.ids, e.lastID)
+	}
+
+	return subscription, nil
+}
+
+// Метод Notify уведомляет подписчиков о том, что произошло определенное событие (Event).
+// В режиме DispatchSync вызываются все обработчики, даже если часть из них
+// вернула ошибку, и ошибки объединяются через errors.Join. В асинхронных
+// режимах Notify возвращает только ошибки постановки в очередь
+func (e *EventPublisher) Notify(ctx context.Context, event Event) error {
+	e.mutex.Lock()
+	if e.closed {
+		e.mutex.Unlock()
+		return ErrPublisherClosed
+	}
+	if err := e.register(event); err != nil {
+		e.mutex.Unlock()
+		return err
+	}
+	subscribers := e.subscribersOf(event)
+	e.sending.Add(1)
+	e.mutex.Unlock()
+	defer e.sending.Done()
+
+	// все обработчики и их повторы видят один конверт, поэтому, например,
+	// EventSQSHandler отправляет повтор с тем же MessageDeduplicationId
+	ctx = ContextWithEnvelope(ctx, Wrap(ctx, event, e.clock))
+
+	if e.mode == DispatchSync {
+		return e.dispatch(ctx, event, subscribers)
+	}
+	return e.enqueue(ctx, event, subscribers)
+}
+
+func (e *EventPublisher) dispatch(ctx context.Context, event Event, subscribers []subscriber) error {
+	var errs []error
+	for _, subscriber := range subscribers {
+		if err := e.invoke(ctx, subscriber, event); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", event.Name(), err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (e *EventPublisher) register(event Event) error {
+	eventType := reflect.TypeOf(event)
+
+	registered, ok := e.types[event.Name()]
+	if !ok {
+		e.types[event.Name()] = eventType
+		return nil
+	}
+	if registered != eventType {
+		return fmt.Errorf("%w: %s is %s, not %s", ErrDuplicateEventName, event.Name(), registered, eventType)
 	}
+
+	return nil
 }
 
-// Метод Notify уведомляет подписанный EventHandler о том, что произошло определенное событие (Event)
-func (e *EventPublisher) Notify(event Event) {
-	for _, handler := range e.handlers[event.Name()] {
-		handler.Notify(event)
+func (e *EventPublisher) subscribersOf(event Event) []subscriber {
+	named := e.handlers[event.Name()]
+
+	result := make([]subscriber, 0, len(named)+len(e.wildcards))
+	result = append(result, named...)
+	for _, wildcard := range e.wildcards {
+		if wildcard.matches(event) {
+			result = append(result, wildcard)
+		}
 	}
-}
\ No newline at end of file
+
+	return result
+}
+
+func (e *EventPublisher) subscribe(s subscriber) (*Subscription, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.lastID++
+	s.id = e.lastID
+	if s.name == "" {
+		e.wildcards = append(e.wildcards, s)
+	} else {
+		e.handlers[s.name] = append(e.handlers[s.name], s)
+	}
+
+	return &Subscription{publisher: e, ids: []uint64{s.id}}, nil
+}
+
+func (e *EventPublisher) unsubscribe(ids ...uint64) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.remove(ids...)
+}
+
+func (e *EventPublisher) remove(ids ...uint64) {
+	removed := make(map[uint64]bool, len(ids))
+	for _, id := range ids {
+		removed[id] = true
+	}
+	keep := func(subscribers []subscriber) []subscriber {
+		result := subscribers[:0:0]
+		for _, s := range subscribers {
+			if !removed[s.id] {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+
+	for name, subscribers := range e.handlers {
+		e.handlers[name] = keep(subscribers)
+	}
+	e.wildcards = keep(e.wildcards)
+}
+
+// EventName возвращает имя событий типа T. У интерфейса, например
+// OrderEvent, общего имени нет, и тогда ok равно false
+func EventName[T Event]() (name string, ok bool) {
+	eventType := reflect.TypeOf((*T)(nil)).Elem()
+	if eventType.Kind() == reflect.Interface {
+		return "", false
+	}
+	return zeroEvent(eventType).Name(), true
+}
+
+// zeroEvent создаёт пустое событие типа eventType, чтобы узнать его имя;
+// для указателей создаётся значение, на которое они указывают
+func zeroEvent(eventType reflect.Type) Event {
+	if eventType.Kind() == reflect.Ptr {
+		return reflect.New(eventType.Elem()).Interface().(Event)
+	}
+	return reflect.Zero(eventType).Interface().(Event)
+}
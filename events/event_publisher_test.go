@@ -0,0 +1,153 @@
+package events_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/events"
+	"github.com/google/uuid"
+)
+
+// impostor использует имя OrderCreated, но это другой тип
+type impostor struct{}
+
+func (impostor) Name() string {
+	return "event.order.created"
+}
+
+func TestSubscribeDeliversTypedEvents(t *testing.T) {
+	publisher := events.NewEventPublisher()
+	orderID := uuid.New()
+
+	var created []events.OrderCreated
+	if _, err := events.Subscribe(publisher, func(ctx context.Context, event events.OrderCreated) error {
+		created = append(created, event)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// подписка на интерфейс получает все события заказа
+	var orderEvents []events.OrderEvent
+	if _, err := events.Subscribe(publisher, func(ctx context.Context, event events.OrderEvent) error {
+		orderEvents = append(orderEvents, event)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, event := range []events.Event{
+		events.NewOrderCreated(orderID),
+		events.NewOrderDispatched(orderID),
+		events.NewEmailSent(uuid.New()),
+	} {
+		if err := publisher.Notify(context.Background(), event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(created) != 1 || created[0].OrderID() != orderID {
+		t.Fatalf("OrderCreated handler received %v", created)
+	}
+	if len(orderEvents) != 2 || orderEvents[1].Name() != "event.order.dispatched" {
+		t.Fatalf("OrderEvent handler received %v", orderEvents)
+	}
+}
+
+func TestDuplicateEventNamesAreRejected(t *testing.T) {
+	publisher := events.NewEventPublisher()
+	if err := publisher.Register(events.OrderCreated{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := publisher.Register(impostor{}); !errors.Is(err, events.ErrDuplicateEventName) {
+		t.Fatalf("Register returned %v", err)
+	}
+	if _, err := events.Subscribe(publisher, func(ctx context.Context, event impostor) error {
+		return nil
+	}); !errors.Is(err, events.ErrDuplicateEventName) {
+		t.Fatalf("Subscribe returned %v", err)
+	}
+	if err := publisher.Notify(context.Background(), impostor{}); !errors.Is(err, events.ErrDuplicateEventName) {
+		t.Fatalf("Notify returned %v", err)
+	}
+
+	// неудачная подписка не оставляет обработчиков для уже проверенных событий
+	calls := 0
+	_, err := publisher.SubscribeHandler(events.HandlerFunc(func(ctx context.Context, event events.Event) error {
+		calls++
+		return nil
+	}), events.OrderDispatched{}, impostor{})
+	if !errors.Is(err, events.ErrDuplicateEventName) {
+		t.Fatalf("SubscribeHandler returned %v", err)
+	}
+	if err := publisher.Notify(context.Background(), events.NewOrderDispatched(uuid.New())); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 0 {
+		t.Fatalf("handler of the failed subscription was called %d times", calls)
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	publisher := events.NewEventPublisher()
+
+	calls := 0
+	subscription, err := events.Subscribe(publisher, func(ctx context.Context, event events.EmailSent) error {
+		calls++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := &events.EmailHandler{}
+	if _, err := publisher.Subscribe(handler, events.EmailSent{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := publisher.Notify(context.Background(), events.NewEmailSent(uuid.New())); err != nil {
+		t.Fatal(err)
+	}
+	subscription.Unsubscribe()
+	subscription.Unsubscribe()
+	if err := publisher.Notify(context.Background(), events.NewEmailSent(uuid.New())); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls != 1 {
+		t.Fatalf("handler was called %d times, want 1", calls)
+	}
+}
+
+func TestNotifyCollectsHandlerErrors(t *testing.T) {
+	publisher := events.NewEventPublisher()
+	errFirst, errSecond := errors.New("first"), errors.New("second")
+
+	calls := 0
+	for _, result := range []error{errFirst, nil, errSecond} {
+		result := result
+		if _, err := events.Subscribe(publisher, func(ctx context.Context, event events.OrderDelivered) error {
+			calls++
+			return result
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := publisher.Notify(context.Background(), events.NewOrderDelivered(uuid.New()))
+	if !errors.Is(err, errFirst) || !errors.Is(err, errSecond) {
+		t.Fatalf("Notify returned %v, want both errors", err)
+	}
+	if calls != 3 {
+		t.Fatalf("%d handlers were called, want 3", calls)
+	}
+}
+
+func TestEventName(t *testing.T) {
+	if name, ok := events.EventName[events.OrderDelivered](); !ok || name != "event.order.delivery.success" {
+		t.Fatalf("EventName[OrderDelivered] = %q, %v", name, ok)
+	}
+	if _, ok := events.EventName[events.OrderEvent](); ok {
+		t.Fatal("interface has an event name")
+	}
+}
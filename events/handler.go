@@ -0,0 +1,215 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"runtime/debug"
+	"time"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/clock"
+)
+
+var ErrHandlerPanic = errors.New("event handler panicked")
+
+// Handler - обработчик событий, который получает контекст и сообщает об ошибках.
+// Старые EventHandler подключаются через Adapt
+type Handler interface {
+	Handle(ctx context.Context, event Event) error
+}
+
+type HandlerFunc func(ctx context.Context, event Event) error
+
+func (f HandlerFunc) Handle(ctx context.Context, event Event) error {
+	return f(ctx, event)
+}
+
+// Adapt позволяет подписать EventHandler, например EmailHandler, как Handler
+func Adapt(handler EventHandler) Handler {
+	return HandlerFunc(func(ctx context.Context, event Event) error {
+		handler.Notify(event)
+		return nil
+	})
+}
+
+// PanicError - паника обработчика, превращённая в ошибку
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("%s: %v", ErrHandlerPanic, e.Value)
+}
+
+func (e *PanicError) Unwrap() error {
+	return ErrHandlerPanic
+}
+
+// DeadLetter - событие, которое обработчик не смог обработать после всех попыток
+type DeadLetter struct {
+	Event    Event
+	Err      error
+	Attempts int
+	FailedAt time.Time
+}
+
+// DeadLetterSink сохраняет необработанные события, например в очередь SQS
+type DeadLetterSink interface {
+	DeadLetter(ctx context.Context, letter DeadLetter) error
+}
+
+type DeadLetterFunc func(ctx context.Context, letter DeadLetter) error
+
+func (f DeadLetterFunc) DeadLetter(ctx context.Context, letter DeadLetter) error {
+	return f(ctx, letter)
+}
+
+// RetryPolicy - повтор с экспоненциальной задержкой: Initial, 2*Initial, ... но не больше Max
+type RetryPolicy struct {
+	Attempts int
+	// Initial по умолчанию defaultRetryDelay
+	Initial time.Duration
+	Max     time.Duration
+}
+
+const defaultRetryDelay = 100 * time.Millisecond
+
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	delay := p.Initial
+	if delay <= 0 {
+		delay = defaultRetryDelay
+	}
+	limit := p.Max
+	if limit <= 0 {
+		limit = time.Duration(math.MaxInt64)
+	}
+
+	// удваиваем, пока не упрёмся в limit, чтобы сдвиг не переполнил Duration
+	for i := 1; i < attempt && delay < limit; i++ {
+		if delay > limit/2 {
+			delay = limit
+			break
+		}
+		delay *= 2
+	}
+	if delay > limit {
+		return limit
+	}
+	return delay
+}
+
+// WithRetry повторяет вызов обработчика, вернувшего ошибку
+func WithRetry(policy RetryPolicy) PublisherOption {
+	return func(e *EventPublisher) {
+		e.retry = policy
+	}
+}
+
+// WithDeadLetter отправляет в sink события, обработка которых не удалась
+// после всех повторов; такая ошибка не возвращается из Notify
+func WithDeadLetter(sink DeadLetterSink) PublisherOption {
+	return func(e *EventPublisher) {
+		e.deadLetter = sink
+	}
+}
+
+// WithClock задаёт часы, по которым отмечается время ошибки в DeadLetter
+func WithClock(clock clock.Clock) PublisherOption {
+	return func(e *EventPublisher) {
+		e.clock = clock
+	}
+}
+
+// WithPanicRecovery превращает панику обработчика в *PanicError
+// и публикует событие GeneralError. Паника не повторяется по RetryPolicy,
+// событие сразу отправляется в DeadLetterSink
+func WithPanicRecovery() PublisherOption {
+	return func(e *EventPublisher) {
+		e.recoverPanics = true
+	}
+}
+
+// invoke вызывает обработчик с учётом политик издателя
+func (e *EventPublisher) invoke(ctx context.Context, subscriber subscriber, event Event) error {
+	attempts := e.retry.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	var panicErr *PanicError
+	attempt := 1
+	for ; ; attempt++ {
+		if err = e.call(ctx, subscriber, event); err == nil {
+			return nil
+		}
+		// паника не повторяется: обработчик почти наверняка упадёт снова,
+		// поэтому событие сразу уходит в DeadLetterSink
+		if errors.As(err, &panicErr) || attempt == attempts {
+			break
+		}
+
+		timer := time.NewTimer(e.retry.delay(attempt))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		}
+	}
+
+	if panicErr != nil {
+		e.reportPanic(ctx, event, err)
+	}
+
+	if e.deadLetter == nil {
+		return err
+	}
+	letter := DeadLetter{
+		Event:    event,
+		Err:      err,
+		Attempts: attempt,
+		FailedAt: e.clock.Now(),
+	}
+	if dlErr := e.deadLetter.DeadLetter(ctx, letter); dlErr != nil {
+		return errors.Join(err, dlErr)
+	}
+
+	return nil
+}
+
+func (e *EventPublisher) call(ctx context.Context, subscriber subscriber, event Event) (err error) {
+	if e.recoverPanics {
+		defer func() {
+			if value := recover(); value != nil {
+				err = &PanicError{Value: value, Stack: debug.Stack()}
+			}
+		}()
+	}
+
+	return subscriber.handle(ctx, event)
+}
+
+// reportPanic сразу уведомляет подписчиков GeneralError, минуя очередь,
+// чтобы воркер не блокировался на собственной очереди
+func (e *EventPublisher) reportPanic(ctx context.Context, event Event, err error) {
+	if _, ok := event.(GeneralError); ok {
+		return
+	}
+
+	general := NewGeneralError(err)
+	e.mutex.Lock()
+	registerErr := e.register(general)
+	subscribers := e.subscribersOf(general)
+	e.mutex.Unlock()
+	if registerErr != nil {
+		e.onError(general, registerErr)
+		return
+	}
+
+	if err := e.dispatch(ctx, general, subscribers); err != nil {
+		e.onError(general, err)
+	}
+}
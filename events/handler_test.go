@@ -0,0 +1,185 @@
+package events_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/clock"
+	"github.com/MaksimDzhangirov/PracticalDDD/events"
+	"github.com/google/uuid"
+)
+
+var errHandler = errors.New("handler failed")
+
+// failing возвращает обработчик, который падает failures раз, а затем работает
+func failing(failures int, calls *int) func(ctx context.Context, event events.OrderDelivered) error {
+	return func(ctx context.Context, event events.OrderDelivered) error {
+		*calls++
+		if *calls <= failures {
+			return errHandler
+		}
+		return nil
+	}
+}
+
+func deadLetters(letters *[]events.DeadLetter) events.DeadLetterSink {
+	return events.DeadLetterFunc(func(ctx context.Context, letter events.DeadLetter) error {
+		*letters = append(*letters, letter)
+		return nil
+	})
+}
+
+func TestRetryRecoversFromTransientErrors(t *testing.T) {
+	publisher := events.NewEventPublisher(events.WithRetry(events.RetryPolicy{Attempts: 3, Initial: time.Millisecond}))
+
+	calls := 0
+	if _, err := events.Subscribe(publisher, failing(2, &calls)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := publisher.Notify(context.Background(), events.NewOrderDelivered(uuid.New())); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Fatalf("handler was called %d times, want 3", calls)
+	}
+}
+
+func TestRetryReturnsLastErrorWithoutDeadLetter(t *testing.T) {
+	publisher := events.NewEventPublisher(events.WithRetry(events.RetryPolicy{Attempts: 2, Initial: time.Millisecond}))
+
+	calls := 0
+	if _, err := events.Subscribe(publisher, failing(10, &calls)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := publisher.Notify(context.Background(), events.NewOrderDelivered(uuid.New())); !errors.Is(err, errHandler) {
+		t.Fatalf("Notify returned %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("handler was called %d times, want 2", calls)
+	}
+}
+
+func TestExhaustedRetriesGoToDeadLetter(t *testing.T) {
+	failedAt := time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)
+	var letters []events.DeadLetter
+	publisher := events.NewEventPublisher(
+		events.WithRetry(events.RetryPolicy{Attempts: 3, Initial: time.Millisecond, Max: 2 * time.Millisecond}),
+		events.WithDeadLetter(deadLetters(&letters)),
+		events.WithClock(clock.Fixed(failedAt)),
+	)
+
+	calls := 0
+	if _, err := events.Subscribe(publisher, failing(10, &calls)); err != nil {
+		t.Fatal(err)
+	}
+
+	event := events.NewOrderDelivered(uuid.New())
+	if err := publisher.Notify(context.Background(), event); err != nil {
+		t.Fatalf("Notify returned %v, dead-lettered errors must not be returned", err)
+	}
+	if len(letters) != 1 {
+		t.Fatalf("%d dead letters, want 1", len(letters))
+	}
+	letter := letters[0]
+	if letter.Event != event || !errors.Is(letter.Err, errHandler) || letter.Attempts != 3 || !letter.FailedAt.Equal(failedAt) {
+		t.Fatalf("dead letter = %+v", letter)
+	}
+}
+
+func TestDeadLetterErrorsAreReturned(t *testing.T) {
+	sinkErr := errors.New("sink is down")
+	publisher := events.NewEventPublisher(events.WithDeadLetter(events.DeadLetterFunc(func(ctx context.Context, letter events.DeadLetter) error {
+		return sinkErr
+	})))
+
+	calls := 0
+	if _, err := events.Subscribe(publisher, failing(10, &calls)); err != nil {
+		t.Fatal(err)
+	}
+
+	err := publisher.Notify(context.Background(), events.NewOrderDelivered(uuid.New()))
+	if !errors.Is(err, errHandler) || !errors.Is(err, sinkErr) {
+		t.Fatalf("Notify returned %v, want both errors", err)
+	}
+}
+
+func TestRetryStopsWhenContextIsCancelled(t *testing.T) {
+	publisher := events.NewEventPublisher(events.WithRetry(events.RetryPolicy{Attempts: 3, Initial: time.Hour}))
+
+	calls := 0
+	if _, err := events.Subscribe(publisher, failing(10, &calls)); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := publisher.Notify(ctx, events.NewOrderDelivered(uuid.New()))
+	if !errors.Is(err, errHandler) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Notify returned %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("handler was called %d times, want 1", calls)
+	}
+}
+
+func TestPanicGoesStraightToDeadLetter(t *testing.T) {
+	var letters []events.DeadLetter
+	publisher := events.NewEventPublisher(
+		events.WithRetry(events.RetryPolicy{Attempts: 5, Initial: time.Millisecond}),
+		events.WithDeadLetter(deadLetters(&letters)),
+		events.WithPanicRecovery(),
+	)
+
+	calls := 0
+	if _, err := events.Subscribe(publisher, func(ctx context.Context, event events.OrderDelivered) error {
+		calls++
+		panic("boom")
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var reported []events.GeneralError
+	if _, err := events.Subscribe(publisher, func(ctx context.Context, event events.GeneralError) error {
+		reported = append(reported, event)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := publisher.Notify(context.Background(), events.NewOrderDelivered(uuid.New())); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls != 1 {
+		t.Fatalf("panicking handler was called %d times, want 1", calls)
+	}
+	if len(letters) != 1 || letters[0].Attempts != 1 {
+		t.Fatalf("dead letters = %+v, want one after the first attempt", letters)
+	}
+	var panicErr *events.PanicError
+	if !errors.As(letters[0].Err, &panicErr) || panicErr.Value != "boom" || !errors.Is(letters[0].Err, events.ErrHandlerPanic) {
+		t.Fatalf("dead letter error = %v", letters[0].Err)
+	}
+	if len(reported) != 1 || string(reported[0]) != panicErr.Error() {
+		t.Fatalf("GeneralError subscribers received %v", reported)
+	}
+}
+
+func TestPanicWithoutRecoveryPropagates(t *testing.T) {
+	publisher := events.NewEventPublisher()
+	if _, err := events.Subscribe(publisher, func(ctx context.Context, event events.OrderDelivered) error {
+		panic("boom")
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("panic was swallowed without WithPanicRecovery")
+		}
+	}()
+	_ = publisher.Notify(context.Background(), events.NewOrderDelivered(uuid.New()))
+}
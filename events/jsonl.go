@@ -0,0 +1,96 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/clock"
+)
+
+// JSONLTransport дописывает конверты событий в файл, по одному JSON на строку,
+// и воспроизводит их; используется при разработке, чтобы повторить поток
+// событий без брокера
+type JSONLTransport struct {
+	path     string
+	registry *Registry
+	clock    clock.Clock
+	mutex    sync.Mutex
+}
+
+func NewJSONLTransport(path string, registry *Registry, clock clock.Clock) *JSONLTransport {
+	return &JSONLTransport{
+		path:     path,
+		registry: registry,
+		clock:    clock,
+	}
+}
+
+func (t *JSONLTransport) Publish(ctx context.Context, events ...Event) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	file, err := os.OpenFile(t.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, event := range events {
+		envelope, err := t.registry.Seal(Wrap(ctx, event, t.clock))
+		if err != nil {
+			file.Close()
+			return err
+		}
+		if err := encoder.Encode(envelope); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// Consume воспроизводит все события файла по порядку и завершается в конце
+// файла; первая ошибка обработчика останавливает воспроизведение
+func (t *JSONLTransport) Consume(ctx context.Context, handler Handler) error {
+	file, err := os.Open(t.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var envelope Envelope
+		if err := json.Unmarshal(scanner.Bytes(), &envelope); err != nil {
+			return fmt.Errorf("%s:%d: %w", t.path, line, err)
+		}
+		envelope, err := t.registry.Open(envelope)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", t.path, line, err)
+		}
+
+		if err := handler.Handle(ContextWithEnvelope(ctx, envelope), envelope.Event); err != nil {
+			return fmt.Errorf("%s:%d: %w", t.path, line, err)
+		}
+	}
+
+	return scanner.Err()
+}
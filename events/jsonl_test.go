@@ -0,0 +1,93 @@
+package events_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/clock"
+	"github.com/MaksimDzhangirov/PracticalDDD/events"
+	"github.com/google/uuid"
+)
+
+func TestJSONLTransportReplaysInOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	transport := events.NewJSONLTransport(path, events.NewDefaultRegistry(events.JSONCodec{}), clock.System)
+	orderID := uuid.New()
+
+	written := []events.Event{events.NewOrderCreated(orderID), events.NewOrderDispatched(orderID)}
+	if err := transport.Publish(context.Background(), written...); err != nil {
+		t.Fatal(err)
+	}
+	// повторная публикация дописывает файл
+	written = append(written, events.NewOrderDelivered(orderID))
+	if err := transport.Publish(context.Background(), written[2]); err != nil {
+		t.Fatal(err)
+	}
+
+	for replay := 0; replay < 2; replay++ {
+		var handler collector
+		if err := transport.Consume(context.Background(), &handler); err != nil {
+			t.Fatal(err)
+		}
+		if len(handler.events) != len(written) {
+			t.Fatalf("replayed %d events, want %d", len(handler.events), len(written))
+		}
+		for i := range written {
+			if handler.events[i] != written[i] || handler.envelopes[i].AggregateID != orderID {
+				t.Fatalf("event %d = %v, want %v", i, handler.events[i], written[i])
+			}
+		}
+	}
+}
+
+func TestJSONLTransportStopsAtTruncatedLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	transport := events.NewJSONLTransport(path, events.NewDefaultRegistry(events.JSONCodec{}), clock.System)
+	if err := transport.Publish(context.Background(), events.NewOrderCreated(uuid.New()), events.NewOrderDelivered(uuid.New())); err != nil {
+		t.Fatal(err)
+	}
+
+	// запись последней строки прервалась на середине
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data[:len(data)-20], 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var handler collector
+	err = transport.Consume(context.Background(), &handler)
+	if err == nil || !strings.Contains(err.Error(), path+":2:") {
+		t.Fatalf("Consume returned %v, want an error on line 2", err)
+	}
+	if len(handler.events) != 1 {
+		t.Fatalf("replayed %d events before the truncated line, want 1", len(handler.events))
+	}
+}
+
+func TestJSONLTransportStopsOnHandlerError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	transport := events.NewJSONLTransport(path, events.NewDefaultRegistry(events.JSONCodec{}), clock.System)
+	if err := transport.Publish(context.Background(), events.NewOrderCreated(uuid.New()), events.NewOrderDelivered(uuid.New())); err != nil {
+		t.Fatal(err)
+	}
+
+	calls := 0
+	err := transport.Consume(context.Background(), events.HandlerFunc(func(ctx context.Context, event events.Event) error {
+		calls++
+		return errHandler
+	}))
+	if !errors.Is(err, errHandler) || calls != 1 {
+		t.Fatalf("Consume returned %v after %d calls", err, calls)
+	}
+
+	if err := events.NewJSONLTransport(filepath.Join(t.TempDir(), "missing.jsonl"), events.NewDefaultRegistry(events.JSONCodec{}), clock.System).
+		Consume(context.Background(), &collector{}); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Consume of a missing file returned %v", err)
+	}
+}
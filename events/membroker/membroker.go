@@ -0,0 +1,128 @@
+// Package membroker - брокер сообщений в памяти с темами в стиле NATS
+// для локального запуска BrokerTransport без внешнего сервера
+package membroker
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/events"
+)
+
+// Broker доставляет сообщения синхронно в горутине Publish и возвращает
+// ошибки обработчиков, поэтому тесты видят результат сразу после публикации.
+// В теме "*" заменяет один токен, а ">" - все оставшиеся
+type Broker struct {
+	mutex         sync.Mutex
+	subscriptions []*subscription
+	lastID        uint64
+	// next - номер следующего получателя в каждой группе
+	next map[string]int
+}
+
+type subscription struct {
+	id         uint64
+	broker     *Broker
+	pattern    []string
+	queueGroup string
+	handler    func(ctx context.Context, message events.BrokerMessage) error
+}
+
+func New() *Broker {
+	return &Broker{
+		next: map[string]int{},
+	}
+}
+
+func (b *Broker) Subscribe(subject string, queueGroup string, handler func(ctx context.Context, message events.BrokerMessage) error) (events.BrokerSubscription, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.lastID++
+	s := &subscription{
+		id:         b.lastID,
+		broker:     b,
+		pattern:    strings.Split(subject, "."),
+		queueGroup: queueGroup,
+		handler:    handler,
+	}
+	b.subscriptions = append(b.subscriptions, s)
+
+	return s, nil
+}
+
+func (b *Broker) Publish(ctx context.Context, message events.BrokerMessage) error {
+	var errs []error
+	for _, s := range b.receivers(message.Subject) {
+		if err := s.handler(ctx, message); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// receivers выбирает всех подписчиков без группы и по одному из каждой группы
+func (b *Broker) receivers(subject string) []*subscription {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	tokens := strings.Split(subject, ".")
+	groups := map[string][]*subscription{}
+	var groupOrder []string
+	var result []*subscription
+
+	for _, s := range b.subscriptions {
+		if !matches(s.pattern, tokens) {
+			continue
+		}
+		if s.queueGroup == "" {
+			result = append(result, s)
+			continue
+		}
+
+		key := strings.Join(s.pattern, ".") + " " + s.queueGroup
+		if _, ok := groups[key]; !ok {
+			groupOrder = append(groupOrder, key)
+		}
+		groups[key] = append(groups[key], s)
+	}
+
+	for _, key := range groupOrder {
+		members := groups[key]
+		result = append(result, members[b.next[key]%len(members)])
+		b.next[key]++
+	}
+
+	return result
+}
+
+func matches(pattern, tokens []string) bool {
+	for i, part := range pattern {
+		if part == ">" {
+			return len(tokens) > i
+		}
+		if i >= len(tokens) || (part != "*" && part != tokens[i]) {
+			return false
+		}
+	}
+
+	return len(pattern) == len(tokens)
+}
+
+func (s *subscription) Unsubscribe() error {
+	s.broker.mutex.Lock()
+	defer s.broker.mutex.Unlock()
+
+	subscriptions := s.broker.subscriptions
+	for i, other := range subscriptions {
+		if other.id == s.id {
+			s.broker.subscriptions = append(subscriptions[:i:i], subscriptions[i+1:]...)
+			break
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package membroker_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/events"
+	"github.com/MaksimDzhangirov/PracticalDDD/events/membroker"
+)
+
+// subscribe подписывается на subject и считает полученные сообщения
+func subscribe(t *testing.T, broker *membroker.Broker, subject, queueGroup string) (*int, events.BrokerSubscription) {
+	t.Helper()
+
+	received := new(int)
+	subscription, err := broker.Subscribe(subject, queueGroup, func(ctx context.Context, message events.BrokerMessage) error {
+		*received++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return received, subscription
+}
+
+func TestSubjectWildcards(t *testing.T) {
+	tests := []struct {
+		pattern string
+		subject string
+		match   bool
+	}{
+		{"event.order.created", "event.order.created", true},
+		{"event.order.created", "event.order.dispatched", false},
+		{"event.order.*", "event.order.created", true},
+		{"event.order.*", "event.order.delivery.success", false},
+		{"event.*.created", "event.order.created", true},
+		{"event.>", "event.order.delivery.success", true},
+		{"event.order.>", "event.order", false},
+		{"event.order", "event.order.created", false},
+	}
+	for _, test := range tests {
+		broker := membroker.New()
+		received, _ := subscribe(t, broker, test.pattern, "")
+
+		if err := broker.Publish(context.Background(), events.BrokerMessage{Subject: test.subject}); err != nil {
+			t.Fatal(err)
+		}
+		if (*received == 1) != test.match {
+			t.Fatalf("%q received %d messages on %q", test.pattern, *received, test.subject)
+		}
+	}
+}
+
+func TestQueueGroupsRoundRobin(t *testing.T) {
+	broker := membroker.New()
+	first, _ := subscribe(t, broker, "event.>", "workers")
+	second, _ := subscribe(t, broker, "event.>", "workers")
+	// та же группа с другим шаблоном - отдельная группа
+	other, _ := subscribe(t, broker, "event.order.*", "workers")
+
+	for i := 0; i < 4; i++ {
+		if err := broker.Publish(context.Background(), events.BrokerMessage{Subject: "event.order.created"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if *first != 2 || *second != 2 || *other != 4 {
+		t.Fatalf("received %d, %d and %d messages, want 2, 2 and 4", *first, *second, *other)
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	broker := membroker.New()
+	first, subscription := subscribe(t, broker, "event.>", "workers")
+	second, _ := subscribe(t, broker, "event.>", "workers")
+
+	if err := subscription.Unsubscribe(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := broker.Publish(context.Background(), events.BrokerMessage{Subject: "event.order.created"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if *first != 0 || *second != 3 {
+		t.Fatalf("received %d and %d messages after Unsubscribe, want 0 and 3", *first, *second)
+	}
+}
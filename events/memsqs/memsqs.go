@@ -0,0 +1,294 @@
+// Package memsqs - очередь SQS в памяти для локального запуска
+// SQSService и EventSQSHandler без AWS
+package memsqs
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/clock"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+const (
+	defaultVisibilityTimeout = 30 * time.Second
+	// deduplicationWindow - интервал, в котором FIFO-очередь отбрасывает повторы
+	deduplicationWindow = 5 * time.Minute
+)
+
+var (
+	ErrInvalidReceiptHandle  = errors.New("receipt handle is invalid")
+	ErrMissingFIFOParameters = errors.New("FIFO queue requires MessageGroupId and MessageDeduplicationId")
+)
+
+type message struct {
+	id           string
+	body         string
+	attributes   map[string]*sqs.MessageAttributeValue
+	receipt      string
+	receiveCount int
+	visibleAt    time.Time
+	groupID      string
+}
+
+// Client хранит очереди по их URL; очередь создаётся при первой отправке.
+// Очереди с суффиксом ".fifo" отбрасывают повторы по MessageDeduplicationId
+// и не выдают сообщения группы, пока предыдущее сообщение группы обрабатывается
+type Client struct {
+	mutex   sync.Mutex
+	clock   clock.Clock
+	queues  map[string][]*message
+	sent    map[string]sentMessage
+	lastID  int
+	changed chan struct{}
+}
+
+type sentMessage struct {
+	id     string
+	sentAt time.Time
+}
+
+type Option func(*Client)
+
+// WithClock задаёт часы, по которым отсчитываются задержка доставки,
+// таймаут видимости и окно дедупликации. Long polling ждёт по реальному
+// времени, но замечает сообщения, ставшие видимыми после перевода часов
+func WithClock(clock clock.Clock) Option {
+	return func(c *Client) {
+		c.clock = clock
+	}
+}
+
+func New(options ...Option) *Client {
+	client := &Client{
+		clock:   clock.System,
+		queues:  map[string][]*message{},
+		sent:    map[string]sentMessage{},
+		changed: make(chan struct{}),
+	}
+	for _, option := range options {
+		option(client)
+	}
+
+	return client
+}
+
+func isFIFO(queueURL string) bool {
+	return strings.HasSuffix(queueURL, ".fifo")
+}
+
+func (c *Client) SendMessageWithContext(ctx aws.Context, input *sqs.SendMessageInput, options ...request.Option) (*sqs.SendMessageOutput, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	id, err := c.send(aws.StringValue(input.QueueUrl), &sqs.SendMessageBatchRequestEntry{
+		MessageBody:            input.MessageBody,
+		MessageAttributes:      input.MessageAttributes,
+		DelaySeconds:           input.DelaySeconds,
+		MessageGroupId:         input.MessageGroupId,
+		MessageDeduplicationId: input.MessageDeduplicationId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &sqs.SendMessageOutput{MessageId: aws.String(id)}, nil
+}
+
+func (c *Client) SendMessageBatchWithContext(ctx aws.Context, input *sqs.SendMessageBatchInput, options ...request.Option) (*sqs.SendMessageBatchOutput, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	output := &sqs.SendMessageBatchOutput{}
+	for _, entry := range input.Entries {
+		id, err := c.send(aws.StringValue(input.QueueUrl), entry)
+		if err != nil {
+			output.Failed = append(output.Failed, &sqs.BatchResultErrorEntry{
+				Id:          entry.Id,
+				Code:        aws.String("MissingParameter"),
+				Message:     aws.String(err.Error()),
+				SenderFault: aws.Bool(true),
+			})
+			continue
+		}
+		output.Successful = append(output.Successful, &sqs.SendMessageBatchResultEntry{
+			Id:        entry.Id,
+			MessageId: aws.String(id),
+		})
+	}
+
+	return output, nil
+}
+
+func (c *Client) send(queueURL string, entry *sqs.SendMessageBatchRequestEntry) (string, error) {
+	now := c.clock.Now()
+
+	deduplicationKey := ""
+	if isFIFO(queueURL) {
+		if entry.MessageGroupId == nil || entry.MessageDeduplicationId == nil {
+			return "", ErrMissingFIFOParameters
+		}
+		deduplicationKey = queueURL + "\x00" + aws.StringValue(entry.MessageDeduplicationId)
+		if sent, ok := c.sent[deduplicationKey]; ok && now.Sub(sent.sentAt) < deduplicationWindow {
+			return sent.id, nil
+		}
+	}
+
+	c.lastID++
+	id := strconv.Itoa(c.lastID)
+	if deduplicationKey != "" {
+		c.sent[deduplicationKey] = sentMessage{id: id, sentAt: now}
+	}
+
+	c.queues[queueURL] = append(c.queues[queueURL], &message{
+		id:         id,
+		body:       aws.StringValue(entry.MessageBody),
+		attributes: entry.MessageAttributes,
+		visibleAt:  now.Add(time.Duration(aws.Int64Value(entry.DelaySeconds)) * time.Second),
+		groupID:    aws.StringValue(entry.MessageGroupId),
+	})
+
+	close(c.changed)
+	c.changed = make(chan struct{})
+
+	return id, nil
+}
+
+// ReceiveMessageWithContext ждёт сообщений до WaitTimeSeconds, как long polling в SQS
+func (c *Client) ReceiveMessageWithContext(ctx aws.Context, input *sqs.ReceiveMessageInput, options ...request.Option) (*sqs.ReceiveMessageOutput, error) {
+	deadline := time.Now().Add(time.Duration(aws.Int64Value(input.WaitTimeSeconds)) * time.Second)
+
+	for {
+		c.mutex.Lock()
+		messages := c.receive(input)
+		changed := c.changed
+		c.mutex.Unlock()
+
+		wait := time.Until(deadline)
+		if len(messages) > 0 || wait <= 0 {
+			return &sqs.ReceiveMessageOutput{Messages: messages}, nil
+		}
+
+		// сообщение может стать видимым без отправки, поэтому ждём недолго
+		if wait > 50*time.Millisecond {
+			wait = 50 * time.Millisecond
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-changed:
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (c *Client) receive(input *sqs.ReceiveMessageInput) []*sqs.Message {
+	limit := int(aws.Int64Value(input.MaxNumberOfMessages))
+	if limit <= 0 {
+		limit = 1
+	}
+	visibility := defaultVisibilityTimeout
+	if input.VisibilityTimeout != nil {
+		visibility = time.Duration(*input.VisibilityTimeout) * time.Second
+	}
+
+	now := c.clock.Now()
+	fifo := isFIFO(aws.StringValue(input.QueueUrl))
+	blocked := map[string]bool{}
+
+	var result []*sqs.Message
+	for _, m := range c.queues[aws.StringValue(input.QueueUrl)] {
+		if len(result) == limit {
+			break
+		}
+		if m.visibleAt.After(now) {
+			// сообщение FIFO-группы в обработке задерживает следующие
+			if fifo && m.receiveCount > 0 {
+				blocked[m.groupID] = true
+			}
+			continue
+		}
+		if fifo && blocked[m.groupID] {
+			continue
+		}
+
+		c.lastID++
+		m.receipt = strconv.Itoa(c.lastID)
+		m.receiveCount++
+		m.visibleAt = now.Add(visibility)
+
+		attributes := map[string]*string{
+			sqs.MessageSystemAttributeNameApproximateReceiveCount: aws.String(strconv.Itoa(m.receiveCount)),
+		}
+		if fifo {
+			attributes[sqs.MessageSystemAttributeNameMessageGroupId] = aws.String(m.groupID)
+		}
+		result = append(result, &sqs.Message{
+			MessageId:         aws.String(m.id),
+			ReceiptHandle:     aws.String(m.receipt),
+			Body:              aws.String(m.body),
+			MessageAttributes: m.attributes,
+			Attributes:        attributes,
+		})
+	}
+
+	return result
+}
+
+func (c *Client) DeleteMessageBatchWithContext(ctx aws.Context, input *sqs.DeleteMessageBatchInput, options ...request.Option) (*sqs.DeleteMessageBatchOutput, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	output := &sqs.DeleteMessageBatchOutput{}
+	for _, entry := range input.Entries {
+		if !c.delete(aws.StringValue(input.QueueUrl), aws.StringValue(entry.ReceiptHandle)) {
+			output.Failed = append(output.Failed, &sqs.BatchResultErrorEntry{
+				Id:          entry.Id,
+				Code:        aws.String("ReceiptHandleIsInvalid"),
+				Message:     aws.String(ErrInvalidReceiptHandle.Error()),
+				SenderFault: aws.Bool(true),
+			})
+			continue
+		}
+		output.Successful = append(output.Successful, &sqs.DeleteMessageBatchResultEntry{Id: entry.Id})
+	}
+
+	return output, nil
+}
+
+func (c *Client) delete(queueURL, receipt string) bool {
+	messages := c.queues[queueURL]
+	for i, m := range messages {
+		if m.receipt == receipt {
+			c.queues[queueURL] = append(messages[:i], messages[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Client) ChangeMessageVisibilityWithContext(ctx aws.Context, input *sqs.ChangeMessageVisibilityInput, options ...request.Option) (*sqs.ChangeMessageVisibilityOutput, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, m := range c.queues[aws.StringValue(input.QueueUrl)] {
+		if m.receipt == aws.StringValue(input.ReceiptHandle) {
+			m.visibleAt = c.clock.Now().Add(time.Duration(aws.Int64Value(input.VisibilityTimeout)) * time.Second)
+			return &sqs.ChangeMessageVisibilityOutput{}, nil
+		}
+	}
+
+	return nil, ErrInvalidReceiptHandle
+}
+
+// Len возвращает количество сообщений в очереди, включая невидимые
+func (c *Client) Len(queueURL string) int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return len(c.queues[queueURL])
+}
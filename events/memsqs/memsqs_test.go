@@ -0,0 +1,120 @@
+package memsqs_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/clock"
+	"github.com/MaksimDzhangirov/PracticalDDD/events/memsqs"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+const queueURL = "https://sqs.local/test.fifo"
+
+func sendMessage(t *testing.T, client *memsqs.Client, body, group, deduplicationID string) string {
+	t.Helper()
+
+	output, err := client.SendMessageWithContext(context.Background(), &sqs.SendMessageInput{
+		QueueUrl:               aws.String(queueURL),
+		MessageBody:            aws.String(body),
+		MessageGroupId:         aws.String(group),
+		MessageDeduplicationId: aws.String(deduplicationID),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return aws.StringValue(output.MessageId)
+}
+
+func receive(t *testing.T, client *memsqs.Client, visibility int64) []*sqs.Message {
+	t.Helper()
+
+	return receiveN(t, client, 10, visibility)
+}
+
+func receiveN(t *testing.T, client *memsqs.Client, limit, visibility int64) []*sqs.Message {
+	t.Helper()
+
+	output, err := client.ReceiveMessageWithContext(context.Background(), &sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String(queueURL),
+		MaxNumberOfMessages: aws.Int64(limit),
+		VisibilityTimeout:   aws.Int64(visibility),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return output.Messages
+}
+
+func TestVisibilityTimeoutFollowsClock(t *testing.T) {
+	now := clock.NewManual(time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC))
+	client := memsqs.New(memsqs.WithClock(now))
+	sendMessage(t, client, "first", "a", "1")
+
+	if messages := receive(t, client, 30); len(messages) != 1 {
+		t.Fatalf("received %d messages, want 1", len(messages))
+	}
+	now.Advance(29 * time.Second)
+	if messages := receive(t, client, 30); len(messages) != 0 {
+		t.Fatalf("received %d messages before the visibility timeout", len(messages))
+	}
+	now.Advance(time.Second)
+	messages := receive(t, client, 30)
+	if len(messages) != 1 || aws.StringValue(messages[0].Attributes[sqs.MessageSystemAttributeNameApproximateReceiveCount]) != "2" {
+		t.Fatalf("received %v after the visibility timeout", messages)
+	}
+
+	// продление видимости отсчитывается от текущего времени часов
+	if _, err := client.ChangeMessageVisibilityWithContext(context.Background(), &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          aws.String(queueURL),
+		ReceiptHandle:     messages[0].ReceiptHandle,
+		VisibilityTimeout: aws.Int64(60),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	now.Advance(59 * time.Second)
+	if messages := receive(t, client, 30); len(messages) != 0 {
+		t.Fatalf("received %d messages before the extended timeout", len(messages))
+	}
+}
+
+func TestFIFOGroupWaitsForMessageInFlight(t *testing.T) {
+	now := clock.NewManual(time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC))
+	client := memsqs.New(memsqs.WithClock(now))
+	sendMessage(t, client, "first", "a", "1")
+	sendMessage(t, client, "second", "a", "2")
+	sendMessage(t, client, "other", "b", "3")
+
+	if messages := receiveN(t, client, 1, 30); len(messages) != 1 || aws.StringValue(messages[0].Body) != "first" {
+		t.Fatalf("received %v, want the first message", messages)
+	}
+	// следующее сообщение группы a ждёт, пока обрабатывается первое
+	messages := receive(t, client, 30)
+	if len(messages) != 1 || aws.StringValue(messages[0].Body) != "other" {
+		t.Fatalf("received %v while group a is in flight, want only group b", messages)
+	}
+
+	now.Advance(30 * time.Second)
+	messages = receiveN(t, client, 1, 30)
+	if len(messages) != 1 || aws.StringValue(messages[0].Body) != "first" {
+		t.Fatalf("received %v after the visibility timeout, want the first message again", messages)
+	}
+}
+
+func TestDeduplicationWindowFollowsClock(t *testing.T) {
+	now := clock.NewManual(time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC))
+	client := memsqs.New(memsqs.WithClock(now))
+
+	first := sendMessage(t, client, "body", "a", "same")
+	now.Advance(4 * time.Minute)
+	if again := sendMessage(t, client, "body", "a", "same"); again != first || client.Len(queueURL) != 1 {
+		t.Fatalf("duplicate inside the window was stored as %s", again)
+	}
+
+	now.Advance(time.Minute)
+	if later := sendMessage(t, client, "body", "a", "same"); later == first || client.Len(queueURL) != 2 {
+		t.Fatal("message after the deduplication window was dropped")
+	}
+}
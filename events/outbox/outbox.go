@@ -0,0 +1,146 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/clock"
+	"github.com/MaksimDzhangirov/PracticalDDD/events"
+	gorm_generics "github.com/MaksimDzhangirov/PracticalDDD/gorm-generics"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// инфраструктурный уровень
+
+// Message - строка таблицы outbox_messages. ID совпадает с ID конверта
+// и служит ключом идемпотентности для получателей. Seq растёт в порядке
+// записи и задаёт порядок доставки: у событий одной транзакции CreatedAt
+// может совпадать
+type Message struct {
+	Seq           uint64 `gorm:"primaryKey;autoIncrement"`
+	ID            string `gorm:"uniqueIndex;size:36"`
+	Name          string `gorm:"index"`
+	AggregateType string
+	AggregateID   string     `gorm:"index;size:36"`
+	Body          []byte     `gorm:"not null"`
+	CreatedAt     time.Time  `gorm:"index"`
+	DeliveredAt   *time.Time `gorm:"index"`
+	Attempts      int
+	LastError     string
+	// LockedBy - токен вызова Relay, захватившего сообщение
+	LockedBy    string `gorm:"size:36;index"`
+	LockedUntil *time.Time
+	// PoisonedAt - время, когда Relay перестал доставлять сообщение
+	// после WithMaxAttempts неудачных попыток
+	PoisonedAt *time.Time `gorm:"index"`
+}
+
+func (Message) TableName() string {
+	return "outbox_messages"
+}
+
+// ProcessedEvent отмечает событие, уже обработанное получателем Handler
+type ProcessedEvent struct {
+	ID          string `gorm:"primaryKey;size:36"`
+	Handler     string `gorm:"primaryKey"`
+	ProcessedAt time.Time
+}
+
+func (ProcessedEvent) TableName() string {
+	return "processed_events"
+}
+
+// Migrate создаёт таблицы outbox_messages и processed_events
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(&Message{}, &ProcessedEvent{})
+}
+
+// ContextWithTx сохраняет транзакцию, в которой должны писаться агрегат и события
+func ContextWithTx(ctx context.Context, tx *gorm.DB) context.Context {
+	return gorm_generics.ContextWithTx(ctx, tx)
+}
+
+// DB возвращает транзакцию из ctx или, если её нет, соединение db
+func DB(ctx context.Context, db *gorm.DB) *gorm.DB {
+	return gorm_generics.DB(ctx, db)
+}
+
+// Transaction выполняет fn в транзакции db; вложенный вызов присоединяется
+// к уже открытой транзакции, в том числе к открытой gorm_generics.UnitOfWork.
+// В отличие от UnitOfWork.Do, который во вложенном вызове создаёт точку
+// сохранения, Transaction её не создаёт: изменения вложенного fn, вернувшего
+// ошибку, остаются в транзакции, и их откатывает только внешний вызов
+func Transaction(db *gorm.DB) func(ctx context.Context, fn func(ctx context.Context) error) error {
+	return func(ctx context.Context, fn func(ctx context.Context) error) error {
+		if _, ok := gorm_generics.TxFromContext(ctx); ok {
+			return fn(ctx)
+		}
+
+		return gorm_generics.NewUnitOfWork(db).Do(ctx, fn)
+	}
+}
+
+// Outbox сохраняет события вместо немедленной публикации. Если в ctx есть
+// транзакция, событие будет видно Relay только после её фиксации
+type Outbox struct {
+	db    *gorm.DB
+	clock clock.Clock
+}
+
+func NewOutbox(db *gorm.DB, clock clock.Clock) *Outbox {
+	return &Outbox{
+		db:    db,
+		clock: clock,
+	}
+}
+
+// Notify записывает событие в outbox_messages
+func (o *Outbox) Notify(ctx context.Context, event events.Event) error {
+	envelope := events.Wrap(ctx, event, o.clock)
+
+	body, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+
+	message := Message{
+		ID:            envelope.ID.String(),
+		Name:          envelope.Name,
+		AggregateType: envelope.AggregateType,
+		AggregateID:   envelope.AggregateID.String(),
+		Body:          body,
+		CreatedAt:     envelope.OccurredAt,
+	}
+	return DB(ctx, o.db).Create(&message).Error
+}
+
+// Idempotent пропускает события, которые handler с именем name уже обработал.
+// Отметка об обработке пишется в той же транзакции, что и изменения handler'а,
+// если он берёт соединение через DB(ctx, db), поэтому повторная доставка
+// одного сообщения не видна получателю
+func Idempotent(db *gorm.DB, name string, handler events.Handler, clock clock.Clock) events.Handler {
+	return events.HandlerFunc(func(ctx context.Context, event events.Event) error {
+		envelope, ok := events.EnvelopeOf(ctx, event)
+		if !ok {
+			return handler.Handle(ctx, event)
+		}
+
+		return Transaction(db)(ctx, func(ctx context.Context) error {
+			result := DB(ctx, db).Clauses(clause.OnConflict{DoNothing: true}).Create(&ProcessedEvent{
+				ID:          envelope.ID.String(),
+				Handler:     name,
+				ProcessedAt: clock.Now(),
+			})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return nil
+			}
+
+			return handler.Handle(ctx, event)
+		})
+	})
+}
@@ -0,0 +1,221 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/clock"
+	"github.com/MaksimDzhangirov/PracticalDDD/events"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Relay пересылает сообщения из outbox_messages обработчику, например
+// EventSQSHandler. Доставка "как минимум один раз": если процесс упадёт
+// после отправки, но до отметки DeliveredAt, сообщение будет отправлено снова
+// с тем же ID конверта. Сообщение, которое не удалось доставить за
+// maxAttempts попыток, помечается PoisonedAt и больше не пересылается
+type Relay struct {
+	db          *gorm.DB
+	registry    *events.Registry
+	handler     events.Handler
+	clock       clock.Clock
+	interval    time.Duration
+	batchSize   int
+	lease       time.Duration
+	maxAttempts int
+}
+
+type RelayOption func(*Relay)
+
+// WithInterval задаёт паузу между опросами таблицы
+func WithInterval(interval time.Duration) RelayOption {
+	return func(r *Relay) {
+		r.interval = interval
+	}
+}
+
+func WithBatchSize(size int) RelayOption {
+	return func(r *Relay) {
+		r.batchSize = size
+	}
+}
+
+// WithLease задаёт, на сколько Relay захватывает сообщения; по истечении
+// срока их может забрать другой экземпляр
+func WithLease(lease time.Duration) RelayOption {
+	return func(r *Relay) {
+		r.lease = lease
+	}
+}
+
+// WithMaxAttempts задаёт количество попыток доставки одного сообщения;
+// 0 - повторять без ограничений
+func WithMaxAttempts(attempts int) RelayOption {
+	return func(r *Relay) {
+		r.maxAttempts = attempts
+	}
+}
+
+func NewRelay(db *gorm.DB, registry *events.Registry, handler events.Handler, clock clock.Clock, options ...RelayOption) *Relay {
+	relay := &Relay{
+		db:          db,
+		registry:    registry,
+		handler:     handler,
+		clock:       clock,
+		interval:    time.Second,
+		batchSize:   100,
+		lease:       time.Minute,
+		maxAttempts: 10,
+	}
+	for _, option := range options {
+		option(relay)
+	}
+
+	return relay
+}
+
+// Run опрашивает таблицу до отмены ctx
+func (r *Relay) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.Flush(ctx); err != nil {
+			log.Print(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Flush пересылает одну пачку сообщений и возвращает количество доставленных
+func (r *Relay) Flush(ctx context.Context) (int, error) {
+	messages, err := r.claim(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	// после ошибки остальные события агрегата ждут, чтобы не нарушить порядок
+	failed := map[string]bool{}
+	for _, message := range messages {
+		if failed[message.AggregateID] {
+			if err := r.release(ctx, message); err != nil {
+				return delivered, err
+			}
+			continue
+		}
+
+		if err := r.deliver(ctx, message); err != nil {
+			failed[message.AggregateID] = message.AggregateID != uuid.Nil.String()
+			if err := r.fail(ctx, message, err); err != nil {
+				return delivered, err
+			}
+			continue
+		}
+
+		if err := r.markDelivered(ctx, message); err != nil {
+			return delivered, err
+		}
+		delivered++
+	}
+
+	return delivered, nil
+}
+
+// free - сообщения, которые можно захватить в момент now
+func free(db *gorm.DB, now time.Time) *gorm.DB {
+	return db.Where("delivered_at IS NULL AND poisoned_at IS NULL AND (locked_until IS NULL OR locked_until < ?)", now)
+}
+
+// claim захватывает свободные сообщения, помечая их токеном вызова.
+// Повторная проверка условия в UPDATE не даёт двум Relay захватить одно
+// сообщение, а по токену читаются только сообщения, захваченные этим
+// вызовом. Запросы не используют подзапрос к той же таблице с LIMIT,
+// который не поддерживает MySQL
+func (r *Relay) claim(ctx context.Context) ([]Message, error) {
+	now := r.clock.Now()
+	db := r.db.WithContext(ctx)
+
+	var ids []string
+	err := free(db.Model(&Message{}), now).
+		Order("seq").
+		Limit(r.batchSize).
+		Pluck("id", &ids).
+		Error
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	token := uuid.NewString()
+	err = free(db.Model(&Message{}), now).
+		Where("id IN ?", ids).
+		Updates(map[string]interface{}{"locked_by": token, "locked_until": now.Add(r.lease)}).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []Message
+	err = db.Where("locked_by = ? AND delivered_at IS NULL", token).
+		Order("seq").
+		Find(&messages).
+		Error
+	return messages, err
+}
+
+func (r *Relay) deliver(ctx context.Context, message Message) error {
+	var envelope events.Envelope
+	if err := json.Unmarshal(message.Body, &envelope); err != nil {
+		return err
+	}
+
+	envelope, err := r.registry.Open(envelope)
+	if err != nil {
+		return err
+	}
+
+	return r.handler.Handle(events.ContextWithEnvelope(ctx, envelope), envelope.Event)
+}
+
+func (r *Relay) markDelivered(ctx context.Context, message Message) error {
+	return r.update(ctx, message, map[string]interface{}{
+		"delivered_at": r.clock.Now(),
+		"attempts":     message.Attempts + 1,
+		"locked_until": nil,
+	})
+}
+
+func (r *Relay) fail(ctx context.Context, message Message, cause error) error {
+	values := map[string]interface{}{
+		"attempts":     message.Attempts + 1,
+		"last_error":   cause.Error(),
+		"locked_until": nil,
+	}
+	if r.maxAttempts > 0 && message.Attempts+1 >= r.maxAttempts {
+		values["poisoned_at"] = r.clock.Now()
+	}
+
+	return r.update(ctx, message, values)
+}
+
+func (r *Relay) release(ctx context.Context, message Message) error {
+	return r.update(ctx, message, map[string]interface{}{
+		"locked_until": nil,
+	})
+}
+
+func (r *Relay) update(ctx context.Context, message Message, values map[string]interface{}) error {
+	return r.db.WithContext(ctx).
+		Model(&Message{}).
+		Where("id = ? AND locked_by = ?", message.ID, message.LockedBy).
+		Updates(values).
+		Error
+}
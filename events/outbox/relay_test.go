@@ -0,0 +1,246 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/clock"
+	"github.com/MaksimDzhangirov/PracticalDDD/events"
+	"github.com/MaksimDzhangirov/PracticalDDD/events/outbox"
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// handled - побочный эффект обработчика, который пишется в транзакции Idempotent
+type handled struct {
+	EventID string `gorm:"primaryKey;size:36"`
+	Count   int
+}
+
+func openDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "outbox.db") + "?_busy_timeout=5000&_txlock=immediate"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := outbox.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&handled{}); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func notify(t *testing.T, db *gorm.DB, clock clock.Clock, count int) {
+	t.Helper()
+
+	box := outbox.NewOutbox(db, clock)
+	for i := 0; i < count; i++ {
+		if err := box.Notify(context.Background(), events.NewOrderCreated(uuid.New())); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// counter увеличивает handled.Count в транзакции, открытой Idempotent
+func counter(db *gorm.DB) events.Handler {
+	return events.HandlerFunc(func(ctx context.Context, event events.Event) error {
+		envelope, _ := events.EnvelopeOf(ctx, event)
+		tx := outbox.DB(ctx, db)
+
+		row := handled{EventID: envelope.ID.String()}
+		if err := tx.FirstOrCreate(&row).Error; err != nil {
+			return err
+		}
+		return tx.Model(&row).Update("count", gorm.Expr("count + 1")).Error
+	})
+}
+
+func TestRelayDeliversExactlyOnce(t *testing.T) {
+	db := openDB(t)
+	now := clock.NewManual(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	notify(t, db, now, 50)
+
+	registry := events.NewDefaultRegistry(events.JSONCodec{})
+	handler := outbox.Idempotent(db, "counter", counter(db), now)
+
+	// несколько Relay опрашивают таблицу одновременно
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		relay := outbox.NewRelay(db, registry, handler, now, outbox.WithBatchSize(7))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				delivered, err := relay.Flush(context.Background())
+				if err != nil {
+					errs <- err
+					return
+				}
+				if delivered == 0 {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	// процесс упал после отправки, но до отметки DeliveredAt
+	if err := db.Model(&outbox.Message{}).Where("1 = 1").Update("delivered_at", nil).Error; err != nil {
+		t.Fatal(err)
+	}
+	relay := outbox.NewRelay(db, registry, handler, now)
+	if delivered, err := relay.Flush(context.Background()); err != nil || delivered != 50 {
+		t.Fatalf("redelivery: delivered %d, err %v", delivered, err)
+	}
+
+	var rows []handled
+	if err := db.Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 50 {
+		t.Fatalf("handled %d events, want 50", len(rows))
+	}
+	for _, row := range rows {
+		if row.Count != 1 {
+			t.Fatalf("event %s handled %d times", row.EventID, row.Count)
+		}
+	}
+}
+
+func TestRelayClaimRespectsLease(t *testing.T) {
+	db := openDB(t)
+	now := clock.NewManual(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	notify(t, db, now, 3)
+
+	registry := events.NewDefaultRegistry(events.JSONCodec{})
+	var delivered []string
+	recording := events.HandlerFunc(func(ctx context.Context, event events.Event) error {
+		envelope, _ := events.EnvelopeOf(ctx, event)
+		delivered = append(delivered, envelope.ID.String())
+		return nil
+	})
+
+	// первый Relay захватил сообщения и "упал", не доставив их
+	var messages []outbox.Message
+	crashed := outbox.NewRelay(db, registry, events.HandlerFunc(func(ctx context.Context, event events.Event) error {
+		if err := db.Where("locked_by <> ''").Find(&messages).Error; err != nil {
+			return err
+		}
+		panic("crash")
+	}), now, outbox.WithLease(time.Minute))
+	func() {
+		defer func() { recover() }()
+		crashed.Flush(context.Background())
+	}()
+	if len(messages) != 3 {
+		t.Fatalf("claimed %d messages, want 3", len(messages))
+	}
+
+	relay := outbox.NewRelay(db, registry, recording, now)
+	if count, err := relay.Flush(context.Background()); err != nil || count != 0 {
+		t.Fatalf("messages under lease: delivered %d, err %v", count, err)
+	}
+
+	now.Advance(time.Minute + time.Second)
+	if count, err := relay.Flush(context.Background()); err != nil || count != 3 {
+		t.Fatalf("after lease: delivered %d, err %v", count, err)
+	}
+	if len(delivered) != 3 {
+		t.Fatalf("handler called %d times, want 3", len(delivered))
+	}
+}
+
+func TestRelayPoisonsMessageAfterMaxAttempts(t *testing.T) {
+	db := openDB(t)
+	now := clock.NewManual(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	notify(t, db, now, 1)
+
+	calls := 0
+	failing := events.HandlerFunc(func(ctx context.Context, event events.Event) error {
+		calls++
+		return errors.New("broker is down")
+	})
+	relay := outbox.NewRelay(db, events.NewDefaultRegistry(events.JSONCodec{}), failing, now, outbox.WithMaxAttempts(3))
+
+	for i := 0; i < 5; i++ {
+		if _, err := relay.Flush(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 3 {
+		t.Fatalf("handler called %d times, want 3", calls)
+	}
+
+	var message outbox.Message
+	if err := db.First(&message).Error; err != nil {
+		t.Fatal(err)
+	}
+	if message.PoisonedAt == nil || message.Attempts != 3 || message.LastError != "broker is down" {
+		t.Fatalf("message is not poisoned: %+v", message)
+	}
+}
+
+func TestRelayKeepsWriteOrderForEqualTimestamps(t *testing.T) {
+	db := openDB(t)
+	// все события записаны в один момент, порядок задаёт только Seq
+	fixed := clock.Fixed(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	orderID := uuid.New()
+	box := outbox.NewOutbox(db, fixed)
+	var written []string
+	err := outbox.Transaction(db)(context.Background(), func(ctx context.Context) error {
+		for i := 0; i < 20; i++ {
+			event := events.NewOrderDispatched(orderID)
+			envelope := events.NewEnvelope(event, fixed)
+			written = append(written, envelope.ID.String())
+			if err := box.Notify(events.ContextWithEnvelope(ctx, envelope), event); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var delivered []string
+	recording := events.HandlerFunc(func(ctx context.Context, event events.Event) error {
+		envelope, _ := events.EnvelopeOf(ctx, event)
+		delivered = append(delivered, envelope.ID.String())
+		return nil
+	})
+	relay := outbox.NewRelay(db, events.NewDefaultRegistry(events.JSONCodec{}), recording, fixed, outbox.WithBatchSize(7))
+	for {
+		count, err := relay.Flush(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count == 0 {
+			break
+		}
+	}
+
+	if len(delivered) != len(written) {
+		t.Fatalf("delivered %d events, want %d", len(delivered), len(written))
+	}
+	for i := range written {
+		if delivered[i] != written[i] {
+			t.Fatalf("event %d delivered out of order", i)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+var (
+	ErrUnknownEvent     = errors.New("unknown event")
+	ErrUnsupportedEvent = errors.New("event does not support codec")
+	ErrMissingUpcaster  = errors.New("no upcaster for event schema version")
+)
+
+// Codec переводит событие в байты и обратно
+type Codec interface {
+	ContentType() string
+	Marshal(event Event) ([]byte, error)
+	Unmarshal(data []byte, target interface{}) error
+}
+
+// JSONCodec использует MarshalJSON/UnmarshalJSON событий
+type JSONCodec struct{}
+
+func (JSONCodec) ContentType() string {
+	return "application/json"
+}
+
+func (JSONCodec) Marshal(event Event) ([]byte, error) {
+	return json.Marshal(event)
+}
+
+func (JSONCodec) Unmarshal(data []byte, target interface{}) error {
+	return json.Unmarshal(data, target)
+}
+
+// ProtoMarshaler и ProtoUnmarshaler совпадают с методами, которые
+// генерирует protoc-gen-gogo, поэтому сгенерированные сообщения
+// подходят для ProtoCodec без дополнительных зависимостей
+type ProtoMarshaler interface {
+	Marshal() ([]byte, error)
+}
+
+type ProtoUnmarshaler interface {
+	Unmarshal(data []byte) error
+}
+
+// ProtoCodec сериализует события в формате protobuf
+type ProtoCodec struct{}
+
+func (ProtoCodec) ContentType() string {
+	return "application/x-protobuf"
+}
+
+func (ProtoCodec) Marshal(event Event) ([]byte, error) {
+	message, ok := event.(ProtoMarshaler)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is not a protobuf message", ErrUnsupportedEvent, event.Name())
+	}
+	return message.Marshal()
+}
+
+func (ProtoCodec) Unmarshal(data []byte, target interface{}) error {
+	message, ok := target.(ProtoUnmarshaler)
+	if !ok {
+		return fmt.Errorf("%w: %T is not a protobuf message", ErrUnsupportedEvent, target)
+	}
+	return message.Unmarshal(data)
+}
+
+// Upcaster переводит данные события из версии схемы from в from+1
+type Upcaster func(payload []byte) ([]byte, error)
+
+type registration struct {
+	eventType reflect.Type
+	version   int
+	decode    func(codec Codec, data []byte) (Event, error)
+	upcasters map[int]Upcaster
+}
+
+// Registry знает все типы событий и восстанавливает их по имени
+type Registry struct {
+	mutex   sync.RWMutex
+	codec   Codec
+	entries map[string]*registration
+}
+
+func NewRegistry(codec Codec) *Registry {
+	return &Registry{
+		codec:   codec,
+		entries: map[string]*registration{},
+	}
+}
+
+// NewDefaultRegistry содержит все события пакета
+func NewDefaultRegistry(codec Codec) *Registry {
+	registry := NewRegistry(codec)
+	for _, register := range []func(*Registry) error{
+		RegisterEvent[GeneralError],
+		RegisterEvent[OrderDispatched],
+		RegisterEvent[OrderDelivered],
+		RegisterEvent[OrderDeliveryFailed],
+		RegisterEvent[DeliveryAddressChangeFailed],
+		RegisterEvent[DeliveryAddressChanged],
+		RegisterEvent[OrderCreated],
+		RegisterEvent[EmailSent],
+	} {
+		if err := register(registry); err != nil {
+			panic(err)
+		}
+	}
+
+	return registry
+}
+
+// RegisterEvent регистрирует имя события T и декодер для него
+func RegisterEvent[T Event](registry *Registry) error {
+	eventType := reflect.TypeOf((*T)(nil)).Elem()
+	if eventType.Kind() == reflect.Interface {
+		return fmt.Errorf("%w: %s is an interface", ErrUnsupportedEvent, eventType)
+	}
+
+	event := zeroEvent(eventType)
+	return registry.register(event.Name(), &registration{
+		eventType: eventType,
+		version:   schemaVersionOf(event),
+		decode: func(codec Codec, data []byte) (Event, error) {
+			target := reflect.New(eventType)
+			if err := codec.Unmarshal(data, target.Interface()); err != nil {
+				return nil, err
+			}
+			return target.Elem().Interface().(Event), nil
+		},
+		upcasters: map[int]Upcaster{},
+	})
+}
+
+func (r *Registry) register(name string, entry *registration) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if existing, ok := r.entries[name]; ok {
+		if existing.eventType == entry.eventType {
+			return nil
+		}
+		return fmt.Errorf("%w: %s is %s, not %s", ErrDuplicateEventName, name, existing.eventType, entry.eventType)
+	}
+	r.entries[name] = entry
+
+	return nil
+}
+
+// AddUpcaster регистрирует перевод данных события name из версии from в from+1
+func (r *Registry) AddUpcaster(name string, from int, upcaster Upcaster) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	entry, ok := r.entries[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownEvent, name)
+	}
+	entry.upcasters[from] = upcaster
+
+	return nil
+}
+
+// Names возвращает отсортированные имена зарегистрированных событий
+func (r *Registry) Names() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	names := make([]string, 0, len(r.entries))
+	for name := range r.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func (r *Registry) Codec() Codec {
+	return r.codec
+}
+
+func (r *Registry) Encode(event Event) ([]byte, error) {
+	return r.codec.Marshal(event)
+}
+
+// Seal записывает событие конверта в Payload кодеком реестра. Данные
+// двоичного кодека, например ProtoCodec, хранятся в JSON конверта строкой
+// base64; ContentType подсказывает Open, как их прочитать
+func (r *Registry) Seal(envelope Envelope) (Envelope, error) {
+	data, err := r.codec.Marshal(envelope.Event)
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	envelope.ContentType = r.codec.ContentType()
+	if isJSON(envelope.ContentType) {
+		envelope.Payload = data
+		return envelope, nil
+	}
+
+	// []byte кодируется в JSON строкой base64
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return Envelope{}, err
+	}
+	envelope.Payload = payload
+	return envelope, nil
+}
+
+// Decode восстанавливает событие name, данные которого записаны кодеком
+// реестра в версии схемы version; старые версии проходят через цепочку
+// upcaster'ов
+func (r *Registry) Decode(name string, version int, payload []byte) (Event, error) {
+	return r.decode(r.codec, name, version, payload)
+}
+
+func (r *Registry) decode(codec Codec, name string, version int, payload []byte) (Event, error) {
+	r.mutex.RLock()
+	entry, ok := r.entries[name]
+	r.mutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEvent, name)
+	}
+
+	if version <= 0 {
+		version = 1
+	}
+	for ; version < entry.version; version++ {
+		r.mutex.RLock()
+		upcaster, ok := entry.upcasters[version]
+		r.mutex.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("%w: %s v%d", ErrMissingUpcaster, name, version)
+		}
+
+		upcasted, err := upcaster(payload)
+		if err != nil {
+			return nil, fmt.Errorf("upcast %s v%d: %w", name, version, err)
+		}
+		payload = upcasted
+	}
+
+	event, err := entry.decode(codec, payload)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", name, err)
+	}
+
+	return event, nil
+}
+
+// Open восстанавливает событие в конверте, прочитанном из JSON. Конверт
+// без ContentType содержит событие в JSON, остальные должны быть записаны
+// кодеком реестра
+func (r *Registry) Open(envelope Envelope) (Envelope, error) {
+	codec, payload, err := r.payloadOf(envelope)
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	event, err := r.decode(codec, envelope.Name, envelope.SchemaVersion, payload)
+	if err != nil {
+		return Envelope{}, err
+	}
+
+	envelope.Event = event
+	envelope.SchemaVersion = schemaVersionOf(event)
+	// при повторной записи конверт сериализует уже восстановленное событие
+	envelope.ContentType = ""
+	return envelope, nil
+}
+
+func (r *Registry) payloadOf(envelope Envelope) (Codec, []byte, error) {
+	if isJSON(envelope.ContentType) {
+		return JSONCodec{}, envelope.Payload, nil
+	}
+	if envelope.ContentType != r.codec.ContentType() {
+		return nil, nil, fmt.Errorf("%w: content type %s", ErrUnsupportedEvent, envelope.ContentType)
+	}
+
+	var payload []byte
+	if err := json.Unmarshal(envelope.Payload, &payload); err != nil {
+		return nil, nil, fmt.Errorf("decode %s: %w", envelope.Name, err)
+	}
+	return r.codec, payload, nil
+}
+
+func isJSON(contentType string) bool {
+	return contentType == "" || contentType == JSONCodec{}.ContentType()
+}
+
+func schemaVersionOf(event Event) int {
+	if versioned, ok := event.(Versioned); ok {
+		return versioned.SchemaVersion()
+	}
+	return 1
+}
@@ -0,0 +1,230 @@
+package events_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/events"
+	"github.com/google/uuid"
+)
+
+// customerRenamed - событие третьей версии схемы: в v2 поле name
+// переименовано в full_name, в v3 добавлено поле locale
+type customerRenamed struct {
+	FullName string `json:"full_name"`
+	Locale   string `json:"locale"`
+}
+
+func (customerRenamed) Name() string {
+	return "event.test.customer-renamed"
+}
+
+func (customerRenamed) SchemaVersion() int {
+	return 3
+}
+
+// ping реализует методы сообщений protoc-gen-gogo
+type ping struct {
+	Text string
+}
+
+func (ping) Name() string {
+	return "event.test.ping"
+}
+
+func (p ping) Marshal() ([]byte, error) {
+	return []byte(p.Text), nil
+}
+
+func (p *ping) Unmarshal(data []byte) error {
+	p.Text = string(data)
+	return nil
+}
+
+func renameField(from, to string) events.Upcaster {
+	return func(payload []byte) ([]byte, error) {
+		var fields map[string]interface{}
+		if err := json.Unmarshal(payload, &fields); err != nil {
+			return nil, err
+		}
+		fields[to] = fields[from]
+		delete(fields, from)
+		return json.Marshal(fields)
+	}
+}
+
+func TestDefaultRegistryDecodesEveryEvent(t *testing.T) {
+	registry := events.NewDefaultRegistry(events.JSONCodec{})
+	orderID := uuid.New()
+
+	for _, event := range []events.Event{
+		events.GeneralError("failure"),
+		events.NewOrderCreated(orderID),
+		events.NewOrderDispatched(orderID),
+		events.NewOrderDelivered(orderID),
+		events.NewOrderDeliveryFailed(orderID),
+		events.NewDeliveryAddressChanged(orderID),
+		events.NewDeliveryAddressChangeFailed(orderID),
+		events.NewEmailSent(uuid.New()),
+	} {
+		data, err := registry.Encode(event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := registry.Decode(event.Name(), 1, data)
+		if err != nil {
+			t.Fatalf("Decode(%s): %v", event.Name(), err)
+		}
+		if decoded != event {
+			t.Fatalf("%s decoded as %#v, want %#v", data, decoded, event)
+		}
+	}
+
+	if len(registry.Names()) != 8 {
+		t.Fatalf("Names() = %v", registry.Names())
+	}
+	if _, err := registry.Decode("event.order.cancelled", 1, []byte(`{}`)); !errors.Is(err, events.ErrUnknownEvent) {
+		t.Fatalf("unknown event returned %v", err)
+	}
+}
+
+func TestRegisterEventRejectsConflicts(t *testing.T) {
+	registry := events.NewDefaultRegistry(events.JSONCodec{})
+
+	if err := events.RegisterEvent[events.OrderCreated](registry); err != nil {
+		t.Fatalf("registering the same type again returned %v", err)
+	}
+	if err := events.RegisterEvent[impostor](registry); !errors.Is(err, events.ErrDuplicateEventName) {
+		t.Fatalf("duplicate name returned %v", err)
+	}
+	if err := events.RegisterEvent[events.OrderEvent](registry); !errors.Is(err, events.ErrUnsupportedEvent) {
+		t.Fatalf("interface returned %v", err)
+	}
+	if err := registry.AddUpcaster("event.test.unknown", 1, renameField("a", "b")); !errors.Is(err, events.ErrUnknownEvent) {
+		t.Fatalf("upcaster for an unknown event returned %v", err)
+	}
+}
+
+func TestUpcastersMigrateOldPayloads(t *testing.T) {
+	registry := events.NewRegistry(events.JSONCodec{})
+	if err := events.RegisterEvent[customerRenamed](registry); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.AddUpcaster(customerRenamed{}.Name(), 1, renameField("name", "full_name")); err != nil {
+		t.Fatal(err)
+	}
+
+	// без перевода из v2 в v3 старые данные не читаются
+	if _, err := registry.Decode(customerRenamed{}.Name(), 1, []byte(`{"name":"Иван"}`)); !errors.Is(err, events.ErrMissingUpcaster) {
+		t.Fatalf("Decode without the v2 upcaster returned %v", err)
+	}
+
+	if err := registry.AddUpcaster(customerRenamed{}.Name(), 2, func(payload []byte) ([]byte, error) {
+		return bytes.Replace(payload, []byte("}"), []byte(`,"locale":"ru"}`), 1), nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		version int
+		payload string
+	}{
+		{1, `{"name":"Иван"}`},
+		{2, `{"full_name":"Иван"}`},
+		{3, `{"full_name":"Иван","locale":"ru"}`},
+	}
+	for _, test := range tests {
+		event, err := registry.Decode(customerRenamed{}.Name(), test.version, []byte(test.payload))
+		if err != nil {
+			t.Fatalf("Decode v%d: %v", test.version, err)
+		}
+		if event != (customerRenamed{FullName: "Иван", Locale: "ru"}) {
+			t.Fatalf("v%d decoded as %+v", test.version, event)
+		}
+	}
+
+	// Open обновляет версию схемы конверта
+	envelope := events.Envelope{Name: customerRenamed{}.Name(), SchemaVersion: 1, Payload: json.RawMessage(`{"name":"Иван"}`)}
+	opened, err := registry.Open(envelope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opened.SchemaVersion != 3 || opened.Event != (customerRenamed{FullName: "Иван", Locale: "ru"}) {
+		t.Fatalf("Open() = %+v", opened)
+	}
+
+	failure := errors.New("broken payload")
+	if err := registry.AddUpcaster(customerRenamed{}.Name(), 1, func(payload []byte) ([]byte, error) {
+		return nil, failure
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := registry.Decode(customerRenamed{}.Name(), 1, []byte(`{}`)); !errors.Is(err, failure) {
+		t.Fatalf("failing upcaster returned %v", err)
+	}
+}
+
+func TestProtoCodecSealAndOpen(t *testing.T) {
+	registry := events.NewRegistry(events.ProtoCodec{})
+	if err := events.RegisterEvent[ping](registry); err != nil {
+		t.Fatal(err)
+	}
+
+	sealed, err := registry.Seal(events.NewEnvelope(ping{Text: "\x00binary\xff"}, occurredAt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sealed.ContentType != "application/x-protobuf" {
+		t.Fatalf("ContentType = %q", sealed.ContentType)
+	}
+
+	// конверт с двоичными данными переживает запись в JSON
+	data, err := json.Marshal(sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded events.Envelope
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	opened, err := registry.Open(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opened.ID != sealed.ID || !reflect.DeepEqual(opened.Event, ping{Text: "\x00binary\xff"}) {
+		t.Fatalf("Open() = %+v", opened)
+	}
+
+	if _, err := registry.Seal(events.NewEnvelope(events.NewOrderCreated(uuid.New()), occurredAt)); !errors.Is(err, events.ErrUnsupportedEvent) {
+		t.Fatalf("Seal of a JSON-only event returned %v", err)
+	}
+	decoded.ContentType = "application/xml"
+	if _, err := registry.Open(decoded); !errors.Is(err, events.ErrUnsupportedEvent) {
+		t.Fatalf("Open with an unknown content type returned %v", err)
+	}
+}
+
+func TestOpenReadsJSONEnvelopesWithAnyCodec(t *testing.T) {
+	registry := events.NewDefaultRegistry(events.ProtoCodec{})
+	event := events.NewOrderDelivered(uuid.New())
+
+	data, err := json.Marshal(events.NewEnvelope(event, occurredAt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var envelope events.Envelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		t.Fatal(err)
+	}
+
+	opened, err := registry.Open(envelope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opened.Event != event {
+		t.Fatalf("Open() = %+v", opened.Event)
+	}
+}
@@ -2,94 +2,317 @@ package events
 
 // инфраструктурный уровень
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"strconv"
+	"strings"
+	"sync"
 
 	//
 	// какой-то импорт
 	//
+	"github.com/MaksimDzhangirov/PracticalDDD/clock"
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/google/uuid"
 )
 
-// EventSQSHandler передаёт внутренние события во внешний мир
+var ErrNoRoute = errors.New("no SQS queue configured for event")
+
+// SQSClient - методы SQS, которые использует пакет. Его реализуют *sqs.SQS
+// и очередь в памяти memsqs.Client для локального запуска
+type SQSClient interface {
+	SendMessageWithContext(ctx aws.Context, input *sqs.SendMessageInput, options ...request.Option) (*sqs.SendMessageOutput, error)
+	SendMessageBatchWithContext(ctx aws.Context, input *sqs.SendMessageBatchInput, options ...request.Option) (*sqs.SendMessageBatchOutput, error)
+	ReceiveMessageWithContext(ctx aws.Context, input *sqs.ReceiveMessageInput, options ...request.Option) (*sqs.ReceiveMessageOutput, error)
+	DeleteMessageBatchWithContext(ctx aws.Context, input *sqs.DeleteMessageBatchInput, options ...request.Option) (*sqs.DeleteMessageBatchOutput, error)
+	ChangeMessageVisibilityWithContext(ctx aws.Context, input *sqs.ChangeMessageVisibilityInput, options ...request.Option) (*sqs.ChangeMessageVisibilityOutput, error)
+}
+
+// Атрибуты сообщения, по которым подписки SNS/SQS могут фильтровать события
+const (
+	AttributeEventName     = "EventName"
+	AttributeSchemaVersion = "SchemaVersion"
+	AttributeAggregateType = "AggregateType"
+)
+
+// maxSQSBatchBytes - ограничение SQS на суммарный размер пачки
+const maxSQSBatchBytes = 256 * 1024
+
+// EventSQSHandler передаёт внутренние события во внешний мир. Очередь
+// выбирается по имени события, затем по семейству (RouteFamily), затем
+// используется очередь по умолчанию. Для очередей с суффиксом ".fifo"
+// заполняются MessageGroupId (ID агрегата) и MessageDeduplicationId - ID
+// конверта события. Повторная отправка сохраняет ID, если событие
+// доставляется в ctx вместе со своим конвертом (ContextWithEnvelope), как
+// это делают EventPublisher, outbox и SQSService
 type EventSQSHandler struct {
-	svc *sqs.SQS
+	svc      SQSClient
+	queueURL string
+	clock    clock.Clock
+	mutex    sync.RWMutex
+	byName   map[string]string
+	families []familyRoute
+}
+
+type familyRoute struct {
+	matches  func(event Event) bool
+	queueURL string
 }
 
-// Notify передаёт события через SQS
-func (e *EventSQSHandler) Notify(event Event) {
-	data := map[string]string{
-		"event": event.Name(),
+// NewEventSQSHandler создаёт обработчик с очередью по умолчанию queueURL;
+// пустой queueURL означает, что события без маршрута не отправляются
+func NewEventSQSHandler(svc SQSClient, queueURL string, clock clock.Clock) *EventSQSHandler {
+	return &EventSQSHandler{
+		svc:      svc,
+		queueURL: queueURL,
+		clock:    clock,
+		byName:   map[string]string{},
 	}
+}
+
+// Route отправляет события с именем name в очередь queueURL
+func (e *EventSQSHandler) Route(name string, queueURL string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
 
-	body, err := json.Marshal(data)
+	e.byName[name] = queueURL
+}
+
+// RouteFamily отправляет в queueURL события, реализующие T, например OrderEvent
+func RouteFamily[T Event](handler *EventSQSHandler, queueURL string) {
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+
+	handler.families = append(handler.families, familyRoute{
+		matches: func(event Event) bool {
+			_, ok := event.(T)
+			return ok
+		},
+		queueURL: queueURL,
+	})
+}
+
+func (e *EventSQSHandler) route(event Event) (string, error) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	if queueURL, ok := e.byName[event.Name()]; ok {
+		return queueURL, nil
+	}
+	for _, family := range e.families {
+		if family.matches(event) {
+			return family.queueURL, nil
+		}
+	}
+	if e.queueURL == "" {
+		return "", fmt.Errorf("%w: %s", ErrNoRoute, event.Name())
+	}
+
+	return e.queueURL, nil
+}
+
+// outgoing - событие, подготовленное к отправке
+type outgoing struct {
+	event    Event
+	queueURL string
+	entry    *sqs.SendMessageBatchRequestEntry
+}
+
+func (e *EventSQSHandler) prepare(ctx context.Context, event Event) (outgoing, error) {
+	queueURL, err := e.route(event)
 	if err != nil {
-		log.Fatal(err)
+		return outgoing{}, err
 	}
 
-	_, err = e.svc.SendMessage(&sqs.SendMessageInput{
+	envelope := Wrap(ctx, event, e.clock)
+	body, err := json.Marshal(envelope)
+	if err != nil {
+		return outgoing{}, err
+	}
+	entry := &sqs.SendMessageBatchRequestEntry{
 		MessageBody: aws.String(string(body)),
-		QueueUrl:    &e.svc.Endpoint,
-	})
+		MessageAttributes: map[string]*sqs.MessageAttributeValue{
+			AttributeEventName: {
+				DataType:    aws.String("String"),
+				StringValue: aws.String(envelope.Name),
+			},
+			AttributeSchemaVersion: {
+				DataType:    aws.String("Number"),
+				StringValue: aws.String(strconv.Itoa(envelope.SchemaVersion)),
+			},
+		},
+	}
+	if envelope.AggregateType != "" {
+		entry.MessageAttributes[AttributeAggregateType] = &sqs.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(envelope.AggregateType),
+		}
+	}
+	if isFIFO(queueURL) {
+		// события без агрегата упорядочиваются в пределах своего имени
+		group := envelope.Name
+		if envelope.AggregateID != uuid.Nil {
+			group = envelope.AggregateID.String()
+		}
+		entry.MessageGroupId = aws.String(group)
+		entry.MessageDeduplicationId = aws.String(envelope.ID.String())
+	}
+
+	return outgoing{event: event, queueURL: queueURL, entry: entry}, nil
+}
+
+// Handle передаёт событие через SQS в конверте с метаданными; ошибки
+// возвращаются издателю, который может повторить отправку или передать
+// событие в DeadLetterSink
+func (e *EventSQSHandler) Handle(ctx context.Context, event Event) error {
+	message, err := e.prepare(ctx, event)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
+
+	_, err = e.svc.SendMessageWithContext(ctx, &sqs.SendMessageInput{
+		QueueUrl:               aws.String(message.queueURL),
+		MessageBody:            message.entry.MessageBody,
+		MessageAttributes:      message.entry.MessageAttributes,
+		MessageGroupId:         message.entry.MessageGroupId,
+		MessageDeduplicationId: message.entry.MessageDeduplicationId,
+	})
+	return err
 }
 
-// инфраструктурный уровень
-type SQSService struct {
-	svc         *sqs.SQS
-	publisher   *EventPublisher
-	stopChannel chan bool
+// SQSBatchFailure - событие, которое SQS не принял при пакетной отправке
+type SQSBatchFailure struct {
+	Event       Event
+	Code        string
+	Message     string
+	SenderFault bool
+}
+
+// SQSBatchError перечисляет не отправленные события; остальные события
+// пачки отправлены и повторять их не нужно
+type SQSBatchError struct {
+	Failures []SQSBatchFailure
+	// Err - ошибка запросов, которые не выполнились целиком
+	Err error
+}
+
+func (e *SQSBatchError) Error() string {
+	message := fmt.Sprintf("%d events were not sent to SQS", len(e.Failures))
+	if e.Err != nil {
+		message += ": " + e.Err.Error()
+	}
+	return message
+}
+
+func (e *SQSBatchError) Unwrap() error {
+	return e.Err
 }
 
-// Run запускает прослушивание SQS сообщений
-func (s *SQSService) Run(event Event) {
-	eventChan := make(chan Event)
+// Events возвращает не отправленные события для повторной отправки
+func (e *SQSBatchError) Events() []Event {
+	result := make([]Event, len(e.Failures))
+	for i, failure := range e.Failures {
+		result[i] = failure.Event
+	}
+	return result
+}
 
-MessageLoop:
-	for {
-		s.listen(eventChan)
+// Publish отправляет события через SendMessageBatch пачками по 10
+// сообщений и не более 256 КБ, порядок событий внутри очереди сохраняется
+func (e *EventSQSHandler) Publish(ctx context.Context, events ...Event) error {
+	var order []string
+	byQueue := map[string][]outgoing{}
+	batchErr := &SQSBatchError{}
+	var errs []error
 
-		select {
-		case event := <-eventChan:
-			s.publisher.Notify(event)
-		case <-s.stopChannel:
-			break MessageLoop
+	for _, event := range events {
+		message, err := e.prepare(ctx, event)
+		if err != nil {
+			batchErr.Failures = append(batchErr.Failures, SQSBatchFailure{Event: event, Message: err.Error(), SenderFault: true})
+			errs = append(errs, err)
+			continue
+		}
+		if _, ok := byQueue[message.queueURL]; !ok {
+			order = append(order, message.queueURL)
 		}
+		byQueue[message.queueURL] = append(byQueue[message.queueURL], message)
 	}
 
-	close(eventChan)
-	close(s.stopChannel)
-}
+	for _, queueURL := range order {
+		for _, batch := range splitBatch(byQueue[queueURL]) {
+			if err := e.sendBatch(ctx, queueURL, batch, batchErr); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
 
-// Stop останавливает прослушивание SQS сообщений
-func (s *SQSService) Stop() {
-	s.stopChannel <- true
+	if len(batchErr.Failures) == 0 {
+		return nil
+	}
+	batchErr.Err = errors.Join(errs...)
+	return batchErr
 }
 
-func (s *SQSService) listen(eventChan chan Event) {
-	go func() {
-		message, err := s.svc.ReceiveMessage(&sqs.ReceiveMessageInput{
-			//
-			// какой-то код
-			//
-		})
-		fmt.Println(message)
+func (e *EventSQSHandler) sendBatch(ctx context.Context, queueURL string, batch []outgoing, batchErr *SQSBatchError) error {
+	// Id записи нужен только для сопоставления ошибок, поэтому это номер в пачке
+	entries := make([]*sqs.SendMessageBatchRequestEntry, len(batch))
+	for i, message := range batch {
+		entry := *message.entry
+		entry.Id = aws.String(strconv.Itoa(i))
+		entries[i] = &entry
+	}
 
+	output, err := e.svc.SendMessageBatchWithContext(ctx, &sqs.SendMessageBatchInput{
+		QueueUrl: aws.String(queueURL),
+		Entries:  entries,
+	})
+	if err != nil {
+		for _, message := range batch {
+			batchErr.Failures = append(batchErr.Failures, SQSBatchFailure{Event: message.event, Message: err.Error()})
+		}
+		return err
+	}
+
+	for _, failed := range output.Failed {
 		var event Event
-		if err != nil {
-			log.Print(err)
-			event = NewGeneralError(err)
-			return
-		} else {
-			//
-			// извлечь сообщение
-			//
+		if i, err := strconv.Atoi(aws.StringValue(failed.Id)); err == nil && i >= 0 && i < len(batch) {
+			event = batch[i].event
+		}
+		batchErr.Failures = append(batchErr.Failures, SQSBatchFailure{
+			Event:       event,
+			Code:        aws.StringValue(failed.Code),
+			Message:     aws.StringValue(failed.Message),
+			SenderFault: aws.BoolValue(failed.SenderFault),
+		})
+	}
+	return nil
+}
+
+func splitBatch(messages []outgoing) [][]outgoing {
+	var batches [][]outgoing
+	var current []outgoing
+	size := 0
+
+	for _, message := range messages {
+		messageSize := len(aws.StringValue(message.entry.MessageBody))
+		if len(current) == maxSQSBatch || (len(current) > 0 && size+messageSize > maxSQSBatchBytes) {
+			batches = append(batches, current)
+			current, size = nil, 0
 		}
+		current = append(current, message)
+		size += messageSize
+	}
+	if len(current) > 0 {
+		batches = append(batches, current)
+	}
+
+	return batches
+}
 
-		eventChan <- event
-	}()
+func isFIFO(queueURL string) bool {
+	return strings.HasSuffix(queueURL, ".fifo")
 }
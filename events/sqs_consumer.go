@@ -0,0 +1,349 @@
+package events
+
+// инфраструктурный уровень
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// maxSQSBatch - ограничение SQS на размер пачки при чтении и удалении
+const maxSQSBatch = 10
+
+// SQSConsumerConfig - настройки SQSService, нулевые значения заменяются значениями по умолчанию
+type SQSConsumerConfig struct {
+	// Concurrency - количество одновременно обрабатываемых сообщений, по умолчанию 1
+	Concurrency int
+	// BatchSize - сколько сообщений читать за один запрос, от 1 до 10, по умолчанию 10
+	BatchSize int
+	// WaitTime - длительность long polling, по умолчанию 20 секунд
+	WaitTime time.Duration
+	// VisibilityTimeout продлевается каждые VisibilityTimeout/2, пока сообщение
+	// ждёт свободного обработчика или обрабатывается
+	VisibilityTimeout time.Duration
+	// DeadLetterQueueURL - очередь для сообщений, которые не удалось обработать
+	// MaxReceives раз; если не задана, повторы не ограничиваются
+	DeadLetterQueueURL string
+	MaxReceives        int
+	// OnError получает ошибки обработки и удаления, по умолчанию они пишутся в лог
+	OnError func(message *sqs.Message, err error)
+}
+
+func (c SQSConsumerConfig) withDefaults() SQSConsumerConfig {
+	if c.Concurrency <= 0 {
+		c.Concurrency = 1
+	}
+	if c.BatchSize <= 0 || c.BatchSize > maxSQSBatch {
+		c.BatchSize = maxSQSBatch
+	}
+	if c.WaitTime <= 0 {
+		c.WaitTime = 20 * time.Second
+	}
+	if c.VisibilityTimeout < time.Second {
+		c.VisibilityTimeout = 30 * time.Second
+	}
+	if c.OnError == nil {
+		c.OnError = func(message *sqs.Message, err error) {
+			log.Printf("sqs message %s: %v", aws.StringValue(message.MessageId), err)
+		}
+	}
+	return c
+}
+
+// SQSService читает события из очереди SQS и передаёт их издателю.
+// Сообщение удаляется только после успешной обработки, поэтому издатель
+// должен работать в режиме DispatchSync
+type SQSService struct {
+	svc       SQSClient
+	queueURL  string
+	publisher Notifier
+	registry  *Registry
+	config    SQSConsumerConfig
+}
+
+func NewSQSService(svc SQSClient, queueURL string, publisher Notifier, registry *Registry, config SQSConsumerConfig) *SQSService {
+	return &SQSService{
+		svc:       svc,
+		queueURL:  queueURL,
+		publisher: publisher,
+		registry:  registry,
+		config:    config.withDefaults(),
+	}
+}
+
+// Run передаёт сообщения издателю, пока не будет отменён ctx
+func (s *SQSService) Run(ctx context.Context) error {
+	return s.Consume(ctx, HandlerFunc(s.publisher.Notify))
+}
+
+// Consume читает сообщения, пока не будет отменён ctx или не вернёт ошибку
+// ReceiveMessage (SDK к этому моменту уже повторил запрос). После остановки
+// новые сообщения не запрашиваются, а уже переданные обработчику
+// обрабатываются до конца. Ошибки удаления сообщений передаются в OnError
+// сразу, как только происходят
+func (s *SQSService) Consume(ctx context.Context, handler Handler) error {
+	// обработка и удаление не должны прерываться вместе с чтением
+	work := context.WithoutCancel(ctx)
+
+	messages := make(chan receivedMessage)
+	acks := make(chan *sqs.Message, s.config.BatchSize)
+
+	var workers sync.WaitGroup
+	for i := 0; i < s.config.Concurrency; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for received := range messages {
+				if s.process(work, received, handler) {
+					acks <- received.message
+				}
+			}
+		}()
+	}
+
+	deleted := make(chan struct{})
+	go func() {
+		defer close(deleted)
+		s.deleteAcknowledged(work, acks)
+	}()
+
+	err := s.poll(ctx, work, messages)
+
+	close(messages)
+	workers.Wait()
+	close(acks)
+	<-deleted
+
+	return err
+}
+
+// receivedMessage - полученное сообщение, видимость которого продлевается,
+// пока оно ждёт свободного обработчика и обрабатывается
+type receivedMessage struct {
+	message *sqs.Message
+	stop    func()
+}
+
+func (s *SQSService) poll(ctx, work context.Context, messages chan<- receivedMessage) error {
+	for ctx.Err() == nil {
+		output, err := s.svc.ReceiveMessageWithContext(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String(s.queueURL),
+			MaxNumberOfMessages: aws.Int64(int64(s.config.BatchSize)),
+			WaitTimeSeconds:     aws.Int64(int64(s.config.WaitTime / time.Second)),
+			VisibilityTimeout:   aws.Int64(int64(s.config.VisibilityTimeout / time.Second)),
+			AttributeNames: aws.StringSlice([]string{
+				sqs.MessageSystemAttributeNameApproximateReceiveCount,
+				sqs.MessageSystemAttributeNameMessageGroupId,
+			}),
+			MessageAttributeNames: aws.StringSlice([]string{sqs.QueueAttributeNameAll}),
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("receive messages: %w", err)
+		}
+
+		// пачка может ждать обработчиков дольше VisibilityTimeout,
+		// поэтому видимость продлевается сразу для всех сообщений
+		batch := make([]receivedMessage, len(output.Messages))
+		for i, message := range output.Messages {
+			batch[i] = receivedMessage{message: message, stop: s.extendVisibility(work, message)}
+		}
+
+		for i, received := range batch {
+			select {
+			case messages <- received:
+			case <-ctx.Done():
+				// необработанные сообщения снова станут видимы после VisibilityTimeout
+				for _, rest := range batch[i:] {
+					rest.stop()
+				}
+				return nil
+			}
+		}
+	}
+
+	return nil
+}
+
+// process возвращает true, если сообщение можно удалить из очереди
+func (s *SQSService) process(ctx context.Context, received receivedMessage, handler Handler) bool {
+	message := received.message
+	err := s.handle(ctx, message, handler)
+	received.stop()
+	if err == nil {
+		return true
+	}
+
+	s.config.OnError(message, err)
+	if !s.exhausted(message) {
+		return false
+	}
+
+	if err := s.redrive(ctx, message); err != nil {
+		s.config.OnError(message, err)
+		return false
+	}
+	return true
+}
+
+func (s *SQSService) handle(ctx context.Context, message *sqs.Message, handler Handler) error {
+	envelope, err := s.decode(message)
+	if err != nil {
+		return err
+	}
+
+	return handler.Handle(ContextWithEnvelope(ctx, envelope), envelope.Event)
+}
+
+// decode восстанавливает событие из конверта в теле сообщения
+func (s *SQSService) decode(message *sqs.Message) (Envelope, error) {
+	var envelope Envelope
+	if err := json.Unmarshal([]byte(aws.StringValue(message.Body)), &envelope); err != nil {
+		return Envelope{}, err
+	}
+
+	return s.registry.Open(envelope)
+}
+
+// extendVisibility не даёт сообщению стать видимым другим получателям,
+// пока медленный обработчик не закончит работу
+func (s *SQSService) extendVisibility(ctx context.Context, message *sqs.Message) func() {
+	done := make(chan struct{})
+	finished := make(chan struct{})
+
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(s.config.VisibilityTimeout / 2)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				_, err := s.svc.ChangeMessageVisibilityWithContext(ctx, &sqs.ChangeMessageVisibilityInput{
+					QueueUrl:          aws.String(s.queueURL),
+					ReceiptHandle:     message.ReceiptHandle,
+					VisibilityTimeout: aws.Int64(int64(s.config.VisibilityTimeout / time.Second)),
+				})
+				if err != nil {
+					s.config.OnError(message, err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
+func (s *SQSService) exhausted(message *sqs.Message) bool {
+	if s.config.DeadLetterQueueURL == "" || s.config.MaxReceives <= 0 {
+		return false
+	}
+
+	count, err := strconv.Atoi(aws.StringValue(message.Attributes[sqs.MessageSystemAttributeNameApproximateReceiveCount]))
+	return err == nil && count >= s.config.MaxReceives
+}
+
+// redrive пересылает сообщение в очередь недоставленных. В FIFO-очереди
+// сообщение остаётся в своей группе, а повторная пересылка того же
+// сообщения отбрасывается по MessageDeduplicationId
+func (s *SQSService) redrive(ctx context.Context, message *sqs.Message) error {
+	input := &sqs.SendMessageInput{
+		QueueUrl:          aws.String(s.config.DeadLetterQueueURL),
+		MessageBody:       message.Body,
+		MessageAttributes: message.MessageAttributes,
+	}
+	if isFIFO(s.config.DeadLetterQueueURL) {
+		groupID := message.Attributes[sqs.MessageSystemAttributeNameMessageGroupId]
+		if aws.StringValue(groupID) == "" {
+			groupID = message.MessageId
+		}
+		input.MessageGroupId = groupID
+		input.MessageDeduplicationId = message.MessageId
+	}
+
+	_, err := s.svc.SendMessageWithContext(ctx, input)
+	return err
+}
+
+// deleteAcknowledged удаляет обработанные сообщения пачками;
+// не удалённые сообщения будут получены повторно
+func (s *SQSService) deleteAcknowledged(ctx context.Context, acks <-chan *sqs.Message) {
+	// после обработки видимость уже не продлевается, и у сообщения остаётся
+	// не меньше VisibilityTimeout/2, поэтому пачка отправляется чаще
+	interval := s.config.VisibilityTimeout / 4
+	if interval > time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var batch []*sqs.Message
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		s.deleteBatch(ctx, batch)
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case message, ok := <-acks:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, message)
+			if len(batch) == maxSQSBatch {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// deleteBatch передаёт ошибки удаления в OnError для каждого сообщения
+func (s *SQSService) deleteBatch(ctx context.Context, messages []*sqs.Message) {
+	entries := make([]*sqs.DeleteMessageBatchRequestEntry, len(messages))
+	for i, message := range messages {
+		entries[i] = &sqs.DeleteMessageBatchRequestEntry{
+			Id:            aws.String(strconv.Itoa(i)),
+			ReceiptHandle: message.ReceiptHandle,
+		}
+	}
+
+	output, err := s.svc.DeleteMessageBatchWithContext(ctx, &sqs.DeleteMessageBatchInput{
+		QueueUrl: aws.String(s.queueURL),
+		Entries:  entries,
+	})
+	if err != nil {
+		for _, message := range messages {
+			s.config.OnError(message, fmt.Errorf("delete message: %w", err))
+		}
+		return
+	}
+
+	// не удалённые сообщения будут получены повторно, обработчики идемпотентны
+	for _, failed := range output.Failed {
+		i, err := strconv.Atoi(aws.StringValue(failed.Id))
+		if err != nil || i < 0 || i >= len(messages) {
+			continue
+		}
+		s.config.OnError(messages[i], fmt.Errorf("delete message: %s", aws.StringValue(failed.Message)))
+	}
+}
@@ -0,0 +1,297 @@
+package events_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/clock"
+	"github.com/MaksimDzhangirov/PracticalDDD/events"
+	"github.com/MaksimDzhangirov/PracticalDDD/events/memsqs"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/google/uuid"
+)
+
+const (
+	queueURL = "https://sqs.local/events.fifo"
+	dlqURL   = "https://sqs.local/events-dlq.fifo"
+)
+
+func send(t *testing.T, client *memsqs.Client, event events.Event) {
+	t.Helper()
+
+	publisher := events.NewEventSQSHandler(client, queueURL, clock.System)
+	if err := publisher.Handle(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// extendingClient запоминает сообщения, видимость которых продлевалась
+type extendingClient struct {
+	*memsqs.Client
+	mutex    sync.Mutex
+	extended map[string]bool
+}
+
+func newExtendingClient(now clock.Clock) *extendingClient {
+	return &extendingClient{
+		Client:   memsqs.New(memsqs.WithClock(now)),
+		extended: map[string]bool{},
+	}
+}
+
+func (c *extendingClient) ChangeMessageVisibilityWithContext(ctx aws.Context, input *sqs.ChangeMessageVisibilityInput, options ...request.Option) (*sqs.ChangeMessageVisibilityOutput, error) {
+	output, err := c.Client.ChangeMessageVisibilityWithContext(ctx, input, options...)
+	if err == nil {
+		c.mutex.Lock()
+		c.extended[aws.StringValue(input.ReceiptHandle)] = true
+		c.mutex.Unlock()
+	}
+	return output, err
+}
+
+// outliveVisibility переводит часы дальше VisibilityTimeout в 1 секунду
+// шагами по 900 мс; перед каждым шагом SQSService должен удалить
+// обработанные сообщения и продлить видимость всех inFlight сообщений
+func (c *extendingClient) outliveVisibility(now *clock.Manual, inFlight int) error {
+	for step := 0; step < 2; step++ {
+		c.mutex.Lock()
+		c.extended = map[string]bool{}
+		c.mutex.Unlock()
+
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			c.mutex.Lock()
+			extended := len(c.extended)
+			c.mutex.Unlock()
+			if extended >= inFlight && c.Len(queueURL) <= inFlight {
+				break
+			}
+			if time.Now().After(deadline) {
+				return errors.New("visibility was not extended")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		now.Advance(900 * time.Millisecond)
+	}
+	return nil
+}
+
+// consume запускает Consume и останавливает его после stop
+func consume(service *events.SQSService, handler events.Handler) (stop func() error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() {
+		result <- service.Consume(ctx, handler)
+	}()
+
+	return func() error {
+		cancel()
+		return <-result
+	}
+}
+
+func TestSQSServiceDeletesHandledMessages(t *testing.T) {
+	client := memsqs.New()
+	orderID := uuid.New()
+	send(t, client, events.NewOrderCreated(orderID))
+
+	received := make(chan events.Event, 1)
+	service := events.NewSQSService(client, queueURL, nil, events.NewDefaultRegistry(events.JSONCodec{}), events.SQSConsumerConfig{
+		WaitTime: time.Second,
+	})
+	stop := consume(service, events.HandlerFunc(func(ctx context.Context, event events.Event) error {
+		received <- event
+		return nil
+	}))
+
+	select {
+	case event := <-received:
+		created, ok := event.(events.OrderCreated)
+		if !ok || created.OrderID() != orderID {
+			t.Fatalf("received %#v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("message was not received")
+	}
+
+	if err := stop(); err != nil {
+		t.Fatal(err)
+	}
+	if n := client.Len(queueURL); n != 0 {
+		t.Fatalf("queue has %d messages after ack, want 0", n)
+	}
+}
+
+func TestSQSServiceRedrivesToFIFODeadLetterQueue(t *testing.T) {
+	now := clock.NewManual(time.Now())
+	client := memsqs.New(memsqs.WithClock(now))
+	orderID := uuid.New()
+	send(t, client, events.NewOrderCreated(orderID))
+
+	attempts := make(chan struct{}, 10)
+	service := events.NewSQSService(client, queueURL, nil, events.NewDefaultRegistry(events.JSONCodec{}), events.SQSConsumerConfig{
+		WaitTime:           time.Second,
+		VisibilityTimeout:  time.Second,
+		DeadLetterQueueURL: dlqURL,
+		MaxReceives:        2,
+		OnError:            func(message *sqs.Message, err error) {},
+	})
+	stop := consume(service, events.HandlerFunc(func(ctx context.Context, event events.Event) error {
+		attempts <- struct{}{}
+		return errors.New("handler failed")
+	}))
+
+	// после неудачи сообщение снова становится видимым, когда истекает VisibilityTimeout
+	timeout := time.After(5 * time.Second)
+	for i := 0; i < 2; {
+		select {
+		case <-attempts:
+			i++
+		case <-time.After(20 * time.Millisecond):
+			now.Advance(time.Second)
+		case <-timeout:
+			t.Fatalf("attempt %d was not made", i+1)
+		}
+	}
+	if err := stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := client.Len(queueURL); n != 0 {
+		t.Fatalf("queue has %d messages after redrive, want 0", n)
+	}
+	output, err := client.ReceiveMessageWithContext(context.Background(), &sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String(dlqURL),
+		MaxNumberOfMessages: aws.Int64(10),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(output.Messages) != 1 {
+		t.Fatalf("dead letter queue has %d messages, want 1", len(output.Messages))
+	}
+	group := output.Messages[0].Attributes[sqs.MessageSystemAttributeNameMessageGroupId]
+	if aws.StringValue(group) != orderID.String() {
+		t.Fatalf("dead letter group %q, want %q", aws.StringValue(group), orderID)
+	}
+}
+
+func TestSQSServiceExtendsVisibilityOfSlowMessages(t *testing.T) {
+	now := clock.NewManual(time.Now())
+	client := newExtendingClient(now)
+	send(t, client.Client, events.NewOrderCreated(uuid.New()))
+
+	done := make(chan error, 1)
+	service := events.NewSQSService(client, queueURL, nil, events.NewDefaultRegistry(events.JSONCodec{}), events.SQSConsumerConfig{
+		WaitTime:          time.Second,
+		VisibilityTimeout: time.Second,
+	})
+	stop := consume(service, events.HandlerFunc(func(ctx context.Context, event events.Event) error {
+		// обработка дольше VisibilityTimeout: сообщение не должно стать видимым
+		if err := client.outliveVisibility(now, 1); err != nil {
+			done <- err
+			return nil
+		}
+		output, err := client.ReceiveMessageWithContext(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String(queueURL),
+			MaxNumberOfMessages: aws.Int64(10),
+		})
+		if err == nil && len(output.Messages) > 0 {
+			err = errors.New("message became visible while it was handled")
+		}
+		done <- err
+		return nil
+	}))
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("message was not handled")
+	}
+	if err := stop(); err != nil {
+		t.Fatal(err)
+	}
+	if n := client.Len(queueURL); n != 0 {
+		t.Fatalf("queue has %d messages after ack, want 0", n)
+	}
+}
+
+func TestSQSServiceExtendsVisibilityOfWaitingMessages(t *testing.T) {
+	now := clock.NewManual(time.Now())
+	client := newExtendingClient(now)
+	send(t, client.Client, events.NewOrderCreated(uuid.New()))
+	send(t, client.Client, events.NewOrderCreated(uuid.New()))
+
+	handled := make(chan uuid.UUID, 10)
+	failures := make(chan error, 2)
+	inFlight := 2
+	service := events.NewSQSService(client, queueURL, nil, events.NewDefaultRegistry(events.JSONCodec{}), events.SQSConsumerConfig{
+		WaitTime:          time.Second,
+		VisibilityTimeout: time.Second,
+	})
+	stop := consume(service, events.HandlerFunc(func(ctx context.Context, event events.Event) error {
+		// второе сообщение пачки ждёт обработчика дольше VisibilityTimeout
+		if err := client.outliveVisibility(now, inFlight); err != nil {
+			failures <- err
+		}
+		inFlight--
+		handled <- event.(events.OrderCreated).OrderID()
+		return nil
+	}))
+
+	seen := map[uuid.UUID]bool{}
+	timeout := time.After(8 * time.Second)
+	for len(seen) < 2 {
+		select {
+		case orderID := <-handled:
+			if seen[orderID] {
+				t.Fatalf("order %s was handled twice", orderID)
+			}
+			seen[orderID] = true
+		case err := <-failures:
+			t.Fatal(err)
+		case <-timeout:
+			t.Fatalf("handled %d messages, want 2", len(seen))
+		}
+	}
+	if err := stop(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case orderID := <-handled:
+		t.Fatalf("order %s was handled twice", orderID)
+	default:
+	}
+	if n := client.Len(queueURL); n != 0 {
+		t.Fatalf("queue has %d messages after ack, want 0", n)
+	}
+}
+
+// failingClient не может прочитать очередь
+type failingClient struct {
+	*memsqs.Client
+}
+
+func (failingClient) ReceiveMessageWithContext(ctx aws.Context, input *sqs.ReceiveMessageInput, options ...request.Option) (*sqs.ReceiveMessageOutput, error) {
+	return nil, errors.New("queue does not exist")
+}
+
+func TestSQSServiceReturnsReceiveError(t *testing.T) {
+	service := events.NewSQSService(failingClient{memsqs.New()}, queueURL, nil, events.NewDefaultRegistry(events.JSONCodec{}), events.SQSConsumerConfig{})
+
+	err := service.Consume(context.Background(), events.HandlerFunc(func(ctx context.Context, event events.Event) error {
+		return nil
+	}))
+	if err == nil {
+		t.Fatal("Consume returned nil for a failing queue")
+	}
+}
@@ -0,0 +1,177 @@
+package events_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/clock"
+	"github.com/MaksimDzhangirov/PracticalDDD/events"
+	"github.com/MaksimDzhangirov/PracticalDDD/events/memsqs"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/google/uuid"
+)
+
+func TestEventSQSHandlerDeduplicatesRetries(t *testing.T) {
+	client := memsqs.New()
+	publisher := events.NewEventSQSHandler(client, queueURL, clock.System)
+	event := events.NewOrderCreated(uuid.New())
+
+	// повторная отправка события с тем же конвертом не создаёт второе сообщение
+	ctx := events.ContextWithEnvelope(context.Background(), events.NewEnvelope(event, clock.System))
+	for i := 0; i < 2; i++ {
+		if err := publisher.Handle(ctx, event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := publisher.Publish(ctx, event); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := client.Len(queueURL); n != 1 {
+		t.Fatalf("queue has %d messages, want 1", n)
+	}
+}
+
+func TestEventSQSHandlerSendsEventsWithSameContent(t *testing.T) {
+	client := memsqs.New()
+	publisher := events.NewEventSQSHandler(client, queueURL, clock.System)
+	orderID := uuid.New()
+
+	// два разных события с одинаковыми данными - это два сообщения
+	if err := publisher.Handle(context.Background(), events.NewOrderDispatched(orderID)); err != nil {
+		t.Fatal(err)
+	}
+	if err := publisher.Publish(context.Background(), events.NewOrderDispatched(orderID), events.NewOrderDispatched(orderID)); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := client.Len(queueURL); n != 3 {
+		t.Fatalf("queue has %d messages, want 3", n)
+	}
+}
+
+func TestPublisherRetriesKeepDeduplicationID(t *testing.T) {
+	client := &flakyClient{Client: memsqs.New(), failures: 1}
+	handler := events.NewEventSQSHandler(client, queueURL, clock.System)
+	publisher := events.NewEventPublisher(events.WithRetry(events.RetryPolicy{Attempts: 2, Initial: time.Millisecond}))
+	if _, err := publisher.SubscribeHandler(handler, events.OrderCreated{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// первая отправка дошла до очереди, но клиент вернул ошибку
+	if err := publisher.Notify(context.Background(), events.NewOrderCreated(uuid.New())); err != nil {
+		t.Fatal(err)
+	}
+	if n := client.Len(queueURL); n != 1 {
+		t.Fatalf("queue has %d messages after a retry, want 1", n)
+	}
+}
+
+// flakyClient отправляет сообщение, но первые failures раз сообщает об ошибке
+type flakyClient struct {
+	*memsqs.Client
+	failures int
+}
+
+func (c *flakyClient) SendMessageWithContext(ctx aws.Context, input *sqs.SendMessageInput, options ...request.Option) (*sqs.SendMessageOutput, error) {
+	output, err := c.Client.SendMessageWithContext(ctx, input, options...)
+	if err == nil && c.failures > 0 {
+		c.failures--
+		return nil, errors.New("connection reset")
+	}
+	return output, err
+}
+
+// rejectingClient отклоняет записи пачки с MessageGroupId из rejected
+type rejectingClient struct {
+	*memsqs.Client
+	rejected map[string]bool
+}
+
+func (c rejectingClient) SendMessageBatchWithContext(ctx aws.Context, input *sqs.SendMessageBatchInput, options ...request.Option) (*sqs.SendMessageBatchOutput, error) {
+	accepted := *input
+	accepted.Entries = nil
+	var failed []*sqs.BatchResultErrorEntry
+	for _, entry := range input.Entries {
+		if c.rejected[aws.StringValue(entry.MessageGroupId)] {
+			failed = append(failed, &sqs.BatchResultErrorEntry{
+				Id:      entry.Id,
+				Code:    aws.String("InvalidMessageContents"),
+				Message: aws.String("rejected"),
+			})
+			continue
+		}
+		accepted.Entries = append(accepted.Entries, entry)
+	}
+
+	output, err := c.Client.SendMessageBatchWithContext(ctx, &accepted, options...)
+	if err != nil {
+		return nil, err
+	}
+	output.Failed = append(output.Failed, failed...)
+	return output, nil
+}
+
+func TestPublishReportsPartialFailures(t *testing.T) {
+	rejected := events.NewOrderDelivered(uuid.New())
+	client := rejectingClient{Client: memsqs.New(), rejected: map[string]bool{rejected.OrderID().String(): true}}
+	publisher := events.NewEventSQSHandler(client, queueURL, clock.System)
+
+	batch := []events.Event{rejected}
+	for i := 0; i < 11; i++ {
+		batch = append(batch, events.NewOrderCreated(uuid.New()))
+	}
+	err := publisher.Publish(context.Background(), batch...)
+
+	var batchErr *events.SQSBatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Failures) != 1 {
+		t.Fatalf("Publish returned %v, want one failure", err)
+	}
+	failure := batchErr.Failures[0]
+	if failure.Event != rejected || failure.Code != "InvalidMessageContents" {
+		t.Fatalf("failure = %+v", failure)
+	}
+	if n := client.Len(queueURL); n != 11 {
+		t.Fatalf("queue has %d messages, want 11", n)
+	}
+}
+
+func TestPublishRoutesEvents(t *testing.T) {
+	const (
+		emailQueue    = "https://sqs.local/emails"
+		deliveryQueue = "https://sqs.local/deliveries.fifo"
+	)
+	client := memsqs.New()
+	publisher := events.NewEventSQSHandler(client, "", clock.System)
+	publisher.Route(events.OrderDelivered{}.Name(), deliveryQueue)
+	events.RouteFamily[events.EmailEvent](publisher, emailQueue)
+
+	err := publisher.Publish(context.Background(),
+		events.NewEmailSent(uuid.New()),
+		events.NewOrderDelivered(uuid.New()),
+		events.NewOrderCreated(uuid.New()),
+	)
+
+	var batchErr *events.SQSBatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Failures) != 1 || !errors.Is(err, events.ErrNoRoute) {
+		t.Fatalf("Publish returned %v, want ErrNoRoute for OrderCreated", err)
+	}
+	if client.Len(emailQueue) != 1 || client.Len(deliveryQueue) != 1 {
+		t.Fatalf("queues have %d and %d messages, want 1 and 1", client.Len(emailQueue), client.Len(deliveryQueue))
+	}
+
+	output, err := client.ReceiveMessageWithContext(context.Background(), &sqs.ReceiveMessageInput{QueueUrl: aws.String(emailQueue)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	attributes := output.Messages[0].MessageAttributes
+	if aws.StringValue(attributes[events.AttributeEventName].StringValue) != "event.email.sent" ||
+		aws.StringValue(attributes[events.AttributeSchemaVersion].StringValue) != "1" ||
+		aws.StringValue(attributes[events.AttributeAggregateType].StringValue) != events.AggregateEmail {
+		t.Fatalf("message attributes = %v", attributes)
+	}
+}
@@ -0,0 +1,134 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/clock"
+)
+
+// Sender отправляет события во внешний мир: SQS, брокер сообщений, файл
+type Sender interface {
+	Publish(ctx context.Context, events ...Event) error
+}
+
+// Receiver получает события извне и передаёт их handler'у, пока не будет
+// отменён ctx. Конверт события доступен обработчику через EnvelopeFromContext
+type Receiver interface {
+	Consume(ctx context.Context, handler Handler) error
+}
+
+type Transport interface {
+	Sender
+	Receiver
+}
+
+// Forward возвращает обработчик, который отправляет событие во все senders;
+// так один EventPublisher раздаёт события нескольким транспортам:
+//
+//	events.Subscribe(publisher, events.Forward(sqsHandler, brokerTransport).Handle)
+func Forward(senders ...Sender) Handler {
+	return HandlerFunc(func(ctx context.Context, event Event) error {
+		var errs []error
+		for _, sender := range senders {
+			if err := sender.Publish(ctx, event); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	})
+}
+
+// ChannelTransport передаёт события через канал внутри процесса, без
+// сериализации; удобен в тестах вместо настоящего брокера
+type ChannelTransport struct {
+	messages chan Envelope
+	clock    clock.Clock
+	// mutex удерживают Publish; Close берёт его, чтобы дождаться их завершения
+	mutex     sync.RWMutex
+	closing   chan struct{}
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func NewChannelTransport(buffer int, clock clock.Clock) *ChannelTransport {
+	return &ChannelTransport{
+		messages: make(chan Envelope, buffer),
+		clock:    clock,
+		closing:  make(chan struct{}),
+		closed:   make(chan struct{}),
+	}
+}
+
+// Publish ждёт места в канале, отмены ctx или закрытия транспорта
+func (t *ChannelTransport) Publish(ctx context.Context, events ...Event) error {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	for _, event := range events {
+		select {
+		case <-t.closing:
+			return ErrPublisherClosed
+		default:
+		}
+
+		select {
+		case t.messages <- Wrap(ctx, event, t.clock):
+		case <-t.closing:
+			return ErrPublisherClosed
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// Consume обрабатывает события, пока не будет отменён ctx или закрыт транспорт.
+// Несколько Consume делят события между собой
+func (t *ChannelTransport) Consume(ctx context.Context, handler Handler) error {
+	var errs []error
+	for {
+		select {
+		case <-ctx.Done():
+			return errors.Join(errs...)
+		case envelope := <-t.messages:
+			if err := handler.Handle(ContextWithEnvelope(ctx, envelope), envelope.Event); err != nil {
+				errs = append(errs, err)
+			}
+		case <-t.closed:
+			// после Close новые события не появятся, дообрабатываем буфер
+			for {
+				select {
+				case envelope := <-t.messages:
+					if err := handler.Handle(ContextWithEnvelope(ctx, envelope), envelope.Event); err != nil {
+						errs = append(errs, err)
+					}
+				default:
+					return errors.Join(errs...)
+				}
+			}
+		}
+	}
+}
+
+// Close запрещает публикацию; Consume обработает оставшиеся события и
+// завершится. Publish, ждущие места в канале, возвращают ErrPublisherClosed
+func (t *ChannelTransport) Close() {
+	t.closeOnce.Do(func() {
+		// closing будит ждущие Publish, иначе Close ждал бы их mutex вечно
+		close(t.closing)
+		t.mutex.Lock()
+		close(t.closed)
+		t.mutex.Unlock()
+	})
+}
+
+var (
+	_ Transport = (*ChannelTransport)(nil)
+	_ Transport = (*BrokerTransport)(nil)
+	_ Transport = (*JSONLTransport)(nil)
+	_ Sender    = (*EventSQSHandler)(nil)
+	_ Receiver  = (*SQSService)(nil)
+)
@@ -0,0 +1,189 @@
+package events_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/clock"
+	"github.com/MaksimDzhangirov/PracticalDDD/events"
+	"github.com/google/uuid"
+)
+
+// collector запоминает полученные события и их конверты
+type collector struct {
+	mutex     sync.Mutex
+	events    []events.Event
+	envelopes []events.Envelope
+}
+
+func (c *collector) Handle(ctx context.Context, event events.Event) error {
+	envelope, _ := events.EnvelopeFromContext(ctx)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.events = append(c.events, event)
+	c.envelopes = append(c.envelopes, envelope)
+	return nil
+}
+
+func (c *collector) received() []events.Event {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return append([]events.Event(nil), c.events...)
+}
+
+func TestChannelTransportDeliversWithEnvelope(t *testing.T) {
+	transport := events.NewChannelTransport(10, clock.System)
+	created := events.NewOrderCreated(uuid.New())
+	parent := events.NewEnvelope(events.NewEmailSent(uuid.New()), clock.System)
+
+	if err := transport.Publish(events.ContextWithEnvelope(context.Background(), parent), created); err != nil {
+		t.Fatal(err)
+	}
+	transport.Close()
+
+	var handler collector
+	if err := transport.Consume(context.Background(), &handler); err != nil {
+		t.Fatal(err)
+	}
+	if len(handler.events) != 1 || handler.events[0] != created {
+		t.Fatalf("received %v", handler.events)
+	}
+	if envelope := handler.envelopes[0]; envelope.Name != created.Name() || envelope.CausationID != parent.ID {
+		t.Fatalf("envelope = %+v", envelope)
+	}
+}
+
+func TestChannelTransportCloseDrainsBuffer(t *testing.T) {
+	transport := events.NewChannelTransport(10, clock.System)
+	for i := 0; i < 5; i++ {
+		if err := transport.Publish(context.Background(), events.NewOrderCreated(uuid.New())); err != nil {
+			t.Fatal(err)
+		}
+	}
+	transport.Close()
+
+	if err := transport.Publish(context.Background(), events.NewOrderCreated(uuid.New())); !errors.Is(err, events.ErrPublisherClosed) {
+		t.Fatalf("Publish after Close returned %v", err)
+	}
+
+	// Consume обрабатывает всё, что было опубликовано до Close, и завершается
+	var handler collector
+	if err := transport.Consume(context.Background(), &handler); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(handler.received()); n != 5 {
+		t.Fatalf("received %d events, want 5", n)
+	}
+}
+
+func TestChannelTransportCloseWakesBlockedPublish(t *testing.T) {
+	transport := events.NewChannelTransport(0, clock.System)
+
+	result := make(chan error, 1)
+	go func() {
+		result <- transport.Publish(context.Background(), events.NewOrderCreated(uuid.New()))
+	}()
+	time.Sleep(10 * time.Millisecond)
+	transport.Close()
+
+	select {
+	case err := <-result:
+		if !errors.Is(err, events.ErrPublisherClosed) {
+			t.Fatalf("blocked Publish returned %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not wake the blocked Publish")
+	}
+}
+
+func TestChannelTransportConsumersShareEvents(t *testing.T) {
+	transport := events.NewChannelTransport(0, clock.System)
+	handlers := []*collector{{}, {}, {}}
+
+	var consumers sync.WaitGroup
+	for _, handler := range handlers {
+		consumers.Add(1)
+		go func(handler *collector) {
+			defer consumers.Done()
+			if err := transport.Consume(context.Background(), handler); err != nil {
+				t.Error(err)
+			}
+		}(handler)
+	}
+
+	sent := map[events.Event]bool{}
+	for i := 0; i < 30; i++ {
+		event := events.NewOrderCreated(uuid.New())
+		sent[event] = true
+		if err := transport.Publish(context.Background(), event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	transport.Close()
+	consumers.Wait()
+
+	received := 0
+	for _, handler := range handlers {
+		for _, event := range handler.received() {
+			if !sent[event] {
+				t.Fatalf("%v was received twice or never sent", event)
+			}
+			delete(sent, event)
+			received++
+		}
+	}
+	if received != 30 {
+		t.Fatalf("received %d events, want 30", received)
+	}
+}
+
+func TestChannelTransportConsumeCollectsHandlerErrors(t *testing.T) {
+	transport := events.NewChannelTransport(10, clock.System)
+	for i := 0; i < 2; i++ {
+		if err := transport.Publish(context.Background(), events.NewOrderCreated(uuid.New())); err != nil {
+			t.Fatal(err)
+		}
+	}
+	transport.Close()
+
+	calls := 0
+	err := transport.Consume(context.Background(), events.HandlerFunc(func(ctx context.Context, event events.Event) error {
+		calls++
+		return errHandler
+	}))
+	if !errors.Is(err, errHandler) || calls != 2 {
+		t.Fatalf("Consume returned %v after %d calls", err, calls)
+	}
+}
+
+type senderFunc func(ctx context.Context, events ...events.Event) error
+
+func (f senderFunc) Publish(ctx context.Context, events ...events.Event) error {
+	return f(ctx, events...)
+}
+
+func TestForwardSendsToEverySender(t *testing.T) {
+	transport := events.NewChannelTransport(10, clock.System)
+	failing := senderFunc(func(ctx context.Context, events ...events.Event) error {
+		return errHandler
+	})
+	event := events.NewOrderDelivered(uuid.New())
+
+	if err := events.Forward(failing, transport).Handle(context.Background(), event); !errors.Is(err, errHandler) {
+		t.Fatalf("Forward returned %v", err)
+	}
+	transport.Close()
+
+	var handler collector
+	if err := transport.Consume(context.Background(), &handler); err != nil {
+		t.Fatal(err)
+	}
+	if received := handler.received(); len(received) != 1 || received[0] != event {
+		t.Fatalf("transport received %v after another sender failed", received)
+	}
+}
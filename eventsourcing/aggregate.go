@@ -0,0 +1,67 @@
+// Package eventsourcing хранит агрегаты как поток событий: состояние агрегата
+// восстанавливается применением событий по порядку
+package eventsourcing
+
+import (
+	"github.com/MaksimDzhangirov/PracticalDDD/events"
+	"github.com/google/uuid"
+)
+
+// Aggregate - агрегат, состояние которого меняется только через Apply
+type Aggregate interface {
+	AggregateType() string
+	AggregateID() uuid.UUID
+	// Apply изменяет состояние агрегата; вызывается и для новых событий,
+	// и при восстановлении агрегата из хранилища, поэтому не проверяет инвариантов
+	Apply(event events.Event) error
+	Root() *AggregateRoot
+}
+
+// Snapshotter реализуют агрегаты, которые умеют сохранять своё состояние
+// целиком, чтобы не применять при загрузке весь поток событий
+type Snapshotter interface {
+	Snapshot() ([]byte, error)
+	RestoreSnapshot(state []byte) error
+}
+
+// AggregateRoot встраивается в агрегат и хранит его версию и события,
+// ещё не записанные в EventStore
+type AggregateRoot struct {
+	version int
+	changes []events.Event
+}
+
+func (r *AggregateRoot) Root() *AggregateRoot {
+	return r
+}
+
+// Version - количество событий агрегата, записанных в EventStore
+func (r *AggregateRoot) Version() int {
+	return r.version
+}
+
+// Changes возвращает новые события агрегата
+func (r *AggregateRoot) Changes() []events.Event {
+	return r.changes
+}
+
+// Raise применяет новое событие к aggregate и запоминает его
+//
+//	func (ca *CustomerAccount) MarkAsDeleted() error {
+//		...
+//		return ca.Raise(ca, CustomerAccountDeleted{ID: ca.id})
+//	}
+func (r *AggregateRoot) Raise(aggregate Aggregate, event events.Event) error {
+	if err := aggregate.Apply(event); err != nil {
+		return err
+	}
+	r.changes = append(r.changes, event)
+
+	return nil
+}
+
+// commit отмечает новые события записанными
+func (r *AggregateRoot) commit() {
+	r.version += len(r.changes)
+	r.changes = nil
+}
@@ -0,0 +1,91 @@
+package eventsourcing
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/clock"
+	gorm_generics "github.com/MaksimDzhangirov/PracticalDDD/gorm-generics"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CheckpointStore хранит позицию последнего события, обработанного проекцией
+type CheckpointStore interface {
+	// Checkpoint возвращает 0, если проекция ещё ничего не обработала
+	Checkpoint(ctx context.Context, projection string) (int64, error)
+	SaveCheckpoint(ctx context.Context, projection string, position int64) error
+}
+
+type MemoryCheckpoints struct {
+	mutex     sync.RWMutex
+	positions map[string]int64
+}
+
+func NewMemoryCheckpoints() *MemoryCheckpoints {
+	return &MemoryCheckpoints{
+		positions: map[string]int64{},
+	}
+}
+
+func (c *MemoryCheckpoints) Checkpoint(ctx context.Context, projection string) (int64, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.positions[projection], nil
+}
+
+func (c *MemoryCheckpoints) SaveCheckpoint(ctx context.Context, projection string, position int64) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.positions[projection] = position
+
+	return nil
+}
+
+// StoredCheckpoint - строка таблицы projection_checkpoints
+type StoredCheckpoint struct {
+	Projection string `gorm:"primaryKey"`
+	Position   int64
+	// время ставит clock GormCheckpoints, а не gorm
+	UpdatedAt time.Time `gorm:"autoUpdateTime:false"`
+}
+
+func (StoredCheckpoint) TableName() string {
+	return "projection_checkpoints"
+}
+
+// GormCheckpoints пишет позицию в транзакцию из ctx, поэтому проекция,
+// которая хранит модель чтения в той же базе, обновляет её и позицию атомарно
+type GormCheckpoints struct {
+	db    *gorm.DB
+	clock clock.Clock
+}
+
+func NewGormCheckpoints(db *gorm.DB, clock clock.Clock) *GormCheckpoints {
+	return &GormCheckpoints{
+		db:    db,
+		clock: clock,
+	}
+}
+
+func (c *GormCheckpoints) Checkpoint(ctx context.Context, projection string) (int64, error) {
+	var row StoredCheckpoint
+	err := gorm_generics.DB(ctx, c.db).Where("projection = ?", projection).Take(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+
+	return row.Position, err
+}
+
+func (c *GormCheckpoints) SaveCheckpoint(ctx context.Context, projection string, position int64) error {
+	return gorm_generics.DB(ctx, c.db).Clauses(clause.OnConflict{UpdateAll: true}).Create(&StoredCheckpoint{
+		Projection: projection,
+		Position:   position,
+		UpdatedAt:  c.clock.Now(),
+	}).Error
+}
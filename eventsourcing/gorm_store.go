@@ -0,0 +1,256 @@
+package eventsourcing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/clock"
+	"github.com/MaksimDzhangirov/PracticalDDD/events"
+	gorm_generics "github.com/MaksimDzhangirov/PracticalDDD/gorm-generics"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// инфраструктурный уровень
+
+// StoredEvent - строка таблицы event_store. Уникальный индекс по потоку
+// и версии не даёт двум конкурентным записям получить одну версию
+type StoredEvent struct {
+	Position   int64  `gorm:"primaryKey;autoIncrement"`
+	StreamID   string `gorm:"size:100;not null;uniqueIndex:idx_event_store_stream_version"`
+	Version    int    `gorm:"not null;uniqueIndex:idx_event_store_stream_version"`
+	EventID    string `gorm:"size:36;not null;uniqueIndex"`
+	Name       string `gorm:"index"`
+	Body       []byte `gorm:"not null"`
+	RecordedAt time.Time
+}
+
+func (StoredEvent) TableName() string {
+	return "event_store"
+}
+
+// StoredSnapshot - строка таблицы event_snapshots, последний снимок потока
+type StoredSnapshot struct {
+	StreamID string `gorm:"primaryKey;size:100"`
+	Version  int
+	State    []byte
+	TakenAt  time.Time
+}
+
+func (StoredSnapshot) TableName() string {
+	return "event_snapshots"
+}
+
+// Migrate создаёт таблицы event_store, event_snapshots и projection_checkpoints
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(&StoredEvent{}, &StoredSnapshot{}, &StoredCheckpoint{})
+}
+
+// GormStore пишет события в транзакцию из ctx (см. gorm_generics.UnitOfWork),
+// поэтому их можно записать вместе с сообщениями outbox.
+//
+// Позиции выдаёт автоинкремент базы данных при вставке, а не при фиксации,
+// поэтому транзакция с меньшей позицией может зафиксироваться позже
+// транзакции с большей, а откаченная транзакция оставляет пропуск навсегда.
+// LoadAll останавливается перед пропуском, пока событие за ним записано
+// меньше gapTimeout назад; транзакции записи не должны длиться дольше
+type GormStore struct {
+	db         *gorm.DB
+	registry   *events.Registry
+	clock      clock.Clock
+	gapTimeout time.Duration
+}
+
+type GormStoreOption func(*GormStore)
+
+// WithGapTimeout задаёт, сколько LoadAll ждёт события на месте пропуска
+// позиции, прежде чем считать его транзакцию откаченной
+func WithGapTimeout(timeout time.Duration) GormStoreOption {
+	return func(s *GormStore) {
+		s.gapTimeout = timeout
+	}
+}
+
+func NewGormStore(db *gorm.DB, registry *events.Registry, clock clock.Clock, options ...GormStoreOption) *GormStore {
+	store := &GormStore{
+		db:         db,
+		registry:   registry,
+		clock:      clock,
+		gapTimeout: time.Minute,
+	}
+	for _, option := range options {
+		option(store)
+	}
+
+	return store
+}
+
+func (s *GormStore) Append(ctx context.Context, streamID string, expectedVersion int, envelopes ...events.Envelope) error {
+	if len(envelopes) == 0 {
+		return nil
+	}
+
+	return gorm_generics.NewUnitOfWork(s.db).Do(ctx, func(ctx context.Context) error {
+		db := gorm_generics.DB(ctx, s.db)
+
+		version, err := s.version(db, streamID)
+		if err != nil {
+			return err
+		}
+		if expectedVersion != AnyVersion && expectedVersion != version {
+			return ErrConcurrencyConflict
+		}
+
+		rows := make([]StoredEvent, 0, len(envelopes))
+		for i, envelope := range envelopes {
+			envelope, err := s.registry.Seal(envelope)
+			if err != nil {
+				return err
+			}
+			body, err := json.Marshal(envelope)
+			if err != nil {
+				return err
+			}
+			rows = append(rows, StoredEvent{
+				StreamID:   streamID,
+				Version:    version + i + 1,
+				EventID:    envelope.ID.String(),
+				Name:       envelope.Name,
+				Body:       body,
+				RecordedAt: s.clock.Now(),
+			})
+		}
+
+		if err := db.Create(&rows).Error; err != nil {
+			// писатель, начавший одновременно с нами, занял ту же версию; проверяем
+			// вне транзакции, так как после ошибки она может быть прервана
+			if current, versionErr := s.version(s.db.WithContext(ctx), streamID); versionErr == nil && current != version {
+				return ErrConcurrencyConflict
+			}
+			return err
+		}
+
+		return nil
+	})
+}
+
+func (s *GormStore) version(db *gorm.DB, streamID string) (int, error) {
+	var version int
+	err := db.Model(&StoredEvent{}).
+		Where("stream_id = ?", streamID).
+		Select("COALESCE(MAX(version), 0)").
+		Scan(&version).Error
+
+	return version, err
+}
+
+func (s *GormStore) Load(ctx context.Context, streamID string, fromVersion int) ([]RecordedEvent, error) {
+	var rows []StoredEvent
+	err := gorm_generics.DB(ctx, s.db).
+		Where("stream_id = ? AND version >= ?", streamID, fromVersion).
+		Order("version").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return s.decode(rows)
+}
+
+func (s *GormStore) LoadAll(ctx context.Context, after int64, limit int) ([]RecordedEvent, error) {
+	query := gorm_generics.DB(ctx, s.db).
+		Where("position > ?", after).
+		Order("position")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var rows []StoredEvent
+	if err := query.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	return s.decode(s.contiguous(rows, after))
+}
+
+// contiguous обрезает rows перед пропуском позиции, который ещё может
+// заполнить незафиксированная транзакция. Событие за пропуском вставлено
+// позже пропавшего, поэтому если оно записано больше gapTimeout назад,
+// транзакция пропавшего события длится дольше допустимого и считается откаченной
+func (s *GormStore) contiguous(rows []StoredEvent, after int64) []StoredEvent {
+	now := s.clock.Now()
+	expected := after + 1
+	for i, row := range rows {
+		if row.Position != expected && now.Sub(row.RecordedAt) < s.gapTimeout {
+			return rows[:i]
+		}
+		expected = row.Position + 1
+	}
+
+	return rows
+}
+
+func (s *GormStore) CountAfter(ctx context.Context, after int64) (int64, error) {
+	var count int64
+	err := gorm_generics.DB(ctx, s.db).Model(&StoredEvent{}).
+		Where("position > ?", after).
+		Count(&count).Error
+
+	return count, err
+}
+
+func (s *GormStore) decode(rows []StoredEvent) ([]RecordedEvent, error) {
+	result := make([]RecordedEvent, 0, len(rows))
+	for _, row := range rows {
+		var envelope events.Envelope
+		if err := json.Unmarshal(row.Body, &envelope); err != nil {
+			return nil, err
+		}
+		envelope, err := s.registry.Open(envelope)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, RecordedEvent{
+			Position: row.Position,
+			StreamID: row.StreamID,
+			Version:  row.Version,
+			Envelope: envelope,
+		})
+	}
+
+	return result, nil
+}
+
+// SaveSnapshot пишет снимок в точке сохранения транзакции из ctx: ошибка
+// снимка откатывает только его и не прерывает транзакцию вызывающего
+func (s *GormStore) SaveSnapshot(ctx context.Context, snapshot Snapshot) error {
+	return gorm_generics.NewUnitOfWork(s.db).Do(ctx, func(ctx context.Context) error {
+		return gorm_generics.DB(ctx, s.db).Clauses(clause.OnConflict{UpdateAll: true}).Create(&StoredSnapshot{
+			StreamID: snapshot.StreamID,
+			Version:  snapshot.Version,
+			State:    snapshot.State,
+			TakenAt:  snapshot.TakenAt,
+		}).Error
+	})
+}
+
+func (s *GormStore) LoadSnapshot(ctx context.Context, streamID string) (Snapshot, error) {
+	var row StoredSnapshot
+	err := gorm_generics.DB(ctx, s.db).Where("stream_id = ?", streamID).Take(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Snapshot{}, ErrSnapshotNotFound
+	}
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	return Snapshot{
+		StreamID: row.StreamID,
+		Version:  row.Version,
+		State:    row.State,
+		TakenAt:  row.TakenAt,
+	}, nil
+}
@@ -0,0 +1,116 @@
+package eventsourcing
+
+import (
+	"context"
+	"sync"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/events"
+)
+
+// MemoryStore хранит события и снимки в памяти; подходит для тестов
+// и локального запуска. Позиции выдаются под mutex, поэтому пропусков нет
+type MemoryStore struct {
+	mutex     sync.RWMutex
+	all       []RecordedEvent
+	streams   map[string][]RecordedEvent
+	snapshots map[string]Snapshot
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		streams:   map[string][]RecordedEvent{},
+		snapshots: map[string]Snapshot{},
+	}
+}
+
+func (s *MemoryStore) Append(ctx context.Context, streamID string, expectedVersion int, envelopes ...events.Envelope) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stream := s.streams[streamID]
+	if expectedVersion != AnyVersion && expectedVersion != len(stream) {
+		return ErrConcurrencyConflict
+	}
+
+	for _, envelope := range envelopes {
+		recorded := RecordedEvent{
+			Position: int64(len(s.all) + 1),
+			StreamID: streamID,
+			Version:  len(stream) + 1,
+			Envelope: envelope,
+		}
+		stream = append(stream, recorded)
+		s.all = append(s.all, recorded)
+	}
+	s.streams[streamID] = stream
+
+	return nil
+}
+
+func (s *MemoryStore) Load(ctx context.Context, streamID string, fromVersion int) ([]RecordedEvent, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	stream := s.streams[streamID]
+	if fromVersion < 1 {
+		fromVersion = 1
+	}
+	if fromVersion > len(stream) {
+		return nil, nil
+	}
+
+	return append([]RecordedEvent(nil), stream[fromVersion-1:]...), nil
+}
+
+func (s *MemoryStore) LoadAll(ctx context.Context, after int64, limit int) ([]RecordedEvent, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if after < 0 {
+		after = 0
+	}
+	if after >= int64(len(s.all)) {
+		return nil, nil
+	}
+
+	result := s.all[after:]
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+
+	return append([]RecordedEvent(nil), result...), nil
+}
+
+func (s *MemoryStore) CountAfter(ctx context.Context, after int64) (int64, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if after < 0 {
+		after = 0
+	}
+	if after >= int64(len(s.all)) {
+		return 0, nil
+	}
+	return int64(len(s.all)) - after, nil
+}
+
+func (s *MemoryStore) SaveSnapshot(ctx context.Context, snapshot Snapshot) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.snapshots[snapshot.StreamID] = snapshot
+
+	return nil
+}
+
+func (s *MemoryStore) LoadSnapshot(ctx context.Context, streamID string) (Snapshot, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	snapshot, ok := s.snapshots[streamID]
+	if !ok {
+		return Snapshot{}, ErrSnapshotNotFound
+	}
+
+	return snapshot, nil
+}
@@ -0,0 +1,35 @@
+package eventsourcing
+
+import (
+	"context"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/clock"
+	"github.com/MaksimDzhangirov/PracticalDDD/events"
+)
+
+// EventStoreNotifier записывает в EventStore события агрегатов, которые
+// хранятся не потоком событий, например Order. Так их видят проекции:
+//
+//	services.NewOrderService(repository, eventsourcing.NewEventStoreNotifier(store, clock.System), transaction)
+type EventStoreNotifier struct {
+	store EventStore
+	clock clock.Clock
+}
+
+func NewEventStoreNotifier(store EventStore, clock clock.Clock) *EventStoreNotifier {
+	return &EventStoreNotifier{
+		store: store,
+		clock: clock,
+	}
+}
+
+func (n *EventStoreNotifier) Notify(ctx context.Context, event events.Event) error {
+	envelope := events.Wrap(ctx, event, n.clock)
+
+	streamID := envelope.Name
+	if envelope.AggregateType != "" {
+		streamID = StreamID(envelope.AggregateType, envelope.AggregateID)
+	}
+
+	return n.store.Append(ctx, streamID, AnyVersion, envelope)
+}
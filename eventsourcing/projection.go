@@ -0,0 +1,332 @@
+package eventsourcing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/events"
+)
+
+var (
+	ErrUnknownProjection   = errors.New("unknown projection")
+	ErrDuplicateProjection = errors.New("projection is already registered")
+	ErrDuplicateHandler    = errors.New("projection already handles event")
+)
+
+// Projection строит модель чтения из событий. Обработчики событий -
+// обычные функции Go, которые добавляются через When:
+//
+//	balances := eventsourcing.NewProjection("balances", view.Reset)
+//	eventsourcing.When(balances, view.OnMoneyDeposited)
+type Projection struct {
+	name     string
+	handlers map[string]func(ctx context.Context, event events.Event) error
+	families []familyHandler
+	reset    func(ctx context.Context) error
+	volatile bool
+}
+
+// familyHandler обрабатывает события, реализующие интерфейс eventType
+type familyHandler struct {
+	eventType reflect.Type
+	matches   func(event events.Event) bool
+	handle    func(ctx context.Context, event events.Event) error
+}
+
+// NewProjection создаёт проекцию; reset очищает модель чтения перед
+// полной перестройкой и может быть nil
+func NewProjection(name string, reset func(ctx context.Context) error) *Projection {
+	return &Projection{
+		name:     name,
+		handlers: map[string]func(ctx context.Context, event events.Event) error{},
+		reset:    reset,
+	}
+}
+
+// NewVolatileProjection создаёт проекцию, модель чтения которой хранится в
+// памяти процесса. Её позиция тоже хранится в памяти ProjectionRunner, а не
+// в CheckpointStore, поэтому после перезапуска модель строится заново
+// с нулевой позиции, а не продолжается с позиции, сохранённой до потери данных
+func NewVolatileProjection(name string, reset func(ctx context.Context) error) *Projection {
+	projection := NewProjection(name, reset)
+	projection.volatile = true
+	return projection
+}
+
+// When вызывает handler для событий типа T; остальные события проекция
+// пропускает. Если T - интерфейс, например OrderEvent, handler получает
+// события, которые его реализуют и для которых нет обработчика их
+// собственного типа. Второй обработчик того же T - ошибка программиста,
+// поэтому When паникует с ErrDuplicateHandler
+func When[T events.Event](projection *Projection, handler func(ctx context.Context, event T) error) {
+	handle := func(ctx context.Context, event events.Event) error {
+		actualEvent, ok := event.(T)
+		if !ok {
+			return nil
+		}
+		return handler(ctx, actualEvent)
+	}
+
+	name, ok := events.EventName[T]()
+	if !ok {
+		eventType := reflect.TypeOf((*T)(nil)).Elem()
+		for _, family := range projection.families {
+			if family.eventType == eventType {
+				panic(fmt.Errorf("%w: %s handles %s twice", ErrDuplicateHandler, projection.name, eventType))
+			}
+		}
+		projection.families = append(projection.families, familyHandler{
+			eventType: eventType,
+			matches: func(event events.Event) bool {
+				_, ok := event.(T)
+				return ok
+			},
+			handle: handle,
+		})
+		return
+	}
+
+	if _, ok := projection.handlers[name]; ok {
+		panic(fmt.Errorf("%w: %s handles %s twice", ErrDuplicateHandler, projection.name, name))
+	}
+	projection.handlers[name] = handle
+}
+
+func (p *Projection) Name() string {
+	return p.name
+}
+
+func (p *Projection) Handle(ctx context.Context, event events.Event) error {
+	if handler, ok := p.handlers[event.Name()]; ok {
+		return handler(ctx, event)
+	}
+	for _, family := range p.families {
+		if family.matches(event) {
+			return family.handle(ctx, event)
+		}
+	}
+	return nil
+}
+
+type ProjectionOption func(*ProjectionRunner)
+
+// WithPollInterval задаёт паузу между проверками новых событий в Run
+func WithPollInterval(interval time.Duration) ProjectionOption {
+	return func(r *ProjectionRunner) {
+		r.interval = interval
+	}
+}
+
+// WithProjectionBatchSize задаёт количество событий, читаемых за один запрос
+func WithProjectionBatchSize(size int) ProjectionOption {
+	return func(r *ProjectionRunner) {
+		r.batchSize = size
+	}
+}
+
+// WithProjectionTransaction обрабатывает каждое событие и сохраняет позицию
+// в одной транзакции, например gorm_generics.NewUnitOfWork(db).Do
+func WithProjectionTransaction(transaction func(ctx context.Context, fn func(ctx context.Context) error) error) ProjectionOption {
+	return func(r *ProjectionRunner) {
+		r.transaction = transaction
+	}
+}
+
+// ProjectionRunner читает события из EventStore и передаёт их проекциям.
+// Каждая проекция продвигается независимо от остальных: ошибка одной
+// проекции останавливает только её до следующей попытки
+type ProjectionRunner struct {
+	store       EventStore
+	checkpoints CheckpointStore
+	// volatile - позиции проекций NewVolatileProjection
+	volatile    *MemoryCheckpoints
+	projections []*Projection
+	interval    time.Duration
+	batchSize   int
+	transaction func(ctx context.Context, fn func(ctx context.Context) error) error
+	// running не даёт Rebuild и CatchUp обрабатывать события одновременно,
+	// mutex защищает список проекций
+	running sync.Mutex
+	mutex   sync.RWMutex
+}
+
+func NewProjectionRunner(store EventStore, checkpoints CheckpointStore, options ...ProjectionOption) *ProjectionRunner {
+	r := &ProjectionRunner{
+		store:       store,
+		checkpoints: checkpoints,
+		volatile:    NewMemoryCheckpoints(),
+		interval:    time.Second,
+		batchSize:   100,
+		transaction: func(ctx context.Context, fn func(ctx context.Context) error) error {
+			return fn(ctx)
+		},
+	}
+	for _, option := range options {
+		option(r)
+	}
+
+	return r
+}
+
+func (r *ProjectionRunner) Register(projections ...*Projection) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, projection := range projections {
+		if r.lookup(projection.name) != nil {
+			return fmt.Errorf("%w: %s", ErrDuplicateProjection, projection.name)
+		}
+		r.projections = append(r.projections, projection)
+	}
+
+	return nil
+}
+
+func (r *ProjectionRunner) find(name string) *Projection {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.lookup(name)
+}
+
+func (r *ProjectionRunner) lookup(name string) *Projection {
+	for _, projection := range r.projections {
+		if projection.name == name {
+			return projection
+		}
+	}
+	return nil
+}
+
+func (r *ProjectionRunner) checkpointsOf(projection *Projection) CheckpointStore {
+	if projection.volatile {
+		return r.volatile
+	}
+	return r.checkpoints
+}
+
+func (r *ProjectionRunner) list() []*Projection {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return append([]*Projection(nil), r.projections...)
+}
+
+// Run догоняет хранилище каждые interval, пока не будет отменён ctx.
+// Ошибки проекций не останавливают Run: проекция повторит событие позже
+func (r *ProjectionRunner) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.CatchUp(ctx); err != nil && ctx.Err() == nil {
+			log.Print(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// CatchUp обрабатывает все события, записанные после позиций проекций,
+// и возвращает количество обработанных событий
+func (r *ProjectionRunner) CatchUp(ctx context.Context) (int, error) {
+	r.running.Lock()
+	defer r.running.Unlock()
+
+	total := 0
+	var errs []error
+	for _, projection := range r.list() {
+		processed, err := r.catchUp(ctx, projection)
+		total += processed
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", projection.name, err))
+		}
+	}
+
+	return total, errors.Join(errs...)
+}
+
+func (r *ProjectionRunner) catchUp(ctx context.Context, projection *Projection) (int, error) {
+	checkpoints := r.checkpointsOf(projection)
+	position, err := checkpoints.Checkpoint(ctx, projection.name)
+	if err != nil {
+		return 0, err
+	}
+
+	processed := 0
+	for {
+		recorded, err := r.store.LoadAll(ctx, position, r.batchSize)
+		if err != nil || len(recorded) == 0 {
+			return processed, err
+		}
+
+		for _, event := range recorded {
+			err := r.transaction(ctx, func(ctx context.Context) error {
+				ctx = events.ContextWithEnvelope(ctx, event.Envelope)
+				if err := projection.Handle(ctx, event.Envelope.Event); err != nil {
+					return err
+				}
+				return checkpoints.SaveCheckpoint(ctx, projection.name, event.Position)
+			})
+			if err != nil {
+				return processed, fmt.Errorf("position %d: %w", event.Position, err)
+			}
+			position = event.Position
+			processed++
+		}
+	}
+}
+
+// Rebuild очищает модель чтения проекции и заново обрабатывает все события
+// с нулевой позиции
+func (r *ProjectionRunner) Rebuild(ctx context.Context, name string) error {
+	r.running.Lock()
+	defer r.running.Unlock()
+
+	projection := r.find(name)
+	if projection == nil {
+		return fmt.Errorf("%w: %s", ErrUnknownProjection, name)
+	}
+
+	err := r.transaction(ctx, func(ctx context.Context) error {
+		if projection.reset != nil {
+			if err := projection.reset(ctx); err != nil {
+				return err
+			}
+		}
+		return r.checkpointsOf(projection).SaveCheckpoint(ctx, projection.name, 0)
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = r.catchUp(ctx, projection)
+	return err
+}
+
+// Lag возвращает для каждой проекции количество событий хранилища,
+// которые она ещё не обработала
+func (r *ProjectionRunner) Lag(ctx context.Context) (map[string]int64, error) {
+	projections := r.list()
+	lag := make(map[string]int64, len(projections))
+	for _, projection := range projections {
+		position, err := r.checkpointsOf(projection).Checkpoint(ctx, projection.name)
+		if err != nil {
+			return nil, err
+		}
+		if lag[projection.name], err = r.store.CountAfter(ctx, position); err != nil {
+			return nil, err
+		}
+	}
+
+	return lag, nil
+}
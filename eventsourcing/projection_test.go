@@ -0,0 +1,272 @@
+package eventsourcing_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/events"
+	"github.com/MaksimDzhangirov/PracticalDDD/eventsourcing"
+	gorm_generics "github.com/MaksimDzhangirov/PracticalDDD/gorm-generics"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func TestWhenHandlesEventFamilies(t *testing.T) {
+	projection := eventsourcing.NewProjection("orders", nil)
+	var created, other int
+	eventsourcing.When(projection, func(ctx context.Context, event events.OrderCreated) error {
+		created++
+		return nil
+	})
+	eventsourcing.When(projection, func(ctx context.Context, event events.OrderEvent) error {
+		other++
+		return nil
+	})
+
+	orderID := uuid.New()
+	for _, event := range []events.Event{events.NewOrderCreated(orderID), events.NewOrderDispatched(orderID), events.NewOrderDelivered(orderID)} {
+		if err := projection.Handle(context.Background(), event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if created != 1 || other != 2 {
+		t.Fatalf("created %d, other %d; want 1 and 2", created, other)
+	}
+}
+
+func TestWhenRejectsSecondHandler(t *testing.T) {
+	projection := eventsourcing.NewProjection("orders", nil)
+	eventsourcing.When(projection, func(ctx context.Context, event events.OrderCreated) error {
+		return nil
+	})
+
+	defer func() {
+		err, _ := recover().(error)
+		if !errors.Is(err, eventsourcing.ErrDuplicateHandler) {
+			t.Fatalf("When panicked with %v, want ErrDuplicateHandler", err)
+		}
+	}()
+	eventsourcing.When(projection, func(ctx context.Context, event events.OrderCreated) error {
+		return nil
+	})
+}
+
+// counter - проекция, которая считает события заказов
+type counter struct {
+	handled int
+	resets  int
+	// failing - проекция завершается ошибкой на любом событии
+	failing bool
+	// volatile - проекция хранит модель в памяти
+	volatile bool
+}
+
+func (c *counter) projection(name string) *eventsourcing.Projection {
+	newProjection := eventsourcing.NewProjection
+	if c.volatile {
+		newProjection = eventsourcing.NewVolatileProjection
+	}
+	projection := newProjection(name, func(ctx context.Context) error {
+		c.handled = 0
+		c.resets++
+		return nil
+	})
+	eventsourcing.When(projection, func(ctx context.Context, event events.OrderEvent) error {
+		if c.failing {
+			return errors.New("projection failed")
+		}
+		c.handled++
+		return nil
+	})
+	return projection
+}
+
+// backend - хранилище событий и позиций проекций одной реализации
+type backend struct {
+	store       store
+	checkpoints eventsourcing.CheckpointStore
+	options     []eventsourcing.ProjectionOption
+}
+
+func backends(t *testing.T) map[string]backend {
+	t.Helper()
+
+	db := openDB(t)
+	return map[string]backend{
+		"memory": {
+			store:       eventsourcing.NewMemoryStore(),
+			checkpoints: eventsourcing.NewMemoryCheckpoints(),
+		},
+		"gorm": {
+			store:       eventsourcing.NewGormStore(db, events.NewDefaultRegistry(events.JSONCodec{}), recordedAt),
+			checkpoints: eventsourcing.NewGormCheckpoints(db, recordedAt),
+			options:     []eventsourcing.ProjectionOption{eventsourcing.WithProjectionTransaction(gorm_generics.NewUnitOfWork(db).Do)},
+		},
+	}
+}
+
+// createOrders записывает OrderCreated для n новых заказов
+func createOrders(t *testing.T, store eventsourcing.EventStore, n int) {
+	t.Helper()
+
+	notifier := eventsourcing.NewEventStoreNotifier(store, recordedAt)
+	for i := 0; i < n; i++ {
+		if err := notifier.Notify(context.Background(), events.NewOrderCreated(uuid.New())); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestProjectionRunnerCatchUpAndLag(t *testing.T) {
+	for name, backend := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			runner := eventsourcing.NewProjectionRunner(backend.store, backend.checkpoints,
+				append(backend.options, eventsourcing.WithProjectionBatchSize(2))...)
+			var orders, audit counter
+			if err := runner.Register(orders.projection("orders"), audit.projection("audit")); err != nil {
+				t.Fatal(err)
+			}
+			if err := runner.Register(orders.projection("orders")); !errors.Is(err, eventsourcing.ErrDuplicateProjection) {
+				t.Fatalf("second orders projection returned %v", err)
+			}
+
+			createOrders(t, backend.store, 5)
+			lag, err := runner.Lag(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if lag["orders"] != 5 || lag["audit"] != 5 {
+				t.Fatalf("Lag() = %v before catching up", lag)
+			}
+
+			// пакеты по 2 события читаются, пока хранилище не закончится
+			if processed, err := runner.CatchUp(ctx); err != nil || processed != 10 {
+				t.Fatalf("CatchUp() = %d, %v; want 10", processed, err)
+			}
+			if processed, err := runner.CatchUp(ctx); err != nil || processed != 0 {
+				t.Fatalf("second CatchUp() = %d, %v; want 0", processed, err)
+			}
+
+			createOrders(t, backend.store, 1)
+			if lag, err := runner.Lag(ctx); err != nil || lag["orders"] != 1 {
+				t.Fatalf("Lag() = %v, %v after a new event", lag, err)
+			}
+			if _, err := runner.CatchUp(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if orders.handled != 6 || audit.handled != 6 {
+				t.Fatalf("handled %d and %d events, want 6", orders.handled, audit.handled)
+			}
+			if lag, err := runner.Lag(ctx); err != nil || lag["orders"] != 0 || lag["audit"] != 0 {
+				t.Fatalf("Lag() = %v, %v after catching up", lag, err)
+			}
+		})
+	}
+}
+
+func TestProjectionRunnerIsolatesFailingProjection(t *testing.T) {
+	for name, backend := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			runner := eventsourcing.NewProjectionRunner(backend.store, backend.checkpoints, backend.options...)
+			var failing, healthy counter
+			if err := runner.Register(failing.projection("failing"), healthy.projection("healthy")); err != nil {
+				t.Fatal(err)
+			}
+
+			createOrders(t, backend.store, 3)
+			failing.failing = true
+			if processed, err := runner.CatchUp(ctx); err == nil || processed != 3 {
+				t.Fatalf("CatchUp() = %d, %v; want 3 and an error", processed, err)
+			}
+			if lag, err := runner.Lag(ctx); err != nil || lag["failing"] != 3 || lag["healthy"] != 0 {
+				t.Fatalf("Lag() = %v, %v", lag, err)
+			}
+
+			// после исправления проекция продолжает с события, на котором упала
+			failing.failing = false
+			if processed, err := runner.CatchUp(ctx); err != nil || processed != 3 {
+				t.Fatalf("CatchUp() = %d, %v after the fix; want 3", processed, err)
+			}
+			if failing.handled != 3 || healthy.handled != 3 {
+				t.Fatalf("handled %d and %d events, want 3", failing.handled, healthy.handled)
+			}
+		})
+	}
+}
+
+func TestProjectionRunnerRebuild(t *testing.T) {
+	for name, backend := range backends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			runner := eventsourcing.NewProjectionRunner(backend.store, backend.checkpoints, backend.options...)
+			var orders counter
+			if err := runner.Register(orders.projection("orders")); err != nil {
+				t.Fatal(err)
+			}
+
+			createOrders(t, backend.store, 4)
+			if _, err := runner.CatchUp(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if err := runner.Rebuild(ctx, "orders"); err != nil {
+				t.Fatal(err)
+			}
+			// модель очищена и построена заново, а не дополнена
+			if orders.resets != 1 || orders.handled != 4 {
+				t.Fatalf("Rebuild reset %d times and handled %d events", orders.resets, orders.handled)
+			}
+			if lag, err := runner.Lag(ctx); err != nil || lag["orders"] != 0 {
+				t.Fatalf("Lag() = %v, %v after Rebuild", lag, err)
+			}
+			if err := runner.Rebuild(ctx, "unknown"); !errors.Is(err, eventsourcing.ErrUnknownProjection) {
+				t.Fatalf("Rebuild of an unknown projection returned %v", err)
+			}
+		})
+	}
+}
+
+func TestGormCheckpointsSurviveRestart(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	store := eventsourcing.NewGormStore(db, events.NewDefaultRegistry(events.JSONCodec{}), recordedAt)
+	createOrders(t, store, 3)
+
+	var durable counter
+	volatile := counter{volatile: true}
+	runner := eventsourcing.NewProjectionRunner(store, eventsourcing.NewGormCheckpoints(db, recordedAt))
+	if err := runner.Register(durable.projection("durable"), volatile.projection("volatile")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runner.CatchUp(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var checkpoint eventsourcing.StoredCheckpoint
+	if err := db.Where("projection = ?", "durable").Take(&checkpoint).Error; err != nil {
+		t.Fatal(err)
+	}
+	if checkpoint.Position != 3 || !checkpoint.UpdatedAt.Equal(recordedAt.Now()) {
+		t.Fatalf("stored checkpoint = %+v", checkpoint)
+	}
+	if err := db.Where("projection = ?", "volatile").Take(&eventsourcing.StoredCheckpoint{}).Error; !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("volatile projection stored its checkpoint: %v", err)
+	}
+
+	// новый процесс продолжает сохранённую проекцию, а модель в памяти строит заново
+	createOrders(t, store, 1)
+	durable, volatile = counter{}, counter{volatile: true}
+	restarted := eventsourcing.NewProjectionRunner(store, eventsourcing.NewGormCheckpoints(db, recordedAt))
+	if err := restarted.Register(durable.projection("durable"), volatile.projection("volatile")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := restarted.CatchUp(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if durable.handled != 1 || volatile.handled != 4 {
+		t.Fatalf("after restart handled %d and %d events, want 1 and 4", durable.handled, volatile.handled)
+	}
+}
@@ -0,0 +1,173 @@
+package eventsourcing
+
+import (
+	"context"
+	"errors"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/clock"
+	"github.com/MaksimDzhangirov/PracticalDDD/events"
+	"github.com/google/uuid"
+)
+
+type RepositoryOption func(*repositoryOptions)
+
+type repositoryOptions struct {
+	snapshots       SnapshotStore
+	snapshotEvery   int
+	onSnapshotError func(ctx context.Context, streamID string, err error)
+}
+
+// WithSnapshots сохраняет снимок агрегата в snapshots каждые every событий.
+// Агрегат должен реализовывать Snapshotter
+func WithSnapshots(snapshots SnapshotStore, every int) RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.snapshots = snapshots
+		o.snapshotEvery = every
+	}
+}
+
+// WithSnapshotErrorHandler получает ошибки сохранения снимков. Save их не
+// возвращает, потому что события к этому моменту уже записаны
+func WithSnapshotErrorHandler(handler func(ctx context.Context, streamID string, err error)) RepositoryOption {
+	return func(o *repositoryOptions) {
+		o.onSnapshotError = handler
+	}
+}
+
+// Repository загружает и сохраняет агрегаты T через EventStore
+type Repository[T Aggregate] struct {
+	store   EventStore
+	factory func() T
+	clock   clock.Clock
+	options repositoryOptions
+}
+
+// NewRepository создаёт репозиторий; factory возвращает пустой агрегат,
+// к которому применяются события потока
+func NewRepository[T Aggregate](store EventStore, factory func() T, clock clock.Clock, options ...RepositoryOption) *Repository[T] {
+	r := &Repository[T]{
+		store:   store,
+		factory: factory,
+		clock:   clock,
+	}
+	for _, option := range options {
+		option(&r.options)
+	}
+
+	return r
+}
+
+// Load восстанавливает агрегат из последнего снимка и событий после него
+func (r *Repository[T]) Load(ctx context.Context, id uuid.UUID) (T, error) {
+	aggregate := r.factory()
+	root := aggregate.Root()
+	streamID := StreamID(aggregate.AggregateType(), id)
+
+	if err := r.restore(ctx, aggregate, streamID); err != nil {
+		var zero T
+		return zero, err
+	}
+
+	recorded, err := r.store.Load(ctx, streamID, root.version+1)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	for _, event := range recorded {
+		if err := aggregate.Apply(event.Envelope.Event); err != nil {
+			var zero T
+			return zero, err
+		}
+		root.version = event.Version
+	}
+
+	if root.version == 0 {
+		var zero T
+		return zero, ErrAggregateNotFound
+	}
+
+	return aggregate, nil
+}
+
+func (r *Repository[T]) restore(ctx context.Context, aggregate T, streamID string) error {
+	snapshotter, ok := any(aggregate).(Snapshotter)
+	if r.options.snapshots == nil || !ok {
+		return nil
+	}
+
+	snapshot, err := r.options.snapshots.LoadSnapshot(ctx, streamID)
+	if errors.Is(err, ErrSnapshotNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := snapshotter.RestoreSnapshot(snapshot.State); err != nil {
+		return err
+	}
+	aggregate.Root().version = snapshot.Version
+
+	return nil
+}
+
+// Save записывает новые события агрегата. Если поток изменили после
+// загрузки агрегата, возвращает ErrConcurrencyConflict; агрегат тогда нужно
+// загрузить заново и повторить команду
+func (r *Repository[T]) Save(ctx context.Context, aggregate T) error {
+	root := aggregate.Root()
+	if len(root.changes) == 0 {
+		return nil
+	}
+
+	envelopes := make([]events.Envelope, 0, len(root.changes))
+	for i, event := range root.changes {
+		envelope := events.Wrap(ctx, event, r.clock).WithAggregateVersion(root.version + i + 1)
+		envelope.AggregateType = aggregate.AggregateType()
+		envelope.AggregateID = aggregate.AggregateID()
+		envelopes = append(envelopes, envelope)
+	}
+
+	streamID := StreamID(aggregate.AggregateType(), aggregate.AggregateID())
+	if err := r.store.Append(ctx, streamID, root.version, envelopes...); err != nil {
+		return err
+	}
+
+	before := root.version
+	root.commit()
+
+	// события уже записаны: без снимка агрегат загрузится по событиям,
+	// поэтому ошибку снимка не возвращаем, чтобы вызывающий не повторил команду
+	if err := r.snapshot(ctx, aggregate, streamID, before); err != nil && r.options.onSnapshotError != nil {
+		r.options.onSnapshotError(ctx, streamID, err)
+	}
+
+	return nil
+}
+
+// snapshot сохраняет снимок, если после версии before агрегат прошёл
+// очередную границу в snapshotEvery событий
+func (r *Repository[T]) snapshot(ctx context.Context, aggregate T, streamID string, before int) error {
+	every := r.options.snapshotEvery
+	snapshotter, ok := any(aggregate).(Snapshotter)
+	if r.options.snapshots == nil || every <= 0 || !ok {
+		return nil
+	}
+
+	version := aggregate.Root().version
+	if version/every == before/every {
+		return nil
+	}
+
+	state, err := snapshotter.Snapshot()
+	if err != nil {
+		return err
+	}
+
+	return r.options.snapshots.SaveSnapshot(ctx, Snapshot{
+		StreamID: streamID,
+		Version:  version,
+		State:    state,
+		TakenAt:  r.clock.Now(),
+	})
+}
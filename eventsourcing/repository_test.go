@@ -0,0 +1,123 @@
+package eventsourcing_test
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/clock"
+	"github.com/MaksimDzhangirov/PracticalDDD/events"
+	"github.com/MaksimDzhangirov/PracticalDDD/events/outbox"
+	"github.com/MaksimDzhangirov/PracticalDDD/eventsourcing"
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// order - агрегат со снимками, который считает свои события
+type order struct {
+	eventsourcing.AggregateRoot
+	id     uuid.UUID
+	Events int `json:"events"`
+}
+
+func (o *order) AggregateType() string {
+	return events.AggregateOrder
+}
+
+func (o *order) AggregateID() uuid.UUID {
+	return o.id
+}
+
+func (o *order) Apply(event events.Event) error {
+	if created, ok := event.(events.OrderCreated); ok {
+		o.id = created.OrderID()
+	}
+	o.Events++
+	return nil
+}
+
+func (o *order) Snapshot() ([]byte, error) {
+	return json.Marshal(o)
+}
+
+func (o *order) RestoreSnapshot(state []byte) error {
+	return json.Unmarshal(state, o)
+}
+
+func TestRepositoryIsolatesSnapshotErrors(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "events.db") + "?_busy_timeout=5000"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := eventsourcing.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	// без таблицы снимков каждая запись снимка завершается ошибкой
+	if err := db.Migrator().DropTable(&eventsourcing.StoredSnapshot{}); err != nil {
+		t.Fatal(err)
+	}
+	rolledBack := 0
+	err = db.Callback().Raw().After("gorm:raw").Register("test:rollback_to", func(tx *gorm.DB) {
+		if strings.HasPrefix(tx.Statement.SQL.String(), "ROLLBACK TO SAVEPOINT") && tx.Error == nil {
+			rolledBack++
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := clock.Fixed(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	store := eventsourcing.NewGormStore(db, events.NewDefaultRegistry(events.JSONCodec{}), now)
+	var failed []string
+	repository := eventsourcing.NewRepository(store, func() *order { return &order{} }, now,
+		eventsourcing.WithSnapshots(store, 1),
+		eventsourcing.WithSnapshotErrorHandler(func(ctx context.Context, streamID string, err error) {
+			failed = append(failed, streamID)
+		}),
+	)
+
+	ids := []uuid.UUID{uuid.New(), uuid.New()}
+	err = outbox.Transaction(db)(context.Background(), func(ctx context.Context) error {
+		// запись после неудачного снимка продолжается в той же транзакции
+		for _, id := range ids {
+			aggregate := &order{}
+			if err := aggregate.Raise(aggregate, events.NewOrderCreated(id)); err != nil {
+				return err
+			}
+			if err := repository.Save(ctx, aggregate); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(failed) != len(ids) {
+		t.Fatalf("snapshot errors reported for %v, want %d streams", failed, len(ids))
+	}
+	// SQLite не прерывает транзакцию после ошибки, поэтому проверяем, что
+	// снимок откатывался до своей точки сохранения
+	if rolledBack != len(ids) {
+		t.Fatalf("rolled back to %d savepoints, want %d", rolledBack, len(ids))
+	}
+	if err := db.AutoMigrate(&eventsourcing.StoredSnapshot{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range ids {
+		aggregate, err := repository.Load(context.Background(), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if aggregate.Events != 1 {
+			t.Fatalf("order %s has %d events, want 1", id, aggregate.Events)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package eventsourcing
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/events"
+	"github.com/google/uuid"
+)
+
+const (
+	// NoStream - ожидаемая версия потока, который ещё не создан
+	NoStream = 0
+	// AnyVersion отключает проверку версии при записи
+	AnyVersion = -1
+)
+
+var (
+	ErrConcurrencyConflict = errors.New("stream was changed by another writer")
+	ErrAggregateNotFound   = errors.New("aggregate not found")
+	ErrSnapshotNotFound    = errors.New("snapshot not found")
+)
+
+// RecordedEvent - конверт события, записанный в поток StreamID
+type RecordedEvent struct {
+	// Position - сквозной номер события среди всех потоков хранилища
+	Position int64
+	StreamID string
+	// Version - номер события в потоке, начиная с 1
+	Version  int
+	Envelope events.Envelope
+}
+
+// Snapshot - состояние агрегата после события с номером Version
+type Snapshot struct {
+	StreamID string
+	Version  int
+	State    []byte
+	TakenAt  time.Time
+}
+
+// EventStore хранит потоки событий агрегатов
+type EventStore interface {
+	// Append записывает envelopes в конец потока, если его версия равна
+	// expectedVersion, иначе возвращает ErrConcurrencyConflict
+	Append(ctx context.Context, streamID string, expectedVersion int, envelopes ...events.Envelope) error
+	// Load возвращает события потока, начиная с версии fromVersion
+	Load(ctx context.Context, streamID string, fromVersion int) ([]RecordedEvent, error)
+	// LoadAll возвращает не больше limit событий всех потоков с позицией
+	// больше after в порядке позиций. Результат - непрерывный префикс: если
+	// событие с меньшей позицией ещё может быть зафиксировано, LoadAll
+	// останавливается перед пропуском, чтобы читатель не сдвинул позицию
+	// за событие, которое он ещё не видел
+	LoadAll(ctx context.Context, after int64, limit int) ([]RecordedEvent, error)
+	// CountAfter возвращает количество событий с позицией больше after;
+	// из-за пропусков позиций оно может быть меньше разницы позиций
+	CountAfter(ctx context.Context, after int64) (int64, error)
+}
+
+type SnapshotStore interface {
+	SaveSnapshot(ctx context.Context, snapshot Snapshot) error
+	// LoadSnapshot возвращает ErrSnapshotNotFound, если снимка потока нет
+	LoadSnapshot(ctx context.Context, streamID string) (Snapshot, error)
+}
+
+// StreamID - имя потока агрегата, например "customer-account-<uuid>"
+func StreamID(aggregateType string, id uuid.UUID) string {
+	return aggregateType + "-" + id.String()
+}
@@ -0,0 +1,259 @@
+package eventsourcing_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/clock"
+	"github.com/MaksimDzhangirov/PracticalDDD/events"
+	"github.com/MaksimDzhangirov/PracticalDDD/eventsourcing"
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var recordedAt = clock.Fixed(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+
+// store - хранилище событий и снимков для тестов обеих реализаций
+type store interface {
+	eventsourcing.EventStore
+	eventsourcing.SnapshotStore
+}
+
+func openDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "events.db") + "?_busy_timeout=5000"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := eventsourcing.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// stores возвращает MemoryStore и GormStore на чистой базе
+func stores(t *testing.T) map[string]store {
+	t.Helper()
+
+	return map[string]store{
+		"memory": eventsourcing.NewMemoryStore(),
+		"gorm":   eventsourcing.NewGormStore(openDB(t), events.NewDefaultRegistry(events.JSONCodec{}), recordedAt),
+	}
+}
+
+func envelopes(list ...events.Event) []events.Envelope {
+	result := make([]events.Envelope, 0, len(list))
+	for _, event := range list {
+		result = append(result, events.NewEnvelope(event, recordedAt))
+	}
+	return result
+}
+
+func TestAppendRejectsStaleVersion(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			orderID := uuid.New()
+			streamID := eventsourcing.StreamID(events.AggregateOrder, orderID)
+
+			if err := store.Append(ctx, streamID, eventsourcing.NoStream, envelopes(events.NewOrderCreated(orderID), events.NewOrderDispatched(orderID))...); err != nil {
+				t.Fatal(err)
+			}
+			for _, stale := range []int{eventsourcing.NoStream, 1, 3} {
+				if err := store.Append(ctx, streamID, stale, envelopes(events.NewOrderDelivered(orderID))...); !errors.Is(err, eventsourcing.ErrConcurrencyConflict) {
+					t.Fatalf("Append at version %d returned %v", stale, err)
+				}
+			}
+			if err := store.Append(ctx, streamID, 2, envelopes(events.NewOrderDeliveryFailed(orderID))...); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Append(ctx, streamID, eventsourcing.AnyVersion, envelopes(events.NewOrderDelivered(orderID))...); err != nil {
+				t.Fatal(err)
+			}
+
+			recorded, err := store.Load(ctx, streamID, 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// отклонённые записи не оставили событий в потоке
+			if len(recorded) != 2 || recorded[0].Version != 3 || recorded[1].Version != 4 {
+				t.Fatalf("Load from version 3 = %+v", recorded)
+			}
+			if _, ok := recorded[0].Envelope.Event.(events.OrderDeliveryFailed); !ok {
+				t.Fatalf("version 3 is %T", recorded[0].Envelope.Event)
+			}
+		})
+	}
+}
+
+func TestRepositoryDetectsConcurrentSave(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			repository := eventsourcing.NewRepository(store, func() *order { return &order{} }, recordedAt)
+			orderID := uuid.New()
+
+			created := &order{}
+			if err := created.Raise(created, events.NewOrderCreated(orderID)); err != nil {
+				t.Fatal(err)
+			}
+			if err := repository.Save(ctx, created); err != nil {
+				t.Fatal(err)
+			}
+
+			first, err := repository.Load(ctx, orderID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			second, err := repository.Load(ctx, orderID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, aggregate := range []*order{first, second} {
+				if err := aggregate.Raise(aggregate, events.NewOrderDispatched(orderID)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := repository.Save(ctx, first); err != nil {
+				t.Fatal(err)
+			}
+			if err := repository.Save(ctx, second); !errors.Is(err, eventsourcing.ErrConcurrencyConflict) {
+				t.Fatalf("saving a stale aggregate returned %v", err)
+			}
+			if second.Version() != 1 || len(second.Changes()) != 1 {
+				t.Fatalf("rejected aggregate has version %d and %d changes", second.Version(), len(second.Changes()))
+			}
+		})
+	}
+}
+
+// loadRecorder запоминает, с какой версии Repository читает поток
+type loadRecorder struct {
+	store
+	fromVersions []int
+}
+
+func (r *loadRecorder) Load(ctx context.Context, streamID string, fromVersion int) ([]eventsourcing.RecordedEvent, error) {
+	r.fromVersions = append(r.fromVersions, fromVersion)
+	return r.store.Load(ctx, streamID, fromVersion)
+}
+
+func TestRepositoryLoadsFromSnapshotAndTail(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			recorder := &loadRecorder{store: store}
+			repository := eventsourcing.NewRepository[*order](recorder, func() *order { return &order{} }, recordedAt,
+				eventsourcing.WithSnapshots(store, 3),
+			)
+			orderID := uuid.New()
+
+			aggregate := &order{}
+			// снимок делается, когда сохранение пересекает границу в 3 события
+			for _, batch := range [][]events.Event{
+				{events.NewOrderCreated(orderID), events.NewOrderDispatched(orderID)},
+				{events.NewDeliveryAddressChanged(orderID), events.NewOrderDeliveryFailed(orderID)},
+				{events.NewOrderDispatched(orderID)},
+			} {
+				for _, event := range batch {
+					if err := aggregate.Raise(aggregate, event); err != nil {
+						t.Fatal(err)
+					}
+				}
+				if err := repository.Save(ctx, aggregate); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			snapshot, err := store.LoadSnapshot(ctx, eventsourcing.StreamID(events.AggregateOrder, orderID))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if snapshot.Version != 4 || string(snapshot.State) != `{"events":4}` || !snapshot.TakenAt.Equal(recordedAt.Now()) {
+				t.Fatalf("snapshot = %+v, state %s", snapshot, snapshot.State)
+			}
+
+			loaded, err := repository.Load(ctx, orderID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(recorder.fromVersions) != 1 || recorder.fromVersions[0] != 5 {
+				t.Fatalf("Load read the stream from versions %v, want [5]", recorder.fromVersions)
+			}
+			if loaded.Version() != 5 || loaded.Events != 5 {
+				t.Fatalf("loaded version %d with %d events, want 5 and 5", loaded.Version(), loaded.Events)
+			}
+			if _, err := repository.Load(ctx, uuid.New()); !errors.Is(err, eventsourcing.ErrAggregateNotFound) {
+				t.Fatalf("Load of a missing aggregate returned %v", err)
+			}
+		})
+	}
+}
+
+func positions(recorded []eventsourcing.RecordedEvent) []int64 {
+	result := make([]int64, 0, len(recorded))
+	for _, event := range recorded {
+		result = append(result, event.Position)
+	}
+	return result
+}
+
+func TestGormStoreWaitsForGapsUntilTimeout(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	now := clock.NewManual(recordedAt.Now())
+	store := eventsourcing.NewGormStore(db, events.NewDefaultRegistry(events.JSONCodec{}), now, eventsourcing.WithGapTimeout(time.Minute))
+	createOrders(t, store, 4)
+
+	// событие на позиции 2 ещё не зафиксировано или его транзакция откатилась
+	if err := db.Delete(&eventsourcing.StoredEvent{}, 2).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	runner := eventsourcing.NewProjectionRunner(store, eventsourcing.NewMemoryCheckpoints())
+	var orders counter
+	if err := runner.Register(orders.projection("orders")); err != nil {
+		t.Fatal(err)
+	}
+
+	recorded, err := store.LoadAll(ctx, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := positions(recorded); len(got) != 1 || got[0] != 1 {
+		t.Fatalf("LoadAll(0) = %v before the gap timeout, want [1]", got)
+	}
+	if processed, err := runner.CatchUp(ctx); err != nil || processed != 1 {
+		t.Fatalf("CatchUp() = %d, %v; want 1", processed, err)
+	}
+	// CountAfter считает строки, а не разницу позиций
+	if lag, err := runner.Lag(ctx); err != nil || lag["orders"] != 2 {
+		t.Fatalf("Lag() = %v, %v; want 2", lag, err)
+	}
+
+	now.Advance(time.Minute - time.Second)
+	if processed, err := runner.CatchUp(ctx); err != nil || processed != 0 {
+		t.Fatalf("CatchUp() = %d, %v before the gap timeout; want 0", processed, err)
+	}
+
+	// за gapTimeout транзакция пропавшего события закончилась бы
+	now.Advance(time.Second)
+	recorded, err = store.LoadAll(ctx, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := positions(recorded); len(got) != 2 || got[0] != 3 || got[1] != 4 {
+		t.Fatalf("LoadAll(1) = %v after the gap timeout, want [3 4]", got)
+	}
+	if processed, err := runner.CatchUp(ctx); err != nil || processed != 2 {
+		t.Fatalf("CatchUp() = %d, %v after the gap timeout; want 2", processed, err)
+	}
+}
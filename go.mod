@@ -1,11 +1,19 @@
 module github.com/MaksimDzhangirov/PracticalDDD
 
-go 1.16
+go 1.21
 
 require (
 	flamingo.me/dingo v0.2.9
 	github.com/aws/aws-sdk-go v1.41.6
-	github.com/go-redis/redis/v8 v8.11.4 // indirect
+	github.com/go-redis/redis/v8 v8.11.4
 	github.com/google/uuid v1.3.0
-	gorm.io/gorm v1.21.16
+	gorm.io/driver/sqlite v1.6.0
+	gorm.io/gorm v1.30.0
+)
+
+require (
+	github.com/jinzhu/inflection v1.0.0 // indirect
+	github.com/jinzhu/now v1.1.5 // indirect
+	github.com/mattn/go-sqlite3 v1.14.22 // indirect
+	golang.org/x/text v0.21.0 // indirect
 )
@@ -1,69 +1,84 @@
-package main
+// Package example показывает использование gorm_generics с сущностью Product
+package example
 
 import (
 	"context"
 	"fmt"
 
+	gorm_generics "github.com/MaksimDzhangirov/PracticalDDD/gorm-generics"
 	"gorm.io/gorm"
-
-	"github.com/ompluscator/gorm-generics"
-	// какие-то импорты
 )
 
 // Product - сущность предметной области
 type Product struct {
-	// какие-то поля
+	ID          uint
+	Name        string
+	Weight      uint
+	IsAvailable bool
 }
 
 // ProductGorm - это DTO для сопоставления сущности Product с базой данных
 type ProductGorm struct {
-	// какие-то поля
+	ID          uint   `gorm:"primaryKey;column:id"`
+	Name        string `gorm:"column:name"`
+	Weight      uint   `gorm:"column:weight"`
+	IsAvailable bool   `gorm:"column:is_available"`
 }
 
 // ToEntity соответствует интерфейсу gorm_generics.GormModel
 func (g ProductGorm) ToEntity() Product {
 	return Product{
-		// какие-то поля
+		ID:          g.ID,
+		Name:        g.Name,
+		Weight:      g.Weight,
+		IsAvailable: g.IsAvailable,
 	}
 }
 
 // FromEntity соответствует интерфейсу gorm_generics.GormModel
 func (g ProductGorm) FromEntity(product Product) interface{} {
 	return ProductGorm{
-		// какие-то поля
+		ID:          product.ID,
+		Name:        product.Name,
+		Weight:      product.Weight,
+		IsAvailable: product.IsAvailable,
 	}
 }
 
-func main() {
-	db, err := gorm.Open( /* строка подключения к БД */ )
-	// обработка ошибки
-
-	err = db.AutoMigrate(ProductGorm{})
-	// обработка ошибки
+// Run сохраняет и читает Product через репозиторий; db - любое соединение GORM
+func Run(ctx context.Context, db *gorm.DB) error {
+	if err := db.AutoMigrate(ProductGorm{}); err != nil {
+		return err
+	}
 
 	// инициализируем новый репозиторий, передавая
 	// GORM модель и сущность как тип
 	repository := gorm_generics.NewRepository[ProductGorm, Product](db)
 
-	ctx := context.Background()
-
 	// создаём новую сущность
 	product := Product{
-		// какие-то поля
+		Name:        "product1",
+		Weight:      100,
+		IsAvailable: true,
 	}
 
 	// посылаем новую сущность в репозиторий для сохранения
-	err = repository.Insert(ctx, &product)
-	// обработка ошибки
+	if err := repository.Insert(ctx, &product); err != nil {
+		return err
+	}
 
 	fmt.Println(product)
 	// Выводит:
 	// {1 product1 100 true}
 
 	single, err := repository.FindByID(ctx, product.ID)
-	// обработка ошибки
+	if err != nil {
+		return err
+	}
 
 	fmt.Println(single)
 	// Выводит:
 	// {1 product1 100 true}
+
+	return nil
 }
@@ -0,0 +1,350 @@
+package gorm_generics
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm/schema"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidQuery  = errors.New("invalid query")
+)
+
+type Direction string
+
+const (
+	Ascending  Direction = "ASC"
+	Descending Direction = "DESC"
+)
+
+// Sort - колонка сортировки
+type Sort struct {
+	Field     string
+	Direction Direction
+}
+
+func Asc(field string) Sort {
+	return Sort{Field: field, Direction: Ascending}
+}
+
+func Desc(field string) Sort {
+	return Sort{Field: field, Direction: Descending}
+}
+
+// Query - параметры FindPage. Страницу можно выбрать смещением (Offset)
+// или курсором (After/Before) из предыдущей Page; курсор не зависит от
+// вставок перед страницей и не требует OFFSET в базе данных
+type Query struct {
+	Specification Specification
+	// Sort - порядок записей; первичный ключ всегда добавляется последним,
+	// чтобы порядок был однозначным. Поля, которые могут быть NULL
+	// (указатели, sql.NullString и т. п.), допустимы только с тегом not null
+	Sort  []Sort
+	Limit int
+	// Offset нельзя использовать вместе с курсорами
+	Offset int
+	After  string
+	Before string
+	// Fields ограничивает выбираемые колонки; остальные поля модели
+	// останутся нулевыми
+	Fields []string
+	// WithTotal считает количество записей, удовлетворяющих Specification
+	WithTotal bool
+}
+
+// Page - страница сущностей. Курсоры пустые, если страниц в эту сторону нет
+type Page[E any] struct {
+	Items      []E
+	Total      int64
+	NextCursor string
+	PrevCursor string
+	HasNext    bool
+	HasPrev    bool
+}
+
+// FindPage возвращает страницу сущностей, удовлетворяющих query.Specification
+func (r *GormRepository[M, E]) FindPage(ctx context.Context, query Query) (Page[E], error) {
+	if query.Offset > 0 && (query.After != "" || query.Before != "") {
+		return Page[E]{}, fmt.Errorf("%w: offset and cursor are mutually exclusive", ErrInvalidQuery)
+	}
+	if query.After != "" && query.Before != "" {
+		return Page[E]{}, fmt.Errorf("%w: after and before are mutually exclusive", ErrInvalidQuery)
+	}
+
+	fields, sorts, err := r.sortFields(query.Sort)
+	if err != nil {
+		return Page[E]{}, err
+	}
+
+	db, err := r.where(ctx, query.Specification)
+	if err != nil {
+		return Page[E]{}, err
+	}
+
+	if len(query.Fields) > 0 {
+		columns, err := r.columns(query.Fields, fields)
+		if err != nil {
+			return Page[E]{}, err
+		}
+		db = db.Select(columns)
+	}
+
+	backward := query.Before != ""
+	cursor := query.After
+	if backward {
+		cursor = query.Before
+	}
+	if cursor != "" {
+		values, err := decodeCursor(cursor, fields)
+		if err != nil {
+			return Page[E]{}, err
+		}
+		condition, args := keyset(sorts, values, backward)
+		db = db.Where(condition, args...)
+	}
+
+	for _, sort := range sorts {
+		direction := sort.Direction
+		if backward {
+			direction = reverse(direction)
+		}
+		db = db.Order(fmt.Sprintf("%s %s", sort.Field, direction))
+	}
+	if query.Limit > 0 {
+		db = db.Limit(query.Limit + 1)
+	}
+	if query.Offset > 0 {
+		db = db.Offset(query.Offset)
+	}
+
+	var models []M
+	if err := db.Find(&models).Error; err != nil {
+		return Page[E]{}, err
+	}
+
+	// лишняя запись показывает, что в эту сторону есть ещё страница
+	more := query.Limit > 0 && len(models) > query.Limit
+	if more {
+		models = models[:query.Limit]
+	}
+	if backward {
+		for i, j := 0, len(models)-1; i < j; i, j = i+1, j-1 {
+			models[i], models[j] = models[j], models[i]
+		}
+	}
+
+	page := Page[E]{
+		Items:   make([]E, 0, len(models)),
+		HasNext: more,
+		HasPrev: query.After != "" || query.Offset > 0,
+	}
+	if backward {
+		page.HasNext = true
+		page.HasPrev = more
+	}
+	for _, model := range models {
+		page.Items = append(page.Items, model.ToEntity())
+	}
+
+	if len(models) > 0 {
+		if page.HasNext {
+			if page.NextCursor, err = encodeCursor(ctx, &models[len(models)-1], fields); err != nil {
+				return Page[E]{}, err
+			}
+		}
+		if page.HasPrev {
+			if page.PrevCursor, err = encodeCursor(ctx, &models[0], fields); err != nil {
+				return Page[E]{}, err
+			}
+		}
+	}
+
+	if query.WithTotal {
+		page.Total, err = r.total(ctx, query, len(models), more)
+		if err != nil {
+			return Page[E]{}, err
+		}
+	}
+
+	return page, nil
+}
+
+// total не делает запрос COUNT, если первая страница вместила все записи
+func (r *GormRepository[M, E]) total(ctx context.Context, query Query, count int, more bool) (int64, error) {
+	first := query.Offset == 0 && query.After == "" && query.Before == ""
+	if first && !more {
+		return int64(count), nil
+	}
+	return r.Count(ctx, query.Specification)
+}
+
+// sortFields проверяет колонки сортировки и добавляет первичный ключ
+func (r *GormRepository[M, E]) sortFields(sorts []Sort) ([]*schema.Field, []Sort, error) {
+	modelSchema, err := r.schema()
+	if err != nil {
+		return nil, nil, err
+	}
+	primary := modelSchema.PrioritizedPrimaryField
+	if primary == nil {
+		return nil, nil, fmt.Errorf("%w: %s has no primary key", ErrInvalidQuery, modelSchema.Name)
+	}
+
+	fields := make([]*schema.Field, 0, len(sorts)+1)
+	result := make([]Sort, 0, len(sorts)+1)
+	hasPrimary := false
+	for _, sort := range sorts {
+		field := modelSchema.LookUpField(sort.Field)
+		if field == nil || field.DBName == "" {
+			return nil, nil, fmt.Errorf("%w: %q", ErrInvalidField, sort.Field)
+		}
+		if nullable(field) {
+			return nil, nil, fmt.Errorf("%w: sort field %q is nullable", ErrInvalidQuery, sort.Field)
+		}
+		direction := Direction(strings.ToUpper(string(sort.Direction)))
+		switch direction {
+		case "":
+			direction = Ascending
+		case Ascending, Descending:
+		default:
+			return nil, nil, fmt.Errorf("%w: sort direction %q", ErrInvalidQuery, sort.Direction)
+		}
+
+		fields = append(fields, field)
+		result = append(result, Sort{Field: field.DBName, Direction: direction})
+		hasPrimary = hasPrimary || field == primary
+	}
+	if !hasPrimary {
+		fields = append(fields, primary)
+		result = append(result, Asc(primary.DBName))
+	}
+
+	return fields, result, nil
+}
+
+// nullable сообщает, может ли поле хранить NULL. Условие keyset "a > ?"
+// не выбирает записи с NULL, поэтому по таким полям сортировать нельзя
+func nullable(field *schema.Field) bool {
+	if field.PrimaryKey || field.NotNull {
+		return false
+	}
+	if field.FieldType.Kind() == reflect.Pointer {
+		return true
+	}
+	_, scanner := reflect.New(field.FieldType).Interface().(sql.Scanner)
+	return scanner
+}
+
+// columns проверяет выбранные колонки и добавляет колонки сортировки,
+// без которых нельзя построить курсор
+func (r *GormRepository[M, E]) columns(names []string, sortFields []*schema.Field) ([]string, error) {
+	modelSchema, err := r.schema()
+	if err != nil {
+		return nil, err
+	}
+
+	selected := map[string]bool{}
+	var result []string
+	add := func(name string) {
+		if !selected[name] {
+			selected[name] = true
+			result = append(result, name)
+		}
+	}
+	for _, name := range names {
+		field := modelSchema.LookUpField(name)
+		if field == nil || field.DBName == "" {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidField, name)
+		}
+		add(field.DBName)
+	}
+	for _, field := range sortFields {
+		add(field.DBName)
+	}
+
+	return result, nil
+}
+
+// keyset строит условие "после записи values" для порядка sorts:
+// (a > ?) OR (a = ? AND b > ?) OR ...
+func keyset(sorts []Sort, values []any, backward bool) (string, []any) {
+	var conditions []string
+	var args []any
+	for i, sort := range sorts {
+		var parts []string
+		for j := 0; j < i; j++ {
+			parts = append(parts, fmt.Sprintf("%s = ?", sorts[j].Field))
+			args = append(args, values[j])
+		}
+
+		operator := ">"
+		if (sort.Direction == Descending) != backward {
+			operator = "<"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s ?", sort.Field, operator))
+		args = append(args, values[i])
+
+		conditions = append(conditions, "("+strings.Join(parts, " AND ")+")")
+	}
+
+	return strings.Join(conditions, " OR "), args
+}
+
+func reverse(direction Direction) Direction {
+	if direction == Descending {
+		return Ascending
+	}
+	return Descending
+}
+
+// encodeCursor сохраняет значения колонок сортировки записи model; ValueOf
+// учитывает встроенные структуры, в том числе по указателю
+func encodeCursor(ctx context.Context, model any, fields []*schema.Field) (string, error) {
+	row := reflect.ValueOf(model)
+	values := make([]any, 0, len(fields))
+	for _, field := range fields {
+		value, _ := field.ValueOf(ctx, row)
+		values = append(values, value)
+	}
+
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// decodeCursor восстанавливает значения в типах полей модели, чтобы,
+// например, время сравнивалось как время, а не как строка
+func decodeCursor(cursor string, fields []*schema.Field) ([]any, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	if len(raw) != len(fields) {
+		return nil, fmt.Errorf("%w: cursor does not match sort order", ErrInvalidCursor)
+	}
+
+	values := make([]any, 0, len(fields))
+	for i, field := range fields {
+		value := reflect.New(field.StructField.Type)
+		if err := json.Unmarshal(raw[i], value.Interface()); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+		}
+		values = append(values, value.Elem().Interface())
+	}
+
+	return values, nil
+}
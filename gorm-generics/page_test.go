@@ -0,0 +1,226 @@
+package gorm_generics_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	gorm_generics "github.com/MaksimDzhangirov/PracticalDDD/gorm-generics"
+)
+
+// draftGorm - модель с полем, которое может быть NULL
+type draftGorm struct {
+	ID          uint `gorm:"primaryKey"`
+	PublishedAt *time.Time
+}
+
+func (g draftGorm) ToEntity() draftGorm {
+	return g
+}
+
+func (g draftGorm) FromEntity(entity draftGorm) interface{} {
+	return entity
+}
+
+// insertWeights сохраняет товары с весами weights, веса повторяются,
+// чтобы порядок определялся и первичным ключом
+func insertWeights(t *testing.T, repository *gorm_generics.GormRepository[productGorm, product], weights ...uint) {
+	t.Helper()
+
+	for _, weight := range weights {
+		entity := product{Name: "product", Weight: weight}
+		if err := repository.Insert(context.Background(), &entity); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func ids(products []product) []uint {
+	result := make([]uint, 0, len(products))
+	for _, entity := range products {
+		result = append(result, entity.ID)
+	}
+	return result
+}
+
+func equal(a, b []uint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// sorted - порядок "вес по убыванию, затем ID", прочитанный одной страницей
+func sorted(t *testing.T, repository *gorm_generics.GormRepository[productGorm, product]) []uint {
+	t.Helper()
+
+	page, err := repository.FindPage(context.Background(), gorm_generics.Query{
+		Sort: []gorm_generics.Sort{gorm_generics.Desc("weight")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ids(page.Items)
+}
+
+func TestFindPageCursorRoundTrip(t *testing.T) {
+	repository := gorm_generics.NewRepository[productGorm, product](openDB(t))
+	ctx := context.Background()
+	insertWeights(t, repository, 3, 1, 2, 3, 1, 2, 3)
+	want := sorted(t, repository)
+
+	var got []uint
+	query := gorm_generics.Query{
+		Sort:  []gorm_generics.Sort{gorm_generics.Desc("weight")},
+		Limit: 3,
+	}
+	for pages := 0; ; pages++ {
+		if pages > len(want) {
+			t.Fatal("paging does not stop")
+		}
+		page, err := repository.FindPage(ctx, query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page.HasPrev != (query.After != "") {
+			t.Fatalf("page %d: HasPrev %v", pages, page.HasPrev)
+		}
+		got = append(got, ids(page.Items)...)
+		if !page.HasNext {
+			if page.NextCursor != "" {
+				t.Fatal("last page has a next cursor")
+			}
+			break
+		}
+		query.After = page.NextCursor
+	}
+
+	if !equal(got, want) {
+		t.Fatalf("paged %v, want %v", got, want)
+	}
+
+	if _, err := repository.FindPage(ctx, gorm_generics.Query{After: "not a cursor"}); !errors.Is(err, gorm_generics.ErrInvalidCursor) {
+		t.Fatalf("invalid cursor returned %v", err)
+	}
+}
+
+func TestFindPageBackward(t *testing.T) {
+	repository := gorm_generics.NewRepository[productGorm, product](openDB(t))
+	ctx := context.Background()
+	insertWeights(t, repository, 3, 1, 2, 3, 1, 2, 3)
+	want := sorted(t, repository)
+
+	// доходим до последней страницы
+	query := gorm_generics.Query{
+		Sort:  []gorm_generics.Sort{gorm_generics.Desc("weight")},
+		Limit: 3,
+	}
+	var last gorm_generics.Page[product]
+	for {
+		page, err := repository.FindPage(ctx, query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		last = page
+		if !page.HasNext {
+			break
+		}
+		query.After = page.NextCursor
+	}
+
+	// и возвращаемся к первой
+	got := ids(last.Items)
+	query.After = ""
+	query.Before = last.PrevCursor
+	for pages := 0; ; pages++ {
+		if pages > len(want) {
+			t.Fatal("paging does not stop")
+		}
+		page, err := repository.FindPage(ctx, query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !page.HasNext || page.NextCursor == "" {
+			t.Fatalf("page %d before the last one has no next page", pages)
+		}
+		got = append(ids(page.Items), got...)
+		if !page.HasPrev {
+			break
+		}
+		query.Before = page.PrevCursor
+	}
+
+	if !equal(got, want) {
+		t.Fatalf("paged back %v, want %v", got, want)
+	}
+}
+
+func TestFindPageWithTotal(t *testing.T) {
+	repository := gorm_generics.NewRepository[productGorm, product](openDB(t))
+	ctx := context.Background()
+	insertWeights(t, repository, 1, 2, 3, 4, 5, 6)
+	heavy := gorm_generics.GreaterThan("weight", 2)
+
+	page, err := repository.FindPage(ctx, gorm_generics.Query{
+		Specification: heavy,
+		Limit:         3,
+		WithTotal:     true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 4 || len(page.Items) != 3 {
+		t.Fatalf("first page: total %d, items %d", page.Total, len(page.Items))
+	}
+
+	next, err := repository.FindPage(ctx, gorm_generics.Query{
+		Specification: heavy,
+		Limit:         3,
+		After:         page.NextCursor,
+		WithTotal:     true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.Total != 4 || len(next.Items) != 1 || next.HasNext {
+		t.Fatalf("second page: total %d, items %d, has next %v", next.Total, len(next.Items), next.HasNext)
+	}
+
+	// все записи на первой странице
+	all, err := repository.FindPage(ctx, gorm_generics.Query{Limit: 10, WithTotal: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if all.Total != 6 {
+		t.Fatalf("single page total %d, want 6", all.Total)
+	}
+
+	without, err := repository.FindPage(ctx, gorm_generics.Query{Limit: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if without.Total != 0 {
+		t.Fatalf("total %d without WithTotal", without.Total)
+	}
+}
+
+func TestFindPageRejectsNullableSort(t *testing.T) {
+	db := openDB(t)
+	if err := db.AutoMigrate(&draftGorm{}); err != nil {
+		t.Fatal(err)
+	}
+	repository := gorm_generics.NewRepository[draftGorm, draftGorm](db)
+
+	_, err := repository.FindPage(context.Background(), gorm_generics.Query{
+		Sort: []gorm_generics.Sort{gorm_generics.Asc("published_at")},
+	})
+	if !errors.Is(err, gorm_generics.ErrInvalidQuery) {
+		t.Fatalf("nullable sort returned %v, want ErrInvalidQuery", err)
+	}
+}
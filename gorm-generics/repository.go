@@ -1,15 +1,64 @@
-package main
+// Package gorm_generics - обобщённый репозиторий поверх GORM: сущность
+// предметной области E хранится в базе через модель GORM M
+package gorm_generics
 
-import "context"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
 
-func (r *GormRepository[M, E]) Insert(ctx context.Context, entity *E) error {
-	// отображаем данные из Entity в DTO
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+var (
+	ErrNotFound     = errors.New("record not found")
+	ErrInvalidModel = errors.New("FromEntity returned unexpected model type")
+)
+
+// GormModel отображает сущность E в модель GORM и обратно. FromEntity
+// должна вернуть значение того же типа, что и модель
+type GormModel[E any] interface {
+	ToEntity() E
+	FromEntity(entity E) interface{}
+}
+
+// GormRepository работает в транзакции UnitOfWork, если она есть в ctx
+type GormRepository[M GormModel[E], E any] struct {
+	db *gorm.DB
+}
+
+// NewRepository создаёт репозиторий для модели M и сущности E:
+//
+//	repository := gorm_generics.NewRepository[ProductGorm, Product](db)
+func NewRepository[M GormModel[E], E any](db *gorm.DB) *GormRepository[M, E] {
+	return &GormRepository[M, E]{
+		db: db,
+	}
+}
+
+// toModel отображает данные из Entity в DTO
+func (r *GormRepository[M, E]) toModel(entity E) (M, error) {
 	var start M
-	model := start.FromEntity(*entity).(M)
+	model, ok := start.FromEntity(entity).(M)
+	if !ok {
+		return start, fmt.Errorf("%w: %T", ErrInvalidModel, start.FromEntity(entity))
+	}
+	return model, nil
+}
+
+func (r *GormRepository[M, E]) Insert(ctx context.Context, entity *E) error {
+	model, err := r.toModel(*entity)
+	if err != nil {
+		return err
+	}
 
 	// создаём новую запись в базе данных
-	err := r.db.WithContext(ctx).Create(&model).Error
-	// обработка ошибки
+	if err := DB(ctx, r.db).Create(&model).Error; err != nil {
+		return err
+	}
 
 	// отображаем новую запись из базы в Entity
 	*entity = model.ToEntity()
@@ -19,18 +68,32 @@ func (r *GormRepository[M, E]) Insert(ctx context.Context, entity *E) error {
 func (r *GormRepository[M, E]) FindByID(ctx context.Context, id uint) (E, error) {
 	// извлекаем запись по id из базы данных
 	var model M
-	err := r.db.WithContext(ctx).First(&model, id).Error
-	// обработка ошибки
+	err := DB(ctx, r.db).First(&model, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		var zero E
+		return zero, ErrNotFound
+	}
+	if err != nil {
+		var zero E
+		return zero, err
+	}
 
 	// отображаем запись в Entity
 	return model.ToEntity(), nil
 }
 
+// Find возвращает сущности, удовлетворяющие specification; nil - все сущности
 func (r *GormRepository[M, E]) Find(ctx context.Context, specification Specification) ([]E, error) {
 	// получаем записи по некоторому критерию
+	query, err := r.where(ctx, specification)
+	if err != nil {
+		return nil, err
+	}
+
 	var models []M
-	err := r.db.WithContext(ctx).Where(specification.GetQuery(), specification.GetValues()...).Find(&models).Error
-	// обработка ошибки
+	if err := query.Find(&models).Error; err != nil {
+		return nil, err
+	}
 
 	// отображаем все записи в Entities
 	result := make([]E, 0, len(models))
@@ -40,3 +103,100 @@ func (r *GormRepository[M, E]) Find(ctx context.Context, specification Specifica
 
 	return result, nil
 }
+
+// Update сохраняет все поля сущности, включая нулевые значения, кроме
+// первичного ключа и CreatedAt
+func (r *GormRepository[M, E]) Update(ctx context.Context, entity *E) error {
+	model, err := r.toModel(*entity)
+	if err != nil {
+		return err
+	}
+	modelSchema, err := r.schema()
+	if err != nil {
+		return err
+	}
+
+	omit := []string{"CreatedAt"}
+	for _, field := range modelSchema.PrimaryFields {
+		omit = append(omit, field.Name)
+	}
+	result := DB(ctx, r.db).Model(&model).Select("*").Omit(omit...).Updates(&model)
+	if result.Error != nil {
+		return result.Error
+	}
+	// MySQL не учитывает в RowsAffected строки, значения которых не
+	// изменились, поэтому наличие записи проверяется отдельно
+	if result.RowsAffected == 0 {
+		exists, err := r.exists(ctx, modelSchema, model)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrNotFound
+		}
+	}
+
+	*entity = model.ToEntity()
+	return nil
+}
+
+// exists ищет запись с первичным ключом model
+func (r *GormRepository[M, E]) exists(ctx context.Context, modelSchema *schema.Schema, model M) (bool, error) {
+	query := DB(ctx, r.db).Model(new(M))
+	for _, field := range modelSchema.PrimaryFields {
+		value, _ := field.ValueOf(ctx, reflect.ValueOf(model))
+		query = query.Where(clause.Eq{Column: clause.Column{Name: field.DBName}, Value: value})
+	}
+
+	var count int64
+	err := query.Count(&count).Error
+	return count > 0, err
+}
+
+func (r *GormRepository[M, E]) Delete(ctx context.Context, id uint) error {
+	var model M
+	result := DB(ctx, r.db).Delete(&model, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Count возвращает количество сущностей, удовлетворяющих specification
+func (r *GormRepository[M, E]) Count(ctx context.Context, specification Specification) (int64, error) {
+	query, err := r.where(ctx, specification)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	err = query.Count(&count).Error
+
+	return count, err
+}
+
+func (r *GormRepository[M, E]) schema() (*schema.Schema, error) {
+	var model M
+	statement := &gorm.Statement{DB: r.db}
+	if err := statement.Parse(&model); err != nil {
+		return nil, err
+	}
+	return statement.Schema, nil
+}
+
+func (r *GormRepository[M, E]) where(ctx context.Context, specification Specification) (*gorm.DB, error) {
+	var model M
+	query := DB(ctx, r.db).Model(&model)
+	if specification == nil {
+		return query, nil
+	}
+	if err := Validate(specification); err != nil {
+		return nil, err
+	}
+
+	return query.Where(specification.GetQuery(), specification.GetValues()...), nil
+}
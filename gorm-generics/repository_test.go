@@ -0,0 +1,180 @@
+package gorm_generics_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gorm_generics "github.com/MaksimDzhangirov/PracticalDDD/gorm-generics"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type product struct {
+	ID        uint
+	Name      string
+	Weight    uint
+	CreatedAt time.Time
+}
+
+type productGorm struct {
+	ID        uint `gorm:"primaryKey"`
+	Name      string
+	Weight    uint
+	CreatedAt time.Time
+}
+
+func (g productGorm) ToEntity() product {
+	return product{
+		ID:        g.ID,
+		Name:      g.Name,
+		Weight:    g.Weight,
+		CreatedAt: g.CreatedAt,
+	}
+}
+
+func (g productGorm) FromEntity(entity product) interface{} {
+	return productGorm{
+		ID:        entity.ID,
+		Name:      entity.Name,
+		Weight:    entity.Weight,
+		CreatedAt: entity.CreatedAt,
+	}
+}
+
+func openDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "repository.db") + "?_busy_timeout=5000"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&productGorm{}); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+// insert сохраняет товары с весом 1, 2, ... count
+func insert(t *testing.T, repository *gorm_generics.GormRepository[productGorm, product], count int) []product {
+	t.Helper()
+
+	products := make([]product, 0, count)
+	for i := 1; i <= count; i++ {
+		entity := product{Name: "product", Weight: uint(i)}
+		if err := repository.Insert(context.Background(), &entity); err != nil {
+			t.Fatal(err)
+		}
+		products = append(products, entity)
+	}
+
+	return products
+}
+
+func TestRepositoryInsertAndFindByID(t *testing.T) {
+	repository := gorm_generics.NewRepository[productGorm, product](openDB(t))
+	ctx := context.Background()
+
+	entity := product{Name: "table", Weight: 20}
+	if err := repository.Insert(ctx, &entity); err != nil {
+		t.Fatal(err)
+	}
+	if entity.ID == 0 || entity.CreatedAt.IsZero() {
+		t.Fatalf("inserted entity was not updated from the database: %+v", entity)
+	}
+
+	found, err := repository.FindByID(ctx, entity.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.Name != "table" || found.Weight != 20 {
+		t.Fatalf("found %+v", found)
+	}
+
+	if _, err := repository.FindByID(ctx, entity.ID+1); !errors.Is(err, gorm_generics.ErrNotFound) {
+		t.Fatalf("FindByID of a missing entity returned %v, want ErrNotFound", err)
+	}
+}
+
+func TestRepositoryFindAndCount(t *testing.T) {
+	repository := gorm_generics.NewRepository[productGorm, product](openDB(t))
+	ctx := context.Background()
+	insert(t, repository, 5)
+
+	third := gorm_generics.Equal("weight", 3)
+	found, err := repository.Find(ctx, third)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].Weight != 3 {
+		t.Fatalf("found %+v, want the product with weight 3", found)
+	}
+
+	all, err := repository.Find(ctx, nil)
+	if err != nil || len(all) != 5 {
+		t.Fatalf("found %d products, err %v", len(all), err)
+	}
+
+	if count, err := repository.Count(ctx, third); err != nil || count != 1 {
+		t.Fatalf("count %d, err %v", count, err)
+	}
+	if count, err := repository.Count(ctx, nil); err != nil || count != 5 {
+		t.Fatalf("count %d, err %v", count, err)
+	}
+}
+
+func TestRepositoryUpdate(t *testing.T) {
+	repository := gorm_generics.NewRepository[productGorm, product](openDB(t))
+	ctx := context.Background()
+	inserted := insert(t, repository, 1)[0]
+
+	// нулевые значения сохраняются, а CreatedAt из сущности не затирает запись
+	changed := product{ID: inserted.ID, Name: "", Weight: 0}
+	if err := repository.Update(ctx, &changed); err != nil {
+		t.Fatal(err)
+	}
+	found, err := repository.FindByID(ctx, inserted.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.Name != "" || found.Weight != 0 {
+		t.Fatalf("zero values were not saved: %+v", found)
+	}
+	if !found.CreatedAt.Equal(inserted.CreatedAt) {
+		t.Fatalf("CreatedAt changed from %v to %v", inserted.CreatedAt, found.CreatedAt)
+	}
+
+	// запись без изменений существует
+	if err := repository.Update(ctx, &found); err != nil {
+		t.Fatalf("unchanged update returned %v", err)
+	}
+
+	missing := product{ID: inserted.ID + 1, Name: "missing"}
+	if err := repository.Update(ctx, &missing); !errors.Is(err, gorm_generics.ErrNotFound) {
+		t.Fatalf("Update of a missing entity returned %v, want ErrNotFound", err)
+	}
+}
+
+func TestRepositoryDelete(t *testing.T) {
+	repository := gorm_generics.NewRepository[productGorm, product](openDB(t))
+	ctx := context.Background()
+	products := insert(t, repository, 2)
+
+	if err := repository.Delete(ctx, products[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repository.FindByID(ctx, products[0].ID); !errors.Is(err, gorm_generics.ErrNotFound) {
+		t.Fatalf("deleted entity was found, err %v", err)
+	}
+	if err := repository.Delete(ctx, products[0].ID); !errors.Is(err, gorm_generics.ErrNotFound) {
+		t.Fatalf("second Delete returned %v, want ErrNotFound", err)
+	}
+	if count, err := repository.Count(ctx, nil); err != nil || count != 1 {
+		t.Fatalf("count %d, err %v", count, err)
+	}
+}
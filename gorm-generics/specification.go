@@ -1,28 +1,82 @@
-package main
+package gorm_generics
 
 import (
+	"errors"
 	"fmt"
+	"regexp"
 	"strings"
 )
 
+var ErrInvalidField = errors.New("invalid field name")
+
 type Specification interface {
 	GetQuery() string
 	GetValues() []any
 }
 
+// validator реализуют спецификации, которые могут быть построены с ошибкой
+type validator interface {
+	Validate() error
+}
+
+// Validate проверяет спецификацию и все вложенные в неё спецификации.
+// Репозиторий вызывает её перед запросом
+func Validate(specification Specification) error {
+	if v, ok := specification.(validator); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+// fieldPattern допускает имя колонки или таблица.колонка
+var fieldPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// invalidSpecification заменяет спецификацию с недопустимым полем: поле не
+// попадает в запрос, а Validate возвращает ошибку
+type invalidSpecification struct {
+	err error
+}
+
+func (s invalidSpecification) GetQuery() string {
+	return "1 = 0"
+}
+
+func (s invalidSpecification) GetValues() []any {
+	return nil
+}
+
+func (s invalidSpecification) Validate() error {
+	return s.err
+}
+
+// field возвращает invalidSpecification, если имя поля недопустимо
+func field(name string, build func() Specification) Specification {
+	if !fieldPattern.MatchString(name) {
+		return invalidSpecification{err: fmt.Errorf("%w: %q", ErrInvalidField, name)}
+	}
+	return build()
+}
+
 // joinSpecification - это действующая реализация интерфейса Specification
 // Она используется для операторов AND и OR
 type joinSpecification struct {
 	specifications []Specification
 	separator      string
+	// empty - запрос для пустого списка спецификаций
+	empty string
 }
 
-// GetQuery объединяет все подзапросы
+// GetQuery объединяет все подзапросы, заключая каждый в скобки,
+// чтобы вложенные OR не меняли смысл AND
 func (s joinSpecification) GetQuery() string {
+	if len(s.specifications) == 0 {
+		return s.empty
+	}
+
 	queries := make([]string, 0, len(s.specifications))
 
 	for _, spec := range s.specifications {
-		queries = append(queries, spec.GetQuery())
+		queries = append(queries, fmt.Sprintf("(%s)", spec.GetQuery()))
 	}
 
 	return strings.Join(queries, fmt.Sprintf(" %s ", s.separator))
@@ -39,11 +93,29 @@ func (s joinSpecification) GetValues() []any {
 	return values
 }
 
+func (s joinSpecification) Validate() error {
+	var errs []error
+	for _, spec := range s.specifications {
+		errs = append(errs, Validate(spec))
+	}
+	return errors.Join(errs...)
+}
+
 // And передаёт AND оператор в виде Specification
 func And(specifications ...Specification) Specification {
 	return joinSpecification{
 		specifications: specifications,
 		separator:      "AND",
+		empty:          "1 = 1",
+	}
+}
+
+// Or передаёт OR оператор в виде Specification
+func Or(specifications ...Specification) Specification {
+	return joinSpecification{
+		specifications: specifications,
+		separator:      "OR",
+		empty:          "1 = 0",
 	}
 }
 
@@ -54,7 +126,11 @@ type notSpecification struct {
 
 // GetQuery отрицает подзапрос
 func (s notSpecification) GetQuery() string {
-	return fmt.Sprintf(" NOT (%s)", s.Specification.GetQuery())
+	return fmt.Sprintf("NOT (%s)", s.Specification.GetQuery())
+}
+
+func (s notSpecification) Validate() error {
+	return Validate(s.Specification)
 }
 
 // Not передаёт NOT оператор в виде Specification
@@ -65,7 +141,7 @@ func Not(specification Specification) Specification {
 }
 
 // binaryOperatorSpecification определяет бинарный оператор как Specification
-// Он используется для операторов =, >, <, >=, <=.
+// Он используется для операторов =, <>, >, <, >=, <=, LIKE.
 type binaryOperatorSpecification[T any] struct {
 	field    string
 	operator string
@@ -82,11 +158,115 @@ func (s binaryOperatorSpecification[T]) GetValues() []any {
 	return []any{s.value}
 }
 
+func binary[T any](name string, operator string, value T) Specification {
+	return field(name, func() Specification {
+		return binaryOperatorSpecification[T]{
+			field:    name,
+			operator: operator,
+			value:    value,
+		}
+	})
+}
+
 // Equal передаёт оператор равенства в виде Specification
 func Equal[T any](field string, value T) Specification {
-	return binaryOperatorSpecification[T]{
-		field:    field,
-		operator: "=",
-		value:    value,
-	}
+	return binary(field, "=", value)
+}
+
+func NotEqual[T any](field string, value T) Specification {
+	return binary(field, "<>", value)
+}
+
+func GreaterThan[T any](field string, value T) Specification {
+	return binary(field, ">", value)
+}
+
+func LessThan[T any](field string, value T) Specification {
+	return binary(field, "<", value)
+}
+
+func GreaterOrEqual[T any](field string, value T) Specification {
+	return binary(field, ">=", value)
+}
+
+func LessOrEqual[T any](field string, value T) Specification {
+	return binary(field, "<=", value)
+}
+
+// Like сравнивает поле с шаблоном, где % - любая строка, а _ - любой символ
+func Like(field string, pattern string) Specification {
+	return binary(field, "LIKE", pattern)
+}
+
+// rawSpecification - запрос, собранный внутри пакета из проверенного поля
+type rawSpecification struct {
+	query  string
+	values []any
+}
+
+func (s rawSpecification) GetQuery() string {
+	return s.query
+}
+
+func (s rawSpecification) GetValues() []any {
+	return s.values
+}
+
+// ILike - Like без учёта регистра; LOWER работает во всех базах, поддерживаемых GORM
+func ILike(name string, pattern string) Specification {
+	return field(name, func() Specification {
+		return rawSpecification{
+			query:  fmt.Sprintf("LOWER(%s) LIKE LOWER(?)", name),
+			values: []any{pattern},
+		}
+	})
+}
+
+// In проверяет, что поле равно одному из values; пустой список ничему не соответствует
+func In[T any](name string, values []T) Specification {
+	return field(name, func() Specification {
+		if len(values) == 0 {
+			return rawSpecification{query: "1 = 0"}
+		}
+		return rawSpecification{
+			query:  fmt.Sprintf("%s IN ?", name),
+			values: []any{values},
+		}
+	})
+}
+
+// NotIn проверяет, что поле не равно ни одному из values; пустой список
+// соответствует всем записям
+func NotIn[T any](name string, values []T) Specification {
+	return field(name, func() Specification {
+		if len(values) == 0 {
+			return rawSpecification{query: "1 = 1"}
+		}
+		return rawSpecification{
+			query:  fmt.Sprintf("%s NOT IN ?", name),
+			values: []any{values},
+		}
+	})
+}
+
+// Between проверяет, что поле находится между from и to включительно
+func Between[T any](name string, from T, to T) Specification {
+	return field(name, func() Specification {
+		return rawSpecification{
+			query:  fmt.Sprintf("%s BETWEEN ? AND ?", name),
+			values: []any{from, to},
+		}
+	})
+}
+
+func IsNull(name string) Specification {
+	return field(name, func() Specification {
+		return rawSpecification{query: fmt.Sprintf("%s IS NULL", name)}
+	})
+}
+
+func IsNotNull(name string) Specification {
+	return field(name, func() Specification {
+		return rawSpecification{query: fmt.Sprintf("%s IS NOT NULL", name)}
+	})
 }
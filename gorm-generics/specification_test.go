@@ -0,0 +1,182 @@
+package gorm_generics_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	gorm_generics "github.com/MaksimDzhangirov/PracticalDDD/gorm-generics"
+	"gorm.io/gorm"
+)
+
+func TestFindRejectsInvalidFields(t *testing.T) {
+	db := openDB(t)
+	repository := gorm_generics.NewRepository[productGorm, product](db)
+	insert(t, repository, 3)
+
+	injected := gorm_generics.Equal("name; DROP TABLE product_gorms", 1)
+	for name, specification := range map[string]gorm_generics.Specification{
+		"equal": injected,
+		"not":   gorm_generics.Not(injected),
+		"or":    gorm_generics.Or(gorm_generics.Equal("name", "product"), injected),
+		"and":   gorm_generics.And(gorm_generics.GreaterThan("weight", 1), gorm_generics.Not(gorm_generics.Or(injected))),
+	} {
+		if _, err := repository.Find(context.Background(), specification); !errors.Is(err, gorm_generics.ErrInvalidField) {
+			t.Errorf("%s: Find returned %v, want ErrInvalidField", name, err)
+		}
+	}
+
+	if !db.Migrator().HasTable(&productGorm{}) {
+		t.Fatal("table was dropped")
+	}
+}
+
+func TestAndGroupsNestedOr(t *testing.T) {
+	repository := gorm_generics.NewRepository[productGorm, product](openDB(t))
+	ctx := context.Background()
+	for _, entity := range []product{{Name: "chair", Weight: 1}, {Name: "table", Weight: 3}, {Name: "chair", Weight: 3}, {Name: "chair", Weight: 5}} {
+		if err := repository.Insert(ctx, &entity); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// без скобок запрос стал бы name = chair AND weight = 1 OR weight = 3
+	found, err := repository.Find(ctx, gorm_generics.And(
+		gorm_generics.Equal("name", "chair"),
+		gorm_generics.Or(gorm_generics.Equal("weight", 1), gorm_generics.Equal("weight", 3)),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 2 {
+		t.Fatalf("found %+v, want chairs with weight 1 and 3", found)
+	}
+	for _, entity := range found {
+		if entity.Name != "chair" || (entity.Weight != 1 && entity.Weight != 3) {
+			t.Fatalf("found %+v", entity)
+		}
+	}
+}
+
+func TestEmptyInAndNotIn(t *testing.T) {
+	repository := gorm_generics.NewRepository[productGorm, product](openDB(t))
+	ctx := context.Background()
+	insert(t, repository, 3)
+
+	for name, test := range map[string]struct {
+		specification gorm_generics.Specification
+		want          int
+	}{
+		"in":        {gorm_generics.In("weight", []uint{}), 0},
+		"not in":    {gorm_generics.NotIn("weight", []uint{}), 3},
+		"not(in)":   {gorm_generics.Not(gorm_generics.In("weight", []uint{})), 3},
+		"in values": {gorm_generics.In("weight", []uint{1, 3}), 2},
+	} {
+		found, err := repository.Find(ctx, test.specification)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(found) != test.want {
+			t.Errorf("%s: found %d products, want %d", name, len(found), test.want)
+		}
+	}
+}
+
+func TestComparisonOperators(t *testing.T) {
+	repository := gorm_generics.NewRepository[productGorm, product](openDB(t))
+	ctx := context.Background()
+	insert(t, repository, 5)
+
+	for name, test := range map[string]struct {
+		specification gorm_generics.Specification
+		want          []uint
+	}{
+		"equal":            {gorm_generics.Equal("weight", 3), []uint{3}},
+		"not equal":        {gorm_generics.NotEqual("weight", 3), []uint{1, 2, 4, 5}},
+		"greater than":     {gorm_generics.GreaterThan("weight", 3), []uint{4, 5}},
+		"less than":        {gorm_generics.LessThan("weight", 3), []uint{1, 2}},
+		"greater or equal": {gorm_generics.GreaterOrEqual("weight", 4), []uint{4, 5}},
+		"less or equal":    {gorm_generics.LessOrEqual("weight", 2), []uint{1, 2}},
+		"between":          {gorm_generics.Between("weight", 2, 4), []uint{2, 3, 4}},
+		"empty between":    {gorm_generics.Between("weight", 4, 2), nil},
+	} {
+		found, err := repository.Find(ctx, test.specification)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(found) != len(test.want) {
+			t.Errorf("%s: found %+v, want weights %v", name, found, test.want)
+			continue
+		}
+		for i, entity := range found {
+			if entity.Weight != test.want[i] {
+				t.Errorf("%s: found %+v, want weights %v", name, found, test.want)
+				break
+			}
+		}
+	}
+}
+
+func TestLikeAndILike(t *testing.T) {
+	repository := gorm_generics.NewRepository[productGorm, product](openDB(t))
+	ctx := context.Background()
+	for _, name := range []string{"Oak table", "oak chair", "pine table", "Tab"} {
+		entity := product{Name: name, Weight: 1}
+		if err := repository.Insert(ctx, &entity); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for name, test := range map[string]struct {
+		specification gorm_generics.Specification
+		want          int
+	}{
+		"prefix":            {gorm_generics.Like("name", "pine%"), 1},
+		"suffix":            {gorm_generics.Like("name", "% table"), 2},
+		"single character":  {gorm_generics.Like("name", "Ta_"), 1},
+		"ignoring case":     {gorm_generics.ILike("name", "OAK%"), 2},
+		"ignoring case all": {gorm_generics.ILike("name", "%TAB%"), 3},
+	} {
+		found, err := repository.Find(ctx, test.specification)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(found) != test.want {
+			t.Errorf("%s: found %+v, want %d products", name, found, test.want)
+		}
+	}
+
+	if _, err := repository.Find(ctx, gorm_generics.ILike("LOWER(name)", "oak%")); !errors.Is(err, gorm_generics.ErrInvalidField) {
+		t.Fatalf("ILike with an expression returned %v, want ErrInvalidField", err)
+	}
+}
+
+func TestIsNullAndIsNotNull(t *testing.T) {
+	db := openDB(t)
+	repository := gorm_generics.NewRepository[productGorm, product](db)
+	ctx := context.Background()
+	products := insert(t, repository, 3)
+
+	if err := db.Model(&productGorm{}).Where("id = ?", products[0].ID).Update("name", gorm.Expr("NULL")).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	unnamed, err := repository.Find(ctx, gorm_generics.IsNull("name"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unnamed) != 1 || unnamed[0].ID != products[0].ID {
+		t.Fatalf("IsNull found %+v, want product %d", unnamed, products[0].ID)
+	}
+
+	if count, err := repository.Count(ctx, gorm_generics.IsNotNull("name")); err != nil || count != 2 {
+		t.Fatalf("IsNotNull count %d, err %v; want 2", count, err)
+	}
+	// NULL не равен ни одному значению, поэтому NotEqual его не находит
+	if count, err := repository.Count(ctx, gorm_generics.NotEqual("name", "table")); err != nil || count != 2 {
+		t.Fatalf("NotEqual count %d, err %v; want 2", count, err)
+	}
+	if _, err := repository.Find(ctx, gorm_generics.IsNull("name IS NULL OR 1")); !errors.Is(err, gorm_generics.ErrInvalidField) {
+		t.Fatalf("IsNull with an expression returned %v, want ErrInvalidField", err)
+	}
+}
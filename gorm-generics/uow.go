@@ -0,0 +1,100 @@
+package gorm_generics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"gorm.io/gorm"
+)
+
+type (
+	txKey    struct{}
+	depthKey struct{}
+)
+
+// savepoints нумерует точки сохранения, чтобы их имена не повторялись
+var savepoints atomic.Uint64
+
+// ContextWithTx сохраняет транзакцию, в которой должны работать репозитории
+func ContextWithTx(ctx context.Context, tx *gorm.DB) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// TxFromContext возвращает транзакцию, открытую UnitOfWork
+func TxFromContext(ctx context.Context) (*gorm.DB, bool) {
+	tx, ok := ctx.Value(txKey{}).(*gorm.DB)
+	return tx, ok
+}
+
+// DB возвращает транзакцию из ctx или, если её нет, соединение db
+func DB(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx
+	}
+	return db.WithContext(ctx)
+}
+
+// UnitOfWork выполняет изменения нескольких репозиториев атомарно:
+// все GormRepository и другие хранилища, которые берут соединение через
+// DB(ctx, db), работают в транзакции из ctx
+//
+//	err := uow.Do(ctx, func(ctx context.Context) error {
+//		if err := orders.Insert(ctx, &order); err != nil {
+//			return err
+//		}
+//		return box.Notify(ctx, events.NewOrderCreated(order.ID))
+//	})
+type UnitOfWork struct {
+	db *gorm.DB
+}
+
+func NewUnitOfWork(db *gorm.DB) *UnitOfWork {
+	return &UnitOfWork{
+		db: db,
+	}
+}
+
+// Do открывает транзакцию и фиксирует её, если fn вернула nil, иначе
+// откатывает. Паника в fn откатывает транзакцию и передаётся дальше.
+// Вложенный вызов Do создаёт точку сохранения: его ошибка откатывает только
+// изменения вложенного fn, а после успеха точка сохранения освобождается
+func (u *UnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	tx, ok := TxFromContext(ctx)
+	if !ok {
+		return u.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return fn(ContextWithTx(ctx, tx))
+		})
+	}
+
+	return savepoint(ctx, tx, fn)
+}
+
+// savepoint выполняет fn в точке сохранения транзакции tx. Глубина
+// вложенности хранится в ctx и входит в имя точки сохранения
+func savepoint(ctx context.Context, tx *gorm.DB, fn func(ctx context.Context) error) error {
+	depth, _ := ctx.Value(depthKey{}).(int)
+	depth++
+	name := fmt.Sprintf("uow_%d_%d", depth, savepoints.Add(1))
+	if err := tx.SavePoint(name).Error; err != nil {
+		return err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.RollbackTo(name)
+			panic(r)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, depthKey{}, depth)); err != nil {
+		if rollbackErr := tx.RollbackTo(name).Error; rollbackErr != nil {
+			return errors.Join(err, rollbackErr)
+		}
+		return err
+	}
+
+	// без RELEASE точки сохранения копятся до конца длинной транзакции
+	return tx.Exec("RELEASE SAVEPOINT " + name).Error
+}
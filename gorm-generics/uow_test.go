@@ -0,0 +1,199 @@
+package gorm_generics_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	gorm_generics "github.com/MaksimDzhangirov/PracticalDDD/gorm-generics"
+	"gorm.io/gorm"
+)
+
+var errRollback = errors.New("rollback")
+
+// names возвращает имена сохранённых товаров
+func names(t *testing.T, repository *gorm_generics.GormRepository[productGorm, product]) map[string]bool {
+	t.Helper()
+
+	products, err := repository.Find(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := map[string]bool{}
+	for _, entity := range products {
+		result[entity.Name] = true
+	}
+	return result
+}
+
+func TestUnitOfWorkNestedSavepoints(t *testing.T) {
+	db := openDB(t)
+	repository := gorm_generics.NewRepository[productGorm, product](db)
+	uow := gorm_generics.NewUnitOfWork(db)
+
+	save := func(ctx context.Context, name string) error {
+		return repository.Insert(ctx, &product{Name: name})
+	}
+
+	err := uow.Do(context.Background(), func(ctx context.Context) error {
+		if err := save(ctx, "outer"); err != nil {
+			return err
+		}
+		// первый уровень
+		return uow.Do(ctx, func(ctx context.Context) error {
+			if err := save(ctx, "level1"); err != nil {
+				return err
+			}
+			// второй уровень: ошибка третьего откатывает только его изменения
+			err := uow.Do(ctx, func(ctx context.Context) error {
+				if err := save(ctx, "level2"); err != nil {
+					return err
+				}
+				err := uow.Do(ctx, func(ctx context.Context) error {
+					if err := save(ctx, "level3-failed"); err != nil {
+						return err
+					}
+					return errRollback
+				})
+				if !errors.Is(err, errRollback) {
+					return err
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			// соседний второй уровень откатывается вместе с успешным третьим
+			err = uow.Do(ctx, func(ctx context.Context) error {
+				if err := save(ctx, "level2-failed"); err != nil {
+					return err
+				}
+				if err := uow.Do(ctx, func(ctx context.Context) error {
+					return save(ctx, "level3-of-failed")
+				}); err != nil {
+					return err
+				}
+				return errRollback
+			})
+			if !errors.Is(err, errRollback) {
+				return err
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	saved := names(t, repository)
+	for _, name := range []string{"outer", "level1", "level2"} {
+		if !saved[name] {
+			t.Errorf("%s was rolled back", name)
+		}
+	}
+	for _, name := range []string{"level3-failed", "level2-failed", "level3-of-failed"} {
+		if saved[name] {
+			t.Errorf("%s was committed", name)
+		}
+	}
+}
+
+func TestUnitOfWorkNestedPanicRollsBackSavepoint(t *testing.T) {
+	db := openDB(t)
+	repository := gorm_generics.NewRepository[productGorm, product](db)
+	uow := gorm_generics.NewUnitOfWork(db)
+
+	err := uow.Do(context.Background(), func(ctx context.Context) error {
+		if err := repository.Insert(ctx, &product{Name: "outer"}); err != nil {
+			return err
+		}
+
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("nested panic was not propagated")
+				}
+			}()
+			uow.Do(ctx, func(ctx context.Context) error {
+				if err := repository.Insert(ctx, &product{Name: "panicked"}); err != nil {
+					return err
+				}
+				panic("nested")
+			})
+		}()
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	saved := names(t, repository)
+	if !saved["outer"] || saved["panicked"] {
+		t.Fatalf("saved %v, want only outer", saved)
+	}
+}
+
+func TestUnitOfWorkRollsBackOnError(t *testing.T) {
+	db := openDB(t)
+	repository := gorm_generics.NewRepository[productGorm, product](db)
+	uow := gorm_generics.NewUnitOfWork(db)
+
+	err := uow.Do(context.Background(), func(ctx context.Context) error {
+		if err := repository.Insert(ctx, &product{Name: "outer"}); err != nil {
+			return err
+		}
+		if err := uow.Do(ctx, func(ctx context.Context) error {
+			return repository.Insert(ctx, &product{Name: "nested"})
+		}); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("Do returned %v", err)
+	}
+	if saved := names(t, repository); len(saved) != 0 {
+		t.Fatalf("saved %v after rollback", saved)
+	}
+}
+
+func TestUnitOfWorkReleasesSavepoints(t *testing.T) {
+	db := openDB(t)
+	released := 0
+	err := db.Callback().Raw().After("gorm:raw").Register("test:release", func(tx *gorm.DB) {
+		if strings.HasPrefix(tx.Statement.SQL.String(), "RELEASE SAVEPOINT") && tx.Error == nil {
+			released++
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repository := gorm_generics.NewRepository[productGorm, product](db)
+	uow := gorm_generics.NewUnitOfWork(db)
+
+	err = uow.Do(context.Background(), func(ctx context.Context) error {
+		for i := 0; i < 3; i++ {
+			if err := uow.Do(ctx, func(ctx context.Context) error {
+				return repository.Insert(ctx, &product{Name: "nested"})
+			}); err != nil {
+				return err
+			}
+		}
+		// откаченная точка сохранения не освобождается
+		if err := uow.Do(ctx, func(ctx context.Context) error {
+			return errRollback
+		}); !errors.Is(err, errRollback) {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if released != 3 {
+		t.Fatalf("released %d savepoints, want 3", released)
+	}
+}
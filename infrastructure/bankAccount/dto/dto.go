@@ -16,6 +16,7 @@ type BankAccountGorm struct {
 
 type CurrencyGorm struct {
 	UUID string `gorm:"column:uuid"`
+	Code string `gorm:"column:code"`
 }
 
 type PersonGorm struct {
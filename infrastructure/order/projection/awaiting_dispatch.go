@@ -0,0 +1,82 @@
+package projection
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/events"
+	"github.com/MaksimDzhangirov/PracticalDDD/eventsourcing"
+	"github.com/google/uuid"
+)
+
+// инфраструктурный уровень
+
+// AwaitingDispatch - модель чтения "заказы, ожидающие отправки":
+// заказ попадает в неё по OrderCreated и покидает по OrderDispatched.
+// Модель хранится в памяти и строится заново при каждом запуске; повторная
+// доставка события её не меняет
+type AwaitingDispatch struct {
+	mutex  sync.RWMutex
+	orders map[uuid.UUID]time.Time
+}
+
+func NewAwaitingDispatch() *AwaitingDispatch {
+	return &AwaitingDispatch{
+		orders: map[uuid.UUID]time.Time{},
+	}
+}
+
+// Projection возвращает проекцию для eventsourcing.ProjectionRunner
+func (a *AwaitingDispatch) Projection() *eventsourcing.Projection {
+	projection := eventsourcing.NewVolatileProjection("order.awaiting-dispatch", a.Reset)
+	eventsourcing.When(projection, a.onOrderCreated)
+	eventsourcing.When(projection, a.onOrderDispatched)
+	return projection
+}
+
+// Orders возвращает ID заказов, начиная с самых старых
+func (a *AwaitingDispatch) Orders() []uuid.UUID {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	result := make([]uuid.UUID, 0, len(a.orders))
+	for id := range a.orders {
+		result = append(result, id)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return a.orders[result[i]].Before(a.orders[result[j]])
+	})
+
+	return result
+}
+
+func (a *AwaitingDispatch) Reset(ctx context.Context) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.orders = map[uuid.UUID]time.Time{}
+	return nil
+}
+
+func (a *AwaitingDispatch) onOrderCreated(ctx context.Context, event events.OrderCreated) error {
+	createdAt := time.Time{}
+	if envelope, ok := events.EnvelopeFromContext(ctx); ok {
+		createdAt = envelope.OccurredAt
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.orders[event.OrderID()] = createdAt
+	return nil
+}
+
+func (a *AwaitingDispatch) onOrderDispatched(ctx context.Context, event events.OrderDispatched) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	delete(a.orders, event.OrderID())
+	return nil
+}
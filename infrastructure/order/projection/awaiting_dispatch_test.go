@@ -0,0 +1,73 @@
+package projection_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/clock"
+	"github.com/MaksimDzhangirov/PracticalDDD/events"
+	"github.com/MaksimDzhangirov/PracticalDDD/eventsourcing"
+	"github.com/MaksimDzhangirov/PracticalDDD/infrastructure/order/projection"
+	"github.com/google/uuid"
+)
+
+func TestAwaitingDispatch(t *testing.T) {
+	ctx := context.Background()
+	now := clock.NewManual(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	store := eventsourcing.NewMemoryStore()
+	notifier := eventsourcing.NewEventStoreNotifier(store, now)
+	view := projection.NewAwaitingDispatch()
+	runner := eventsourcing.NewProjectionRunner(store, eventsourcing.NewMemoryCheckpoints())
+	if err := runner.Register(view.Projection()); err != nil {
+		t.Fatal(err)
+	}
+
+	first, second, third := uuid.New(), uuid.New(), uuid.New()
+	for _, event := range []events.Event{
+		events.NewOrderCreated(third),
+		events.NewOrderCreated(first),
+		events.NewOrderDispatched(third),
+		events.NewOrderCreated(second),
+		events.NewOrderDelivered(first),
+	} {
+		now.Advance(time.Minute)
+		if err := notifier.Notify(ctx, event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := runner.CatchUp(ctx); err != nil {
+		t.Fatal(err)
+	}
+	assertOrders := func(want ...uuid.UUID) {
+		t.Helper()
+
+		orders := view.Orders()
+		if len(orders) != len(want) {
+			t.Fatalf("Orders() = %v, want %v", orders, want)
+		}
+		for i := range want {
+			if orders[i] != want[i] {
+				t.Fatalf("Orders() = %v, want %v", orders, want)
+			}
+		}
+	}
+	// заказы идут по времени создания; доставка заказ не убирает
+	assertOrders(first, second)
+
+	// повторная доставка OrderDispatched ничего не меняет
+	recorded, err := store.Load(ctx, eventsourcing.StreamID(events.AggregateOrder, third), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := view.Projection().Handle(events.ContextWithEnvelope(ctx, recorded[1].Envelope), recorded[1].Envelope.Event); err != nil {
+		t.Fatal(err)
+	}
+	assertOrders(first, second)
+
+	if err := runner.Rebuild(ctx, "order.awaiting-dispatch"); err != nil {
+		t.Fatal(err)
+	}
+	assertOrders(first, second)
+}
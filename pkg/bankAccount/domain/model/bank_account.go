@@ -1,6 +1,10 @@
 package model
 
-import "github.com/google/uuid"
+import (
+	"errors"
+
+	"github.com/google/uuid"
+)
 
 // Сущность
 type BankAccount struct {
@@ -10,14 +14,22 @@ type BankAccount struct {
 	currency Currency
 }
 
-func NewBankAccount(currency Currency) BankAccount {
+func NewBankAccount(id uuid.UUID, iban string, currency Currency) BankAccount {
 	return BankAccount{
-		//
-		// определяем поля
-		//
+		id:       id,
+		iban:     iban,
+		currency: currency,
 	}
 }
 
+func (ba BankAccount) IBAN() string {
+	return ba.iban
+}
+
+func (ba BankAccount) Amount() int {
+	return ba.amount
+}
+
 func (ba BankAccount) HasMoney() bool {
 	return ba.amount > 0
 }
@@ -62,7 +74,23 @@ func (bas BankAccounts) HasCurrency(currency Currency) bool {
 	return false
 }
 
-func (bas BankAccounts) AddMoney(amount int, currency Currency) error {
-	panic("not implemented")
-	return nil
+func (bas BankAccounts) ForCurrency(currency Currency) (BankAccount, bool) {
+	for _, ba := range bas {
+		if ba.IsForCurrency(currency) {
+			return ba, true
+		}
+	}
+
+	return BankAccount{}, false
+}
+
+func (bas BankAccounts) AddMoney(amount int, iban string) error {
+	for i := range bas {
+		if bas[i].iban == iban {
+			bas[i].amount += amount
+			return nil
+		}
+	}
+
+	return errors.New("bank account not found")
 }
@@ -1,15 +1,42 @@
 package model
 
-import "github.com/google/uuid"
+import (
+	value_objects "github.com/MaksimDzhangirov/PracticalDDD/value-objects"
+	"github.com/google/uuid"
+)
 
 // Сущность
 type Currency struct {
-	id uuid.UUID
+	id         uuid.UUID
+	code       string
+	minorUnits int
 	//
 	// какие-то поля
 	//
 }
 
+// NewCurrency берёт код и количество знаков после запятой из справочника валют
+func NewCurrency(id uuid.UUID, code string) (Currency, error) {
+	currency, err := value_objects.CurrencyByCode(code)
+	if err != nil {
+		return Currency{}, err
+	}
+
+	return Currency{
+		id:         id,
+		code:       currency.Code,
+		minorUnits: currency.Scale,
+	}, nil
+}
+
+func (c Currency) Code() string {
+	return c.code
+}
+
+func (c Currency) MinorUnits() int {
+	return c.minorUnits
+}
+
 func (c Currency) Equal(other Currency) bool {
 	return c.id == other.id
 }
@@ -1,12 +1,19 @@
 package model
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/events"
+	"github.com/MaksimDzhangirov/PracticalDDD/eventsourcing"
 	"github.com/google/uuid"
 )
 
-// Сущность и агрегат
+// Сущность и агрегат. Состояние меняется только событиями в Apply,
+// поэтому агрегат можно восстановить из EventStore
 type CustomerAccount struct {
+	eventsourcing.AggregateRoot
 	id        uuid.UUID
 	isDeleted bool
 	isLocked  bool
@@ -19,13 +26,39 @@ type CustomerAccount struct {
 	//
 }
 
+// NewCustomerAccount создаёт пустой агрегат для eventsourcing.Repository
+func NewCustomerAccount() *CustomerAccount {
+	return &CustomerAccount{}
+}
+
+// OpenCustomerAccount открывает новый счёт клиента
+func OpenCustomerAccount(id uuid.UUID) (*CustomerAccount, error) {
+	ca := NewCustomerAccount()
+	if err := ca.Raise(ca, CustomerAccountCreated{AccountID: id}); err != nil {
+		return nil, err
+	}
+
+	return ca, nil
+}
+
+func (ca *CustomerAccount) ID() uuid.UUID {
+	return ca.id
+}
+
+func (ca *CustomerAccount) AggregateType() string {
+	return AggregateCustomerAccount
+}
+
+func (ca *CustomerAccount) AggregateID() uuid.UUID {
+	return ca.id
+}
+
 func (ca *CustomerAccount) GetIBANForCurrency(currency Currency) (string, error) {
-	for _, account := range ca.accounts {
-		if account.IsForCurrency(currency) {
-			return account.iban, nil
-		}
+	account, ok := ca.accounts.ForCurrency(currency)
+	if !ok {
+		return "", errors.New("this account does not support this currency")
 	}
-	return "", errors.New("this account does not support this currency")
+	return account.iban, nil
 }
 
 func (ca *CustomerAccount) MarkAsDeleted() error {
@@ -36,18 +69,21 @@ func (ca *CustomerAccount) MarkAsDeleted() error {
 		return errors.New("bank account is in debt")
 	}
 
-	ca.isDeleted = true
-
-	return nil
+	return ca.Raise(ca, CustomerAccountDeleted{AccountID: ca.id})
 }
 
-func (ca *CustomerAccount) CreateAccountForCurrency(currency Currency) error {
+func (ca *CustomerAccount) CreateAccountForCurrency(iban string, currency Currency) error {
 	if ca.accounts.HasCurrency(currency) {
 		return errors.New("there is already bank account for that currency")
 	}
-	ca.accounts = append(ca.accounts, NewBankAccount(currency))
 
-	return nil
+	return ca.Raise(ca, BankAccountOpened{
+		AccountID:     ca.id,
+		BankAccountID: uuid.New(),
+		IBAN:          iban,
+		CurrencyID:    currency.id,
+		CurrencyCode:  currency.code,
+	})
 }
 
 func (ca *CustomerAccount) AddMoney(amount int, currency Currency) error {
@@ -58,5 +94,93 @@ func (ca *CustomerAccount) AddMoney(amount int, currency Currency) error {
 		return errors.New("account is locked")
 	}
 
-	return ca.accounts.AddMoney(amount, currency)
+	account, ok := ca.accounts.ForCurrency(currency)
+	if !ok {
+		return errors.New("this account does not support this currency")
+	}
+
+	return ca.Raise(ca, MoneyDeposited{
+		AccountID: ca.id,
+		IBAN:      account.iban,
+		Amount:    amount,
+	})
+}
+
+func (ca *CustomerAccount) Apply(event events.Event) error {
+	switch actualEvent := event.(type) {
+	case CustomerAccountCreated:
+		ca.id = actualEvent.AccountID
+	case BankAccountOpened:
+		currency, err := NewCurrency(actualEvent.CurrencyID, actualEvent.CurrencyCode)
+		if err != nil {
+			return err
+		}
+		ca.accounts = append(ca.accounts, NewBankAccount(actualEvent.BankAccountID, actualEvent.IBAN, currency))
+	case MoneyDeposited:
+		return ca.accounts.AddMoney(actualEvent.Amount, actualEvent.IBAN)
+	case CustomerAccountDeleted:
+		ca.isDeleted = true
+	default:
+		return fmt.Errorf("customer account: unexpected event %s", event.Name())
+	}
+
+	return nil
+}
+
+// customerAccountSnapshot - состояние CustomerAccount в снимке
+type customerAccountSnapshot struct {
+	ID        uuid.UUID                `json:"id"`
+	IsDeleted bool                     `json:"is_deleted"`
+	IsLocked  bool                     `json:"is_locked"`
+	Accounts  []bankAccountSnapshotRow `json:"accounts"`
+}
+
+type bankAccountSnapshotRow struct {
+	ID           uuid.UUID `json:"id"`
+	IBAN         string    `json:"iban"`
+	Amount       int       `json:"amount"`
+	CurrencyID   uuid.UUID `json:"currency_id"`
+	CurrencyCode string    `json:"currency_code"`
+}
+
+func (ca *CustomerAccount) Snapshot() ([]byte, error) {
+	state := customerAccountSnapshot{
+		ID:        ca.id,
+		IsDeleted: ca.isDeleted,
+		IsLocked:  ca.isLocked,
+	}
+	for _, account := range ca.accounts {
+		state.Accounts = append(state.Accounts, bankAccountSnapshotRow{
+			ID:           account.id,
+			IBAN:         account.iban,
+			Amount:       account.amount,
+			CurrencyID:   account.currency.id,
+			CurrencyCode: account.currency.code,
+		})
+	}
+
+	return json.Marshal(state)
+}
+
+func (ca *CustomerAccount) RestoreSnapshot(data []byte) error {
+	var state customerAccountSnapshot
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+
+	ca.id = state.ID
+	ca.isDeleted = state.IsDeleted
+	ca.isLocked = state.IsLocked
+	ca.accounts = nil
+	for _, row := range state.Accounts {
+		currency, err := NewCurrency(row.CurrencyID, row.CurrencyCode)
+		if err != nil {
+			return err
+		}
+		account := NewBankAccount(row.ID, row.IBAN, currency)
+		account.amount = row.Amount
+		ca.accounts = append(ca.accounts, account)
+	}
+
+	return nil
 }
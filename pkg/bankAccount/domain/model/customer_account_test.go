@@ -0,0 +1,166 @@
+package model_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/clock"
+	"github.com/MaksimDzhangirov/PracticalDDD/eventsourcing"
+	"github.com/MaksimDzhangirov/PracticalDDD/pkg/bankAccount/domain/model"
+	"github.com/google/uuid"
+)
+
+func currency(t *testing.T, code string) model.Currency {
+	t.Helper()
+
+	currency, err := model.NewCurrency(uuid.New(), code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return currency
+}
+
+// openAccount открывает счёт клиента со счётом в валюте currency
+func openAccount(t *testing.T, currency model.Currency) *model.CustomerAccount {
+	t.Helper()
+
+	account, err := model.OpenCustomerAccount(uuid.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := account.CreateAccountForCurrency("DE89370400440532013000", currency); err != nil {
+		t.Fatal(err)
+	}
+	return account
+}
+
+func snapshot(t *testing.T, account *model.CustomerAccount) []byte {
+	t.Helper()
+
+	state, err := account.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return state
+}
+
+func TestCustomerAccountLifecycle(t *testing.T) {
+	eur, usd := currency(t, "EUR"), currency(t, "USD")
+	account := openAccount(t, eur)
+
+	if err := account.CreateAccountForCurrency("DE02120300000000202051", eur); err == nil {
+		t.Fatal("opened a second bank account in EUR")
+	}
+	if err := account.AddMoney(500, usd); err == nil {
+		t.Fatal("deposited USD without a USD bank account")
+	}
+	if err := account.AddMoney(500, eur); err != nil {
+		t.Fatal(err)
+	}
+	if iban, err := account.GetIBANForCurrency(eur); err != nil || iban != "DE89370400440532013000" {
+		t.Fatalf("GetIBANForCurrency(EUR) = %q, %v", iban, err)
+	}
+	if err := account.MarkAsDeleted(); err == nil {
+		t.Fatal("deleted an account with money")
+	}
+
+	// открытие, счёт в EUR и пополнение; отклонённые команды событий не дают
+	if n := len(account.Changes()); n != 3 {
+		t.Fatalf("account raised %d events, want 3", n)
+	}
+
+	if err := account.AddMoney(-700, eur); err != nil {
+		t.Fatal(err)
+	}
+	if err := account.MarkAsDeleted(); err == nil {
+		t.Fatal("deleted an account in debt")
+	}
+
+	empty := openAccount(t, eur)
+	if err := empty.MarkAsDeleted(); err != nil {
+		t.Fatal(err)
+	}
+	if err := empty.AddMoney(100, eur); err == nil {
+		t.Fatal("deposited money to a deleted account")
+	}
+}
+
+func TestCustomerAccountRebuildsFromEvents(t *testing.T) {
+	eur, usd := currency(t, "EUR"), currency(t, "USD")
+	account := openAccount(t, eur)
+	if err := account.CreateAccountForCurrency("US64SVBKUS6S3300958879", usd); err != nil {
+		t.Fatal(err)
+	}
+	if err := account.AddMoney(500, eur); err != nil {
+		t.Fatal(err)
+	}
+	if err := account.AddMoney(-500, eur); err != nil {
+		t.Fatal(err)
+	}
+	if err := account.MarkAsDeleted(); err != nil {
+		t.Fatal(err)
+	}
+
+	rebuilt := model.NewCustomerAccount()
+	for _, event := range account.Changes() {
+		if err := rebuilt.Apply(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if rebuilt.ID() != account.ID() || !bytes.Equal(snapshot(t, rebuilt), snapshot(t, account)) {
+		t.Fatalf("rebuilt %s, want %s", snapshot(t, rebuilt), snapshot(t, account))
+	}
+
+	// тот же агрегат через EventStore
+	ctx := context.Background()
+	repository := eventsourcing.NewRepository(eventsourcing.NewMemoryStore(), model.NewCustomerAccount, clock.System)
+	if err := repository.Save(ctx, account); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := repository.Load(ctx, account.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Version() != 6 || !bytes.Equal(snapshot(t, loaded), snapshot(t, account)) {
+		t.Fatalf("loaded version %d: %s", loaded.Version(), snapshot(t, loaded))
+	}
+}
+
+func TestCustomerAccountSnapshotRoundTrip(t *testing.T) {
+	eur := currency(t, "EUR")
+	account := openAccount(t, eur)
+	if err := account.AddMoney(500, eur); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := model.NewCustomerAccount()
+	if err := restored.RestoreSnapshot(snapshot(t, account)); err != nil {
+		t.Fatal(err)
+	}
+	if restored.ID() != account.ID() || !bytes.Equal(snapshot(t, restored), snapshot(t, account)) {
+		t.Fatalf("restored %s, want %s", snapshot(t, restored), snapshot(t, account))
+	}
+
+	// восстановленный агрегат сохраняет валюту и остаток
+	if err := restored.MarkAsDeleted(); err == nil {
+		t.Fatal("restored account lost its balance")
+	}
+	if err := restored.AddMoney(-500, eur); err != nil {
+		t.Fatal(err)
+	}
+	if err := restored.MarkAsDeleted(); err != nil {
+		t.Fatal(err)
+	}
+
+	deleted := model.NewCustomerAccount()
+	if err := deleted.RestoreSnapshot(snapshot(t, restored)); err != nil {
+		t.Fatal(err)
+	}
+	if err := deleted.AddMoney(100, eur); err == nil {
+		t.Fatal("restored account lost its deletion")
+	}
+	if err := deleted.RestoreSnapshot([]byte(`{"accounts":[{"currency_code":"XXX"}]}`)); err == nil {
+		t.Fatal("restored a snapshot with an unknown currency")
+	}
+}
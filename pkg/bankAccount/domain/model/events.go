@@ -0,0 +1,96 @@
+package model
+
+import (
+	"github.com/MaksimDzhangirov/PracticalDDD/events"
+	"github.com/google/uuid"
+)
+
+const AggregateCustomerAccount = "customer-account"
+
+// События агрегата CustomerAccount
+
+type CustomerAccountCreated struct {
+	AccountID uuid.UUID `json:"account_id"`
+}
+
+func (e CustomerAccountCreated) Name() string {
+	return "event.customer-account.created"
+}
+
+func (e CustomerAccountCreated) AggregateType() string {
+	return AggregateCustomerAccount
+}
+
+func (e CustomerAccountCreated) AggregateID() uuid.UUID {
+	return e.AccountID
+}
+
+type BankAccountOpened struct {
+	AccountID     uuid.UUID `json:"account_id"`
+	BankAccountID uuid.UUID `json:"bank_account_id"`
+	IBAN          string    `json:"iban"`
+	CurrencyID    uuid.UUID `json:"currency_id"`
+	CurrencyCode  string    `json:"currency_code"`
+}
+
+func (e BankAccountOpened) Name() string {
+	return "event.customer-account.bank-account-opened"
+}
+
+func (e BankAccountOpened) AggregateType() string {
+	return AggregateCustomerAccount
+}
+
+func (e BankAccountOpened) AggregateID() uuid.UUID {
+	return e.AccountID
+}
+
+type MoneyDeposited struct {
+	AccountID uuid.UUID `json:"account_id"`
+	IBAN      string    `json:"iban"`
+	Amount    int       `json:"amount"`
+}
+
+func (e MoneyDeposited) Name() string {
+	return "event.customer-account.money-deposited"
+}
+
+func (e MoneyDeposited) AggregateType() string {
+	return AggregateCustomerAccount
+}
+
+func (e MoneyDeposited) AggregateID() uuid.UUID {
+	return e.AccountID
+}
+
+type CustomerAccountDeleted struct {
+	AccountID uuid.UUID `json:"account_id"`
+}
+
+func (e CustomerAccountDeleted) Name() string {
+	return "event.customer-account.deleted"
+}
+
+func (e CustomerAccountDeleted) AggregateType() string {
+	return AggregateCustomerAccount
+}
+
+func (e CustomerAccountDeleted) AggregateID() uuid.UUID {
+	return e.AccountID
+}
+
+// RegisterEvents добавляет события CustomerAccount в registry
+func RegisterEvents(registry *events.Registry) error {
+	for _, register := range []func(*events.Registry) error{
+		events.RegisterEvent[CustomerAccountCreated],
+		events.RegisterEvent[BankAccountOpened],
+		events.RegisterEvent[MoneyDeposited],
+		events.RegisterEvent[CustomerAccountDeleted],
+	} {
+		if err := register(registry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package projection
+
+import (
+	"context"
+	"sync"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/events"
+	"github.com/MaksimDzhangirov/PracticalDDD/eventsourcing"
+	"github.com/MaksimDzhangirov/PracticalDDD/pkg/bankAccount/domain/model"
+)
+
+// инфраструктурный уровень
+
+// Balance - текущий остаток банковского счёта
+type Balance struct {
+	IBAN         string
+	AccountID    string
+	CurrencyCode string
+	Amount       int
+	// Closed - счёт клиента удалён
+	Closed bool
+}
+
+// Balances - модель чтения "остаток по IBAN" из событий CustomerAccount.
+// Модель хранится в памяти, поэтому строится заново при каждом запуске
+type Balances struct {
+	mutex    sync.RWMutex
+	balances map[string]Balance
+	// versions - последняя применённая версия каждого счёта клиента;
+	// повторно доставленное событие не зачисляет деньги второй раз
+	versions map[string]int
+}
+
+func NewBalances() *Balances {
+	return &Balances{
+		balances: map[string]Balance{},
+		versions: map[string]int{},
+	}
+}
+
+// Projection возвращает проекцию для eventsourcing.ProjectionRunner
+func (b *Balances) Projection() *eventsourcing.Projection {
+	projection := eventsourcing.NewVolatileProjection("bank-account.balances", b.Reset)
+	eventsourcing.When(projection, b.onBankAccountOpened)
+	eventsourcing.When(projection, b.onMoneyDeposited)
+	eventsourcing.When(projection, b.onCustomerAccountDeleted)
+	return projection
+}
+
+func (b *Balances) Get(iban string) (Balance, bool) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	balance, ok := b.balances[iban]
+	return balance, ok
+}
+
+func (b *Balances) Reset(ctx context.Context) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.balances = map[string]Balance{}
+	b.versions = map[string]int{}
+	return nil
+}
+
+// applied отмечает событие счёта accountID применённым и возвращает false,
+// если оно уже было применено. Вызывается под mutex
+func (b *Balances) applied(ctx context.Context, accountID string) bool {
+	envelope, ok := events.EnvelopeFromContext(ctx)
+	if !ok || envelope.AggregateVersion == 0 {
+		return true
+	}
+	if envelope.AggregateVersion <= b.versions[accountID] {
+		return false
+	}
+	b.versions[accountID] = envelope.AggregateVersion
+	return true
+}
+
+func (b *Balances) onBankAccountOpened(ctx context.Context, event model.BankAccountOpened) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if !b.applied(ctx, event.AccountID.String()) {
+		return nil
+	}
+	b.balances[event.IBAN] = Balance{
+		IBAN:         event.IBAN,
+		AccountID:    event.AccountID.String(),
+		CurrencyCode: event.CurrencyCode,
+	}
+	return nil
+}
+
+func (b *Balances) onMoneyDeposited(ctx context.Context, event model.MoneyDeposited) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if !b.applied(ctx, event.AccountID.String()) {
+		return nil
+	}
+	balance := b.balances[event.IBAN]
+	balance.Amount += event.Amount
+	b.balances[event.IBAN] = balance
+	return nil
+}
+
+func (b *Balances) onCustomerAccountDeleted(ctx context.Context, event model.CustomerAccountDeleted) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if !b.applied(ctx, event.AccountID.String()) {
+		return nil
+	}
+	for iban, balance := range b.balances {
+		if balance.AccountID == event.AccountID.String() {
+			balance.Closed = true
+			b.balances[iban] = balance
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package projection_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/clock"
+	"github.com/MaksimDzhangirov/PracticalDDD/events"
+	"github.com/MaksimDzhangirov/PracticalDDD/eventsourcing"
+	"github.com/MaksimDzhangirov/PracticalDDD/pkg/bankAccount/domain/model"
+	"github.com/MaksimDzhangirov/PracticalDDD/pkg/bankAccount/infrastructure/projection"
+	"github.com/google/uuid"
+)
+
+const iban = "DE89370400440532013000"
+
+func TestBalances(t *testing.T) {
+	ctx := context.Background()
+	store := eventsourcing.NewMemoryStore()
+	repository := eventsourcing.NewRepository(store, model.NewCustomerAccount, clock.System)
+	view := projection.NewBalances()
+	runner := eventsourcing.NewProjectionRunner(store, eventsourcing.NewMemoryCheckpoints())
+	if err := runner.Register(view.Projection()); err != nil {
+		t.Fatal(err)
+	}
+
+	eur, err := model.NewCurrency(uuid.New(), "EUR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	account, err := model.OpenCustomerAccount(uuid.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := account.CreateAccountForCurrency(iban, eur); err != nil {
+		t.Fatal(err)
+	}
+	for _, amount := range []int{500, 250} {
+		if err := account.AddMoney(amount, eur); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repository.Save(ctx, account); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runner.CatchUp(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	balance, ok := view.Get(iban)
+	if !ok || balance.Amount != 750 || balance.CurrencyCode != "EUR" || balance.AccountID != account.ID().String() || balance.Closed {
+		t.Fatalf("Get(%s) = %+v, %t", iban, balance, ok)
+	}
+
+	// повторно доставленное пополнение не зачисляется второй раз
+	recorded, err := store.Load(ctx, eventsourcing.StreamID(model.AggregateCustomerAccount, account.ID()), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deposit := recorded[0].Envelope
+	if err := view.Projection().Handle(events.ContextWithEnvelope(ctx, deposit), deposit.Event); err != nil {
+		t.Fatal(err)
+	}
+	if balance, _ := view.Get(iban); balance.Amount != 750 {
+		t.Fatalf("redelivered deposit changed the balance to %d", balance.Amount)
+	}
+
+	if err := account.AddMoney(-750, eur); err != nil {
+		t.Fatal(err)
+	}
+	if err := account.MarkAsDeleted(); err != nil {
+		t.Fatal(err)
+	}
+	if err := repository.Save(ctx, account); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runner.CatchUp(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if balance, _ := view.Get(iban); balance.Amount != 0 || !balance.Closed {
+		t.Fatalf("Get(%s) = %+v after deletion", iban, balance)
+	}
+
+	if err := runner.Rebuild(ctx, "bank-account.balances"); err != nil {
+		t.Fatal(err)
+	}
+	if balance, _ := view.Get(iban); balance.Amount != 0 || !balance.Closed {
+		t.Fatalf("Get(%s) = %+v after Rebuild", iban, balance)
+	}
+	if _, ok := view.Get("unknown"); ok {
+		t.Fatal("Get returned a balance for an unknown IBAN")
+	}
+}
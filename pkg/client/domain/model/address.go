@@ -1,8 +1,38 @@
 package model
 
+import (
+	"strconv"
+	"strings"
+
+	value_objects "github.com/MaksimDzhangirov/PracticalDDD/value-objects"
+)
+
 type Address struct {
 	Street   string
 	Number   string
 	Postcode string
 	City     string
+	Country  string
+}
+
+// NewAddress проверяет адрес по тем же правилам, что и value_objects.NewAddress
+func NewAddress(country, street, number, postcode, city string) (Address, error) {
+	address, err := value_objects.NewAddress(country, street, number, postcode)
+	if err != nil {
+		return Address{}, err
+	}
+
+	city = strings.Join(strings.Fields(city), " ")
+	if city == "" {
+		return Address{}, &value_objects.ValidationError{Field: "city", Err: value_objects.ErrRequired}
+	}
+
+	// храним номер в нормализованном виде: " 12 a" -> "12A"
+	return Address{
+		Street:   address.Street,
+		Number:   strconv.Itoa(address.Number) + address.Suffix,
+		Postcode: address.Postcode,
+		City:     city,
+		Country:  address.Country,
+	}, nil
 }
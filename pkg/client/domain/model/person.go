@@ -2,10 +2,23 @@ package model
 
 import (
 	"time"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/clock"
+	value_objects "github.com/MaksimDzhangirov/PracticalDDD/value-objects"
 )
 
 type Birthday time.Time
 
+// NewBirthday отклоняет даты в будущем и неправдоподобно старые даты
+func NewBirthday(date time.Time, clock clock.Clock) (Birthday, error) {
+	birthday, err := value_objects.NewBirthday(date, clock)
+	if err != nil {
+		return Birthday{}, err
+	}
+
+	return Birthday(birthday), nil
+}
+
 type Person struct {
 	SSN       string
 	FirstName string
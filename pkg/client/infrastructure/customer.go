@@ -3,6 +3,7 @@ package infrastructure
 import (
 	"context"
 	"errors"
+	gorm_generics "github.com/MaksimDzhangirov/PracticalDDD/gorm-generics"
 	"github.com/MaksimDzhangirov/PracticalDDD/pkg/client/domain/model"
 	"github.com/MaksimDzhangirov/PracticalDDD/pkg/client/infrastructure/dto"
 	"github.com/google/uuid"
@@ -47,55 +48,39 @@ func (r *CustomerRepository) SaveCustomer(ctx context.Context, customer model.Cu
 }
 
 func (r *CustomerRepository) CreateCustomer(ctx context.Context, customer model.Customer) (*model.Customer, error) {
-	tx := r.connection.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
+	var row dto.CustomerGorm
+	err := gorm_generics.NewUnitOfWork(r.connection).Do(ctx, func(ctx context.Context) error {
+		tx := gorm_generics.DB(ctx, r.connection)
+
+		//
+		// какой-то код
+		//
+
+		var total int64
+		var err error
+		if customer.Person != nil {
+			err = tx.Model(dto.PersonGorm{}).Where("ssn = ?", customer.Person.SSN).Count(&total).Error
+		} else if customer.Company != nil {
+			err = tx.Model(dto.CompanyGorm{}).Where("registration_number = ?", customer.Company.RegistrationNumber).Count(&total).Error
+		}
+		if err != nil {
+			return err
+		} else if total > 0 {
+			return errors.New("there is already such record in DB")
 		}
-	}()
-
-	if err := tx.Error; err != nil {
-		return nil, err
-	}
-
-	//
-	// какой-то код
-	//
-
-	var total int64
-	var err error
-	if customer.Person != nil {
-		err = tx.Model(dto.PersonGorm{}).Where("ssn = ?", customer.Person.SSN).Count(&total).Error
-	} else if customer.Person != nil {
-		err = tx.Model(dto.CompanyGorm{}).Where("registration_number = ?", customer.Company.RegistrationNumber).Count(&total).Error
-	}
-	if err != nil {
-		tx.Rollback()
-		return nil, err
-	} else if total > 0 {
-		tx.Rollback()
-		return nil, errors.New("there is already such record in DB")
-	}
-
-	//
-	// какой-то код
-	//
-	row := NewRow(customer)
-	err = tx.Save(&row).Error
-	if err != nil {
-		tx.Rollback()
-		return nil, err
-	}
 
-	err = tx.Commit().Error
+		//
+		// какой-то код
+		//
+		row = NewRow(customer)
+		return tx.Save(&row).Error
+	})
 	if err != nil {
-		tx.Rollback()
 		return nil, err
 	}
 
 	customer, err = row.ToEntity()
 	if err != nil {
-		tx.Rollback()
 		return nil, err
 	}
 
@@ -134,5 +119,6 @@ func NewRow(customer model.Customer) dto.CustomerGorm {
 		Number:   customer.Address.Number,
 		Postcode: customer.Address.Postcode,
 		City:     customer.Address.City,
+		Country:  customer.Address.Country,
 	}
 }
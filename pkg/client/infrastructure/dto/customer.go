@@ -16,6 +16,7 @@ type CustomerGorm struct {
 	Number    string       `gorm:"column:number"`
 	Postcode  string       `gorm:"column:postcode"`
 	City      string       `gorm:"column:city"`
+	Country   string       `gorm:"column:country"`
 }
 
 func (c CustomerGorm) ToEntity() (model.Customer, error) {
@@ -33,6 +34,7 @@ func (c CustomerGorm) ToEntity() (model.Customer, error) {
 			Number:   c.Number,
 			Postcode: c.Postcode,
 			City:     c.City,
+			Country:  c.Country,
 		},
 	}, nil
 }
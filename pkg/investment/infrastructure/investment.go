@@ -48,10 +48,22 @@ func (f *CryptoInvestmentDBFactory) ToEntity(dto CryptoInvestmentGorm) (model.Cr
 		return model.CryptoInvestment{}, err
 	}
 
+	// сумма хранится в основных единицах, масштаб берём из справочника валют
+	currency, err := value_objects.CurrencyByCode(dto.Currency.Code)
+	if err != nil {
+		return model.CryptoInvestment{}, err
+	}
+	currency.ID = currencyId
+
+	invested, err := value_objects.NewMoneyFromMajor(int64(dto.InvestedAmount), currency)
+	if err != nil {
+		return model.CryptoInvestment{}, err
+	}
+
 	return model.CryptoInvestment{
 		ID:               id,
 		CryptoCurrencyID: cryptoId,
-		InvestedMoney:    value_objects.NewMoney(dto.InvestedAmount, currencyId),
+		InvestedMoney:    invested,
 		BankAccountID:    accountId,
 	}, nil
 }
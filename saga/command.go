@@ -0,0 +1,115 @@
+package saga
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+var (
+	ErrUnknownCommand   = errors.New("no handler for command")
+	ErrDuplicateCommand = errors.New("command handler is already registered")
+	ErrCommandInterface = errors.New("command handler needs a concrete command type")
+)
+
+// Command - просьба выполнить действие, в отличие от события, которое
+// сообщает о том, что уже произошло
+type Command interface {
+	Name() string
+}
+
+// CommandDispatcher отправляет команды процессов. Manager хранит команды
+// в JSON, пока они не отправлены, поэтому диспетчер также восстанавливает
+// команду по имени
+type CommandDispatcher interface {
+	Dispatch(ctx context.Context, command Command) error
+	DecodeCommand(name string, payload []byte) (Command, error)
+}
+
+// CommandBus передаёт команду единственному обработчику её типа
+type CommandBus struct {
+	mutex    sync.RWMutex
+	handlers map[string]func(ctx context.Context, command Command) error
+	decoders map[string]func(payload []byte) (Command, error)
+}
+
+func NewCommandBus() *CommandBus {
+	return &CommandBus{
+		handlers: map[string]func(ctx context.Context, command Command) error{},
+		decoders: map[string]func(payload []byte) (Command, error){},
+	}
+}
+
+// HandleCommand регистрирует обработчик команд типа T; T должен быть
+// конкретным типом, у интерфейса нет имени команды
+func HandleCommand[T Command](bus *CommandBus, handler func(ctx context.Context, command T) error) error {
+	name, err := commandName[T]()
+	if err != nil {
+		return err
+	}
+
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+
+	if _, ok := bus.handlers[name]; ok {
+		return fmt.Errorf("%w: %s", ErrDuplicateCommand, name)
+	}
+	bus.handlers[name] = func(ctx context.Context, command Command) error {
+		actualCommand, ok := command.(T)
+		if !ok {
+			return fmt.Errorf("%w: %T", ErrUnknownCommand, command)
+		}
+		return handler(ctx, actualCommand)
+	}
+	bus.decoders[name] = func(payload []byte) (Command, error) {
+		var command T
+		if err := json.Unmarshal(payload, &command); err != nil {
+			return nil, err
+		}
+		return command, nil
+	}
+
+	return nil
+}
+
+func (b *CommandBus) Dispatch(ctx context.Context, command Command) error {
+	b.mutex.RLock()
+	handler, ok := b.handlers[command.Name()]
+	b.mutex.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownCommand, command.Name())
+	}
+
+	return handler(ctx, command)
+}
+
+// DecodeCommand восстанавливает команду типа, зарегистрированного через HandleCommand
+func (b *CommandBus) DecodeCommand(name string, payload []byte) (Command, error) {
+	b.mutex.RLock()
+	decode, ok := b.decoders[name]
+	b.mutex.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCommand, name)
+	}
+
+	return decode(payload)
+}
+
+// commandName создаёт пустую команду типа T, чтобы узнать её имя;
+// для указателей создаётся значение, на которое они указывают
+func commandName[T Command]() (string, error) {
+	commandType := reflect.TypeOf((*T)(nil)).Elem()
+	switch commandType.Kind() {
+	case reflect.Interface:
+		return "", fmt.Errorf("%w: %s", ErrCommandInterface, commandType)
+	case reflect.Ptr:
+		return reflect.New(commandType.Elem()).Interface().(Command).Name(), nil
+	default:
+		return reflect.Zero(commandType).Interface().(Command).Name(), nil
+	}
+}
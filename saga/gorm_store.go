@@ -0,0 +1,247 @@
+package saga
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	gorm_generics "github.com/MaksimDzhangirov/PracticalDDD/gorm-generics"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// инфраструктурный уровень
+
+// StoredInstance - строка таблицы saga_instances
+type StoredInstance struct {
+	Saga      string `gorm:"primaryKey"`
+	ID        string `gorm:"primaryKey;size:36"`
+	State     []byte
+	Version   int
+	Completed bool
+	// Commands - неотправленные команды в JSON или пустая строка
+	Commands  string
+	UpdatedAt time.Time
+}
+
+func (StoredInstance) TableName() string {
+	return "saga_instances"
+}
+
+// StoredTimeout - строка таблицы saga_timeouts
+type StoredTimeout struct {
+	ID         string    `gorm:"primaryKey;size:36"`
+	Saga       string    `gorm:"uniqueIndex:idx_saga_timeouts_instance_name"`
+	InstanceID string    `gorm:"size:36;uniqueIndex:idx_saga_timeouts_instance_name"`
+	Name       string    `gorm:"uniqueIndex:idx_saga_timeouts_instance_name"`
+	DueAt      time.Time `gorm:"index"`
+}
+
+func (StoredTimeout) TableName() string {
+	return "saga_timeouts"
+}
+
+// Migrate создаёт таблицы saga_instances и saga_timeouts
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(&StoredInstance{}, &StoredTimeout{})
+}
+
+// GormStore пишет в транзакцию из ctx (см. gorm_generics.UnitOfWork), поэтому
+// состояние процесса сохраняется вместе с командами, отправленными через outbox
+type GormStore struct {
+	db *gorm.DB
+}
+
+func NewGormStore(db *gorm.DB) *GormStore {
+	return &GormStore{
+		db: db,
+	}
+}
+
+// Transaction используется Manager, если не задан WithTransaction
+func (s *GormStore) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return gorm_generics.NewUnitOfWork(s.db).Do(ctx, fn)
+}
+
+func (s *GormStore) Load(ctx context.Context, saga string, id uuid.UUID) (Instance, error) {
+	var row StoredInstance
+	err := gorm_generics.DB(ctx, s.db).Where("saga = ? AND id = ?", saga, id.String()).Take(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Instance{}, ErrInstanceNotFound
+	}
+	if err != nil {
+		return Instance{}, err
+	}
+
+	return row.instance()
+}
+
+func (row StoredInstance) instance() (Instance, error) {
+	id, err := uuid.Parse(row.ID)
+	if err != nil {
+		return Instance{}, err
+	}
+	var commands []PendingCommand
+	if row.Commands != "" {
+		if err := json.Unmarshal([]byte(row.Commands), &commands); err != nil {
+			return Instance{}, err
+		}
+	}
+
+	return Instance{
+		Saga:      row.Saga,
+		ID:        id,
+		State:     row.State,
+		Version:   row.Version,
+		Completed: row.Completed,
+		Commands:  commands,
+		UpdatedAt: row.UpdatedAt,
+	}, nil
+}
+
+func (s *GormStore) Save(ctx context.Context, instance Instance, expectedVersion int) error {
+	row := StoredInstance{
+		Saga:      instance.Saga,
+		ID:        instance.ID.String(),
+		State:     instance.State,
+		Version:   instance.Version,
+		Completed: instance.Completed,
+		UpdatedAt: instance.UpdatedAt,
+	}
+	if len(instance.Commands) > 0 {
+		commands, err := json.Marshal(instance.Commands)
+		if err != nil {
+			return err
+		}
+		row.Commands = string(commands)
+	}
+
+	if expectedVersion == 0 {
+		if err := gorm_generics.DB(ctx, s.db).Create(&row).Error; err != nil {
+			// экземпляр уже создан обработчиком другого события; проверяем вне
+			// транзакции, так как после ошибки она может быть прервана
+			var count int64
+			countErr := s.db.WithContext(ctx).Model(&StoredInstance{}).
+				Where("saga = ? AND id = ?", row.Saga, row.ID).
+				Count(&count).Error
+			if countErr == nil && count > 0 {
+				return ErrConcurrencyConflict
+			}
+			return err
+		}
+		return nil
+	}
+
+	result := gorm_generics.DB(ctx, s.db).Model(&StoredInstance{}).
+		Where("saga = ? AND id = ? AND version = ?", row.Saga, row.ID, expectedVersion).
+		Updates(map[string]interface{}{
+			"state":      row.State,
+			"version":    row.Version,
+			"completed":  row.Completed,
+			"commands":   row.Commands,
+			"updated_at": row.UpdatedAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrConcurrencyConflict
+	}
+
+	return nil
+}
+
+func (s *GormStore) Schedule(ctx context.Context, timeout Timeout) error {
+	return gorm_generics.NewUnitOfWork(s.db).Do(ctx, func(ctx context.Context) error {
+		if err := s.CancelTimeouts(ctx, timeout.Saga, timeout.InstanceID, timeout.Name); err != nil {
+			return err
+		}
+
+		return gorm_generics.DB(ctx, s.db).Create(&StoredTimeout{
+			ID:         timeout.ID.String(),
+			Saga:       timeout.Saga,
+			InstanceID: timeout.InstanceID.String(),
+			Name:       timeout.Name,
+			DueAt:      timeout.DueAt,
+		}).Error
+	})
+}
+
+func (s *GormStore) CancelTimeouts(ctx context.Context, saga string, id uuid.UUID, name string) error {
+	query := gorm_generics.DB(ctx, s.db).Where("saga = ? AND instance_id = ?", saga, id.String())
+	if name != "" {
+		query = query.Where("name = ?", name)
+	}
+
+	return query.Delete(&StoredTimeout{}).Error
+}
+
+func (s *GormStore) DueTimeouts(ctx context.Context, saga string, now time.Time, limit int) ([]Timeout, error) {
+	query := gorm_generics.DB(ctx, s.db).Where("saga = ? AND due_at <= ?", saga, now).Order("due_at")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var rows []StoredTimeout
+	if err := query.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]Timeout, 0, len(rows))
+	for _, row := range rows {
+		id, err := uuid.Parse(row.ID)
+		if err != nil {
+			return nil, err
+		}
+		instanceID, err := uuid.Parse(row.InstanceID)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, Timeout{
+			ID:         id,
+			Saga:       row.Saga,
+			InstanceID: instanceID,
+			Name:       row.Name,
+			DueAt:      row.DueAt,
+		})
+	}
+
+	return result, nil
+}
+
+func (s *GormStore) DeleteTimeout(ctx context.Context, id uuid.UUID) error {
+	result := gorm_generics.DB(ctx, s.db).Where("id = ?", id.String()).Delete(&StoredTimeout{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTimeoutNotFound
+	}
+
+	return nil
+}
+
+func (s *GormStore) Pending(ctx context.Context, saga string, limit int) ([]Instance, error) {
+	query := gorm_generics.DB(ctx, s.db).Where("saga = ? AND commands <> ''", saga).Order("updated_at")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var rows []StoredInstance
+	if err := query.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]Instance, 0, len(rows))
+	for _, row := range rows {
+		instance, err := row.instance()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, instance)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,337 @@
+package saga
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/clock"
+	"github.com/MaksimDzhangirov/PracticalDDD/events"
+	"github.com/google/uuid"
+)
+
+type ManagerOption func(*managerOptions)
+
+type managerOptions struct {
+	transaction func(ctx context.Context, fn func(ctx context.Context) error) error
+	interval    time.Duration
+	batchSize   int
+}
+
+// WithTransaction сохраняет состояние, таймауты и команды процесса атомарно,
+// например gorm_generics.NewUnitOfWork(db).Do. Без этой опции Manager
+// использует транзакции хранилища, если оно их поддерживает (GormStore)
+func WithTransaction(transaction func(ctx context.Context, fn func(ctx context.Context) error) error) ManagerOption {
+	return func(o *managerOptions) {
+		o.transaction = transaction
+	}
+}
+
+// WithTimeoutInterval задаёт, как часто Run проверяет наступившие таймауты
+// и неотправленные команды
+func WithTimeoutInterval(interval time.Duration) ManagerOption {
+	return func(o *managerOptions) {
+		o.interval = interval
+	}
+}
+
+// Manager запускает процесс saga: получает события как events.Handler
+// и вызывает наступившие таймауты. При ErrConcurrencyConflict событие нужно
+// обработать повторно, например через events.WithRetry
+type Manager[S any] struct {
+	saga     *Saga[S]
+	store    StateStore
+	commands CommandDispatcher
+	clock    clock.Clock
+	options  managerOptions
+}
+
+// transactional реализуют хранилища, которые сами открывают транзакцию
+type transactional interface {
+	Transaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+func NewManager[S any](saga *Saga[S], store StateStore, commands CommandDispatcher, clock clock.Clock, options ...ManagerOption) *Manager[S] {
+	m := &Manager[S]{
+		saga:     saga,
+		store:    store,
+		commands: commands,
+		clock:    clock,
+		options: managerOptions{
+			interval:  time.Second,
+			batchSize: 100,
+		},
+	}
+	for _, option := range options {
+		option(&m.options)
+	}
+	if m.options.transaction == nil {
+		if store, ok := store.(transactional); ok {
+			m.options.transaction = store.Transaction
+		}
+	}
+
+	return m
+}
+
+// transaction выполняет fn в транзакции, если она настроена, и сообщает,
+// откатятся ли изменения хранилища при ошибке fn
+func (m *Manager[S]) transaction(ctx context.Context, fn func(ctx context.Context) error) (bool, error) {
+	if m.options.transaction == nil {
+		return false, fn(ctx)
+	}
+	return true, m.options.transaction(ctx, fn)
+}
+
+// Handle передаёт событие экземпляру процесса с ключом события. Команды
+// отправляются после сохранения состояния; если отправка не удалась, Handle
+// не возвращает ошибку, чтобы событие не было обработано повторно, а команды
+// остаются в экземпляре до DispatchPending
+func (m *Manager[S]) Handle(ctx context.Context, event events.Event) error {
+	handler, ok := m.saga.handlerFor(event)
+	if !ok {
+		return nil
+	}
+	id, ok := m.saga.correlate(event)
+	if !ok {
+		return nil
+	}
+
+	var instance Instance
+	_, err := m.transaction(ctx, func(ctx context.Context) error {
+		loaded, err := m.store.Load(ctx, m.saga.name, id)
+		if errors.Is(err, ErrInstanceNotFound) {
+			if !handler.starts {
+				return nil
+			}
+			loaded = Instance{Saga: m.saga.name, ID: id}
+		} else if err != nil {
+			return err
+		}
+
+		instance, err = m.step(ctx, loaded, func(ctx context.Context, c *Context[S]) error {
+			return handler.handle(ctx, c, event)
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := m.dispatch(ctx, instance); err != nil {
+		log.Print(err)
+	}
+	return nil
+}
+
+// step выполняет fn над экземпляром и сохраняет результат вместе с
+// командами fn. Завершённый экземпляр возвращается без изменений: его
+// неотправленные команды всё равно отправит dispatch
+func (m *Manager[S]) step(ctx context.Context, instance Instance, fn func(ctx context.Context, c *Context[S]) error) (Instance, error) {
+	if instance.Completed {
+		return instance, nil
+	}
+
+	state := new(S)
+	if len(instance.State) > 0 {
+		if err := json.Unmarshal(instance.State, state); err != nil {
+			return Instance{}, fmt.Errorf("saga %s %s: %w", m.saga.name, instance.ID, err)
+		}
+	}
+
+	c := &Context[S]{
+		ID:    instance.ID,
+		State: state,
+		now:   m.clock.Now(),
+	}
+	if err := fn(ctx, c); err != nil {
+		return Instance{}, err
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return Instance{}, err
+	}
+	for _, command := range c.commands {
+		payload, err := json.Marshal(command)
+		if err != nil {
+			return Instance{}, fmt.Errorf("saga %s %s: %s: %w", m.saga.name, instance.ID, command.Name(), err)
+		}
+		instance.Commands = append(instance.Commands, PendingCommand{Name: command.Name(), Payload: payload})
+	}
+
+	expectedVersion := instance.Version
+	instance.State = data
+	instance.Version++
+	instance.Completed = c.completed
+	instance.UpdatedAt = c.now
+	if err := m.store.Save(ctx, instance, expectedVersion); err != nil {
+		return Instance{}, err
+	}
+
+	return instance, m.applyTimeouts(ctx, c)
+}
+
+// dispatch отправляет команды экземпляра по порядку и после каждой
+// сохраняет экземпляр без неё. Если сохранение не удалось, команда будет
+// отправлена ещё раз, поэтому обработчики команд должны быть идемпотентны
+func (m *Manager[S]) dispatch(ctx context.Context, instance Instance) error {
+	for len(instance.Commands) > 0 {
+		pending := instance.Commands[0]
+		command, err := m.commands.DecodeCommand(pending.Name, pending.Payload)
+		if err == nil {
+			err = m.commands.Dispatch(ctx, command)
+		}
+		if err != nil {
+			return fmt.Errorf("saga %s %s: %s: %w", m.saga.name, instance.ID, pending.Name, err)
+		}
+
+		expectedVersion := instance.Version
+		instance.Commands = instance.Commands[1:]
+		instance.Version++
+		instance.UpdatedAt = m.clock.Now()
+		if err := m.store.Save(ctx, instance, expectedVersion); err != nil {
+			return fmt.Errorf("saga %s %s: %w", m.saga.name, instance.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// DispatchPending повторяет отправку команд, которые не удалось отправить
+// сразу после сохранения состояния, и возвращает количество экземпляров,
+// все команды которых отправлены
+func (m *Manager[S]) DispatchPending(ctx context.Context) (int, error) {
+	pending, err := m.store.Pending(ctx, m.saga.name, m.options.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	dispatched := 0
+	var errs []error
+	for _, instance := range pending {
+		if err := m.dispatch(ctx, instance); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		dispatched++
+	}
+
+	return dispatched, errors.Join(errs...)
+}
+
+func (m *Manager[S]) applyTimeouts(ctx context.Context, c *Context[S]) error {
+	if c.completed {
+		return m.store.CancelTimeouts(ctx, m.saga.name, c.ID, "")
+	}
+
+	for _, name := range c.cancelled {
+		if err := m.store.CancelTimeouts(ctx, m.saga.name, c.ID, name); err != nil {
+			return err
+		}
+	}
+	for _, scheduled := range c.scheduled {
+		err := m.store.Schedule(ctx, Timeout{
+			ID:         uuid.New(),
+			Saga:       m.saga.name,
+			InstanceID: c.ID,
+			Name:       scheduled.name,
+			DueAt:      scheduled.dueAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FireTimeouts вызывает обработчики наступивших таймаутов этого процесса
+// и возвращает их количество. Ошибки отправки команд сработавших таймаутов
+// возвращаются, но таймаут не повторяется: команды отправит DispatchPending
+func (m *Manager[S]) FireTimeouts(ctx context.Context) (int, error) {
+	due, err := m.store.DueTimeouts(ctx, m.saga.name, m.clock.Now(), m.options.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	fired := 0
+	var errs []error
+	for _, timeout := range due {
+		var instance Instance
+		rollback, err := m.transaction(ctx, func(ctx context.Context) error {
+			var err error
+			instance, err = m.fire(ctx, timeout)
+			return err
+		})
+		if errors.Is(err, ErrTimeoutNotFound) {
+			continue
+		}
+		if err != nil && !rollback {
+			// удаление таймаута не откатилось: планируем его снова
+			if restoreErr := m.store.Schedule(ctx, timeout); restoreErr != nil {
+				err = errors.Join(err, restoreErr)
+			}
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		fired++
+
+		if err := m.dispatch(ctx, instance); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return fired, errors.Join(errs...)
+}
+
+// fire захватывает таймаут, удаляя его: если другой Manager удалил таймаут
+// раньше, обработчик не вызывается. В транзакции удаление откатывается
+// вместе с неудачным шагом процесса
+func (m *Manager[S]) fire(ctx context.Context, timeout Timeout) (Instance, error) {
+	if err := m.store.DeleteTimeout(ctx, timeout.ID); err != nil {
+		return Instance{}, err
+	}
+
+	handler, ok := m.saga.timeouts[timeout.Name]
+	if !ok {
+		return Instance{}, nil
+	}
+
+	instance, err := m.store.Load(ctx, m.saga.name, timeout.InstanceID)
+	if errors.Is(err, ErrInstanceNotFound) {
+		return Instance{}, nil
+	}
+	if err != nil {
+		return Instance{}, err
+	}
+
+	return m.step(ctx, instance, handler)
+}
+
+// Run вызывает FireTimeouts и DispatchPending каждые interval, пока не
+// будет отменён ctx
+func (m *Manager[S]) Run(ctx context.Context) error {
+	ticker := time.NewTicker(m.options.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := m.FireTimeouts(ctx); err != nil && ctx.Err() == nil {
+			log.Print(err)
+		}
+		if _, err := m.DispatchPending(ctx); err != nil && ctx.Err() == nil {
+			log.Print(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
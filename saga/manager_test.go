@@ -0,0 +1,368 @@
+package saga_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/clock"
+	"github.com/MaksimDzhangirov/PracticalDDD/events"
+	"github.com/MaksimDzhangirov/PracticalDDD/saga"
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type counterState struct {
+	Events int `json:"events"`
+}
+
+type noop struct{}
+
+func (noop) Name() string {
+	return "command.noop"
+}
+
+// dispatcher записывает команды или возвращает err
+type dispatcher struct {
+	commands []saga.Command
+	err      error
+}
+
+func (d *dispatcher) Dispatch(ctx context.Context, command saga.Command) error {
+	if d.err != nil {
+		return d.err
+	}
+	d.commands = append(d.commands, command)
+	return nil
+}
+
+func (d *dispatcher) DecodeCommand(name string, payload []byte) (saga.Command, error) {
+	if name != (noop{}).Name() {
+		return nil, saga.ErrUnknownCommand
+	}
+	return noop{}, nil
+}
+
+func TestManagerRejectsConcurrentSave(t *testing.T) {
+	store := saga.NewMemoryStore()
+	now := clock.NewManual(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	counter := saga.NewSaga[counterState]("counter")
+
+	var manager *saga.Manager[counterState]
+	nested := false
+	saga.StartOn(counter, func(ctx context.Context, c *saga.Context[counterState], event events.OrderCreated) error {
+		c.State.Events++
+		if !nested {
+			// другой обработчик сохраняет тот же экземпляр раньше
+			nested = true
+			return manager.Handle(ctx, event)
+		}
+		return nil
+	})
+	manager = saga.NewManager(counter, store, &dispatcher{}, now)
+
+	orderID := uuid.New()
+	err := manager.Handle(context.Background(), events.NewOrderCreated(orderID))
+	if !errors.Is(err, saga.ErrConcurrencyConflict) {
+		t.Fatalf("Handle returned %v, want ErrConcurrencyConflict", err)
+	}
+
+	instance, err := store.Load(context.Background(), "counter", orderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if instance.Version != 1 {
+		t.Fatalf("instance version %d, want 1", instance.Version)
+	}
+}
+
+// racingStore отдаёт наступившие таймауты другому Manager раньше, чем
+// их успевает захватить вызывающий
+type racingStore struct {
+	saga.StateStore
+	other func()
+}
+
+func (s *racingStore) DueTimeouts(ctx context.Context, name string, now time.Time, limit int) ([]saga.Timeout, error) {
+	due, err := s.StateStore.DueTimeouts(ctx, name, now, limit)
+	if s.other != nil {
+		s.other()
+		s.other = nil
+	}
+	return due, err
+}
+
+func scheduling(fired *int) *saga.Saga[counterState] {
+	reminder := saga.NewSaga[counterState]("reminder")
+	saga.StartOn(reminder, func(ctx context.Context, c *saga.Context[counterState], event events.OrderCreated) error {
+		c.Schedule("remind", time.Hour)
+		return nil
+	})
+	reminder.OnTimeout("remind", func(ctx context.Context, c *saga.Context[counterState]) error {
+		*fired++
+		c.Send(noop{})
+		return nil
+	})
+	return reminder
+}
+
+func TestManagerFiresClaimedTimeoutOnce(t *testing.T) {
+	store := saga.NewMemoryStore()
+	now := clock.NewManual(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	fired := 0
+	reminder := scheduling(&fired)
+
+	winner := saga.NewManager(reminder, store, &dispatcher{}, now)
+	racing := &racingStore{StateStore: store}
+	loser := saga.NewManager(reminder, racing, &dispatcher{}, now)
+	racing.other = func() {
+		if _, err := winner.FireTimeouts(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := winner.Handle(context.Background(), events.NewOrderCreated(uuid.New())); err != nil {
+		t.Fatal(err)
+	}
+	now.Advance(time.Hour)
+
+	count, err := loser.FireTimeouts(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 || fired != 1 {
+		t.Fatalf("loser fired %d, handler called %d times; want 0 and 1", count, fired)
+	}
+}
+
+// failingStore не сохраняет экземпляры, пока задан err
+type failingStore struct {
+	saga.StateStore
+	err error
+}
+
+func (s *failingStore) Save(ctx context.Context, instance saga.Instance, expectedVersion int) error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.StateStore.Save(ctx, instance, expectedVersion)
+}
+
+func TestManagerReschedulesFailedTimeoutWithoutTransaction(t *testing.T) {
+	store := &failingStore{StateStore: saga.NewMemoryStore()}
+	now := clock.NewManual(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	fired := 0
+	commands := &dispatcher{}
+	manager := saga.NewManager(scheduling(&fired), store, commands, now)
+
+	if err := manager.Handle(context.Background(), events.NewOrderCreated(uuid.New())); err != nil {
+		t.Fatal(err)
+	}
+	now.Advance(time.Hour)
+
+	store.err = errors.New("database is down")
+	if _, err := manager.FireTimeouts(context.Background()); err == nil {
+		t.Fatal("FireTimeouts returned nil for a failing save")
+	}
+
+	// MemoryStore не откатывает удаление таймаута, Manager планирует его снова
+	store.err = nil
+	count, err := manager.FireTimeouts(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 || fired != 2 || len(commands.commands) != 1 {
+		t.Fatalf("fired %d, handler called %d times, sent %d commands; want 1, 2 and 1", count, fired, len(commands.commands))
+	}
+}
+
+func TestManagerKeepsTimeoutCommandsUntilSent(t *testing.T) {
+	store := saga.NewMemoryStore()
+	now := clock.NewManual(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	fired := 0
+	commands := &dispatcher{err: errors.New("bus is down")}
+	manager := saga.NewManager(scheduling(&fired), store, commands, now)
+
+	if err := manager.Handle(context.Background(), events.NewOrderCreated(uuid.New())); err != nil {
+		t.Fatal(err)
+	}
+	now.Advance(time.Hour)
+
+	count, err := manager.FireTimeouts(context.Background())
+	if err == nil || count != 1 {
+		t.Fatalf("FireTimeouts() = %d, %v; want 1 and the command error", count, err)
+	}
+
+	// таймаут не повторяется, команда ждёт в экземпляре
+	commands.err = nil
+	if count, err := manager.FireTimeouts(context.Background()); err != nil || count != 0 {
+		t.Fatalf("second FireTimeouts() = %d, %v; want 0", count, err)
+	}
+	if count, err := manager.DispatchPending(context.Background()); err != nil || count != 1 {
+		t.Fatalf("DispatchPending() = %d, %v; want 1", count, err)
+	}
+	if fired != 1 || len(commands.commands) != 1 {
+		t.Fatalf("handler called %d times, sent %d commands; want 1 and 1", fired, len(commands.commands))
+	}
+}
+
+type releaseStock struct {
+	OrderID uuid.UUID `json:"order_id"`
+}
+
+func (releaseStock) Name() string {
+	return "command.test.release-stock"
+}
+
+type refundOrder struct {
+	OrderID uuid.UUID `json:"order_id"`
+}
+
+func (refundOrder) Name() string {
+	return "command.test.refund"
+}
+
+func stores(t *testing.T) map[string]saga.StateStore {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "saga.db") + "?_busy_timeout=5000&_txlock=immediate"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := saga.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+
+	return map[string]saga.StateStore{
+		"memory": saga.NewMemoryStore(),
+		"gorm":   saga.NewGormStore(db),
+	}
+}
+
+func TestManagerRetriesFailedCompensation(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			now := clock.NewManual(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+			compensation := saga.NewSaga[counterState]("compensation")
+			saga.StartOn(compensation, func(ctx context.Context, c *saga.Context[counterState], event events.OrderDeliveryFailed) error {
+				c.Send(releaseStock{OrderID: c.ID})
+				c.Send(refundOrder{OrderID: c.ID})
+				c.Complete()
+				return nil
+			})
+
+			var sent []saga.Command
+			refundErr := errors.New("payment provider is down")
+			bus := saga.NewCommandBus()
+			errs := []error{
+				saga.HandleCommand(bus, func(ctx context.Context, command releaseStock) error {
+					sent = append(sent, command)
+					return nil
+				}),
+				saga.HandleCommand(bus, func(ctx context.Context, command refundOrder) error {
+					if refundErr != nil {
+						return refundErr
+					}
+					sent = append(sent, command)
+					return nil
+				}),
+			}
+			for _, err := range errs {
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			manager := saga.NewManager(compensation, store, bus, now)
+
+			// состояние сохранено, поэтому событие обработано, хотя возврат денег не прошёл
+			orderID := uuid.New()
+			failed := events.NewOrderDeliveryFailed(orderID)
+			if err := manager.Handle(ctx, failed); err != nil {
+				t.Fatal(err)
+			}
+			// повторная доставка события не отправляет ReleaseStock ещё раз
+			if err := manager.Handle(ctx, failed); err != nil {
+				t.Fatal(err)
+			}
+			if count, err := manager.DispatchPending(ctx); !errors.Is(err, refundErr) || count != 0 {
+				t.Fatalf("DispatchPending() = %d, %v; want 0 and the refund error", count, err)
+			}
+			if len(sent) != 1 {
+				t.Fatalf("sent %v while the refund fails", sent)
+			}
+
+			instance, err := store.Load(ctx, "compensation", orderID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !instance.Completed || len(instance.Commands) != 1 || instance.Commands[0].Name != (refundOrder{}).Name() {
+				t.Fatalf("instance = %+v", instance)
+			}
+
+			refundErr = nil
+			if count, err := manager.DispatchPending(ctx); err != nil || count != 1 {
+				t.Fatalf("DispatchPending() = %d, %v; want 1", count, err)
+			}
+			if count, err := manager.DispatchPending(ctx); err != nil || count != 0 {
+				t.Fatalf("second DispatchPending() = %d, %v; want 0", count, err)
+			}
+			if err := manager.Handle(ctx, failed); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(sent) != 2 || sent[0] != (releaseStock{OrderID: orderID}) || sent[1] != (refundOrder{OrderID: orderID}) {
+				t.Fatalf("sent %v, want ReleaseStock and RefundOrder once", sent)
+			}
+		})
+	}
+}
+
+func TestOnHandlesEventFamiliesAndRejectsDuplicates(t *testing.T) {
+	store := saga.NewMemoryStore()
+	now := clock.NewManual(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	counter := saga.NewSaga[counterState]("counter")
+	saga.StartOn(counter, func(ctx context.Context, c *saga.Context[counterState], event events.OrderEvent) error {
+		c.State.Events++
+		return nil
+	})
+	manager := saga.NewManager(counter, store, &dispatcher{}, now)
+
+	orderID := uuid.New()
+	for _, event := range []events.Event{events.NewOrderCreated(orderID), events.NewOrderDispatched(orderID)} {
+		if err := manager.Handle(context.Background(), event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	instance, err := store.Load(context.Background(), "counter", orderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if instance.Version != 2 {
+		t.Fatalf("instance version %d, want 2", instance.Version)
+	}
+
+	defer func() {
+		err, _ := recover().(error)
+		if !errors.Is(err, saga.ErrDuplicateHandler) {
+			t.Fatalf("On panicked with %v, want ErrDuplicateHandler", err)
+		}
+	}()
+	saga.On(counter, func(ctx context.Context, c *saga.Context[counterState], event events.OrderEvent) error {
+		return nil
+	})
+}
+
+func TestHandleCommandRejectsInterfaces(t *testing.T) {
+	err := saga.HandleCommand(saga.NewCommandBus(), func(ctx context.Context, command saga.Command) error {
+		return nil
+	})
+	if !errors.Is(err, saga.ErrCommandInterface) {
+		t.Fatalf("HandleCommand returned %v, want ErrCommandInterface", err)
+	}
+}
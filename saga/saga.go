@@ -0,0 +1,188 @@
+// Package saga координирует долгие процессы, которые реагируют на
+// последовательность событий: хранит состояние процесса, отправляет команды,
+// планирует таймауты и запускает компенсирующие действия
+package saga
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/events"
+	"github.com/google/uuid"
+)
+
+var ErrDuplicateHandler = errors.New("saga already handles event")
+
+// Saga описывает процесс с состоянием S. Экземпляр процесса определяется
+// ключом события, по умолчанию OrderID():
+//
+//	fulfilment := saga.NewSaga[FulfilmentState]("order-fulfilment")
+//	saga.StartOn(fulfilment, onDispatched)
+//	saga.On(fulfilment, onDelivered)
+//	fulfilment.OnTimeout("delivery-deadline", onDeadline)
+type Saga[S any] struct {
+	name      string
+	handlers  map[string]handler[S]
+	families  []familyHandler[S]
+	timeouts  map[string]func(ctx context.Context, c *Context[S]) error
+	correlate func(event events.Event) (uuid.UUID, bool)
+}
+
+type handler[S any] struct {
+	starts bool
+	handle func(ctx context.Context, c *Context[S], event events.Event) error
+}
+
+// familyHandler обрабатывает события, реализующие интерфейс eventType
+type familyHandler[S any] struct {
+	handler[S]
+	eventType reflect.Type
+	matches   func(event events.Event) bool
+}
+
+func NewSaga[S any](name string) *Saga[S] {
+	return &Saga[S]{
+		name:      name,
+		handlers:  map[string]handler[S]{},
+		timeouts:  map[string]func(ctx context.Context, c *Context[S]) error{},
+		correlate: orderIDOf,
+	}
+}
+
+func orderIDOf(event events.Event) (uuid.UUID, bool) {
+	orderEvent, ok := event.(events.OrderEvent)
+	if !ok {
+		return uuid.Nil, false
+	}
+	return orderEvent.OrderID(), true
+}
+
+func (s *Saga[S]) Name() string {
+	return s.name
+}
+
+// CorrelateBy задаёт, какой экземпляр процесса получает событие
+func (s *Saga[S]) CorrelateBy(correlate func(event events.Event) (uuid.UUID, bool)) {
+	s.correlate = correlate
+}
+
+// StartOn вызывает fn для событий T и создаёт экземпляр процесса, если его ещё нет.
+// Если T - интерфейс, например OrderEvent, fn получает события, которые его
+// реализуют и для которых нет обработчика их собственного типа. Второй
+// обработчик того же T через StartOn или On - ошибка программиста, поэтому
+// они паникуют с ErrDuplicateHandler
+func StartOn[S any, T events.Event](saga *Saga[S], fn func(ctx context.Context, c *Context[S], event T) error) {
+	on(saga, true, fn)
+}
+
+// On вызывает fn для событий T только в уже начатом процессе
+func On[S any, T events.Event](saga *Saga[S], fn func(ctx context.Context, c *Context[S], event T) error) {
+	on(saga, false, fn)
+}
+
+func on[S any, T events.Event](saga *Saga[S], starts bool, fn func(ctx context.Context, c *Context[S], event T) error) {
+	h := handler[S]{
+		starts: starts,
+		handle: func(ctx context.Context, c *Context[S], event events.Event) error {
+			actualEvent, ok := event.(T)
+			if !ok {
+				return nil
+			}
+			return fn(ctx, c, actualEvent)
+		},
+	}
+
+	name, ok := events.EventName[T]()
+	if !ok {
+		eventType := reflect.TypeOf((*T)(nil)).Elem()
+		for _, family := range saga.families {
+			if family.eventType == eventType {
+				panic(fmt.Errorf("%w: %s handles %s twice", ErrDuplicateHandler, saga.name, eventType))
+			}
+		}
+		saga.families = append(saga.families, familyHandler[S]{
+			handler:   h,
+			eventType: eventType,
+			matches: func(event events.Event) bool {
+				_, ok := event.(T)
+				return ok
+			},
+		})
+		return
+	}
+
+	if _, ok := saga.handlers[name]; ok {
+		panic(fmt.Errorf("%w: %s handles %s twice", ErrDuplicateHandler, saga.name, name))
+	}
+	saga.handlers[name] = h
+}
+
+// handlerFor выбирает обработчик собственного типа события, а если его
+// нет - первый обработчик интерфейса, который событие реализует
+func (s *Saga[S]) handlerFor(event events.Event) (handler[S], bool) {
+	if h, ok := s.handlers[event.Name()]; ok {
+		return h, true
+	}
+	for _, family := range s.families {
+		if family.matches(event) {
+			return family.handler, true
+		}
+	}
+	return handler[S]{}, false
+}
+
+// OnTimeout вызывает fn, когда наступает таймаут name, запланированный через Context.Schedule
+func (s *Saga[S]) OnTimeout(name string, fn func(ctx context.Context, c *Context[S]) error) {
+	s.timeouts[name] = fn
+}
+
+// Context - экземпляр процесса во время обработки события или таймаута.
+// Команды и таймауты применяются только после сохранения состояния
+type Context[S any] struct {
+	ID    uuid.UUID
+	State *S
+
+	now       time.Time
+	commands  []Command
+	scheduled []scheduledTimeout
+	cancelled []string
+	completed bool
+}
+
+type scheduledTimeout struct {
+	name  string
+	dueAt time.Time
+}
+
+func (c *Context[S]) Now() time.Time {
+	return c.now
+}
+
+// Send сохраняет команду вместе с состоянием процесса; Manager отправляет
+// её после фиксации и повторяет отправку, пока она не удастся
+func (c *Context[S]) Send(command Command) {
+	c.commands = append(c.commands, command)
+}
+
+// Schedule планирует таймаут name через after; повторный вызов переносит таймаут
+func (c *Context[S]) Schedule(name string, after time.Duration) {
+	c.ScheduleAt(name, c.now.Add(after))
+}
+
+// ScheduleAt планирует таймаут name на момент at; таймаут в прошлом
+// наступает при следующем вызове FireTimeouts
+func (c *Context[S]) ScheduleAt(name string, at time.Time) {
+	c.scheduled = append(c.scheduled, scheduledTimeout{name: name, dueAt: at})
+}
+
+func (c *Context[S]) CancelTimeout(name string) {
+	c.cancelled = append(c.cancelled, name)
+}
+
+// Complete завершает процесс: его таймауты отменяются, а следующие события игнорируются
+func (c *Context[S]) Complete() {
+	c.completed = true
+}
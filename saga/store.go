@@ -0,0 +1,188 @@
+package saga
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInstanceNotFound    = errors.New("saga instance not found")
+	ErrConcurrencyConflict = errors.New("saga instance was changed by another handler")
+	ErrTimeoutNotFound     = errors.New("saga timeout not found")
+)
+
+// Instance - сохранённое состояние экземпляра процесса
+type Instance struct {
+	Saga      string
+	ID        uuid.UUID
+	State     []byte
+	Version   int
+	Completed bool
+	// Commands - команды, сохранённые вместе с состоянием и ещё не отправленные
+	Commands  []PendingCommand
+	UpdatedAt time.Time
+}
+
+// PendingCommand - команда в JSON, ожидающая отправки
+type PendingCommand struct {
+	Name    string          `json:"name"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// Timeout - запланированный вызов OnTimeout экземпляра процесса
+type Timeout struct {
+	ID         uuid.UUID
+	Saga       string
+	InstanceID uuid.UUID
+	Name       string
+	DueAt      time.Time
+}
+
+// StateStore хранит экземпляры процессов и их таймауты
+type StateStore interface {
+	Load(ctx context.Context, saga string, id uuid.UUID) (Instance, error)
+	// Save записывает экземпляр, если сохранённая версия равна expectedVersion
+	// (0 - экземпляра ещё нет), иначе возвращает ErrConcurrencyConflict
+	Save(ctx context.Context, instance Instance, expectedVersion int) error
+	// Schedule заменяет таймаут с тем же именем у того же экземпляра
+	Schedule(ctx context.Context, timeout Timeout) error
+	// CancelTimeouts отменяет таймаут name или, если name пустой, все таймауты экземпляра
+	CancelTimeouts(ctx context.Context, saga string, id uuid.UUID, name string) error
+	// DueTimeouts возвращает не больше limit таймаутов процесса saga, наступивших к now
+	DueTimeouts(ctx context.Context, saga string, now time.Time, limit int) ([]Timeout, error)
+	// DeleteTimeout возвращает ErrTimeoutNotFound, если таймаут уже вызван
+	// другим Manager или отменён
+	DeleteTimeout(ctx context.Context, id uuid.UUID) error
+	// Pending возвращает не больше limit экземпляров процесса saga
+	// с неотправленными командами, начиная с давно изменённых
+	Pending(ctx context.Context, saga string, limit int) ([]Instance, error)
+}
+
+type instanceKey struct {
+	saga string
+	id   uuid.UUID
+}
+
+type MemoryStore struct {
+	mutex     sync.Mutex
+	instances map[instanceKey]Instance
+	timeouts  map[uuid.UUID]Timeout
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		instances: map[instanceKey]Instance{},
+		timeouts:  map[uuid.UUID]Timeout{},
+	}
+}
+
+func (s *MemoryStore) Load(ctx context.Context, saga string, id uuid.UUID) (Instance, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	instance, ok := s.instances[instanceKey{saga: saga, id: id}]
+	if !ok {
+		return Instance{}, ErrInstanceNotFound
+	}
+	return instance, nil
+}
+
+func (s *MemoryStore) Save(ctx context.Context, instance Instance, expectedVersion int) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key := instanceKey{saga: instance.Saga, id: instance.ID}
+	if s.instances[key].Version != expectedVersion {
+		return ErrConcurrencyConflict
+	}
+	// Manager дописывает команды в срез загруженного экземпляра
+	instance.Commands = append([]PendingCommand(nil), instance.Commands...)
+	s.instances[key] = instance
+
+	return nil
+}
+
+func (s *MemoryStore) Schedule(ctx context.Context, timeout Timeout) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.cancel(timeout.Saga, timeout.InstanceID, timeout.Name)
+	s.timeouts[timeout.ID] = timeout
+
+	return nil
+}
+
+func (s *MemoryStore) CancelTimeouts(ctx context.Context, saga string, id uuid.UUID, name string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.cancel(saga, id, name)
+
+	return nil
+}
+
+func (s *MemoryStore) cancel(saga string, id uuid.UUID, name string) {
+	for timeoutID, timeout := range s.timeouts {
+		if timeout.Saga == saga && timeout.InstanceID == id && (name == "" || timeout.Name == name) {
+			delete(s.timeouts, timeoutID)
+		}
+	}
+}
+
+func (s *MemoryStore) DueTimeouts(ctx context.Context, saga string, now time.Time, limit int) ([]Timeout, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var result []Timeout
+	for _, timeout := range s.timeouts {
+		if timeout.Saga == saga && !timeout.DueAt.After(now) {
+			result = append(result, timeout)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].DueAt.Before(result[j].DueAt)
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+
+	return result, nil
+}
+
+func (s *MemoryStore) DeleteTimeout(ctx context.Context, id uuid.UUID) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.timeouts[id]; !ok {
+		return ErrTimeoutNotFound
+	}
+	delete(s.timeouts, id)
+
+	return nil
+}
+
+func (s *MemoryStore) Pending(ctx context.Context, saga string, limit int) ([]Instance, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var result []Instance
+	for _, instance := range s.instances {
+		if instance.Saga == saga && len(instance.Commands) > 0 {
+			result = append(result, instance)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].UpdatedAt.Before(result[j].UpdatedAt)
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+
+	return result, nil
+}
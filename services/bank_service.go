@@ -7,8 +7,9 @@ import (
 )
 
 type ExchangeRateService interface {
-	IsConversionPossible (from domain.Currency, to domain.Currency) bool
+	IsConversionPossible(from domain.Currency, to domain.Currency) bool
 	Convert(to domain.Currency, from value_objects.Money) (value_objects.Money, error)
+	ConvertMoneyBag(to domain.Currency, bag value_objects.MoneyBag) (value_objects.Money, error)
 }
 
 type DefaultExchangeRateService struct {
@@ -35,4 +36,16 @@ func (s *DefaultExchangeRateService) Convert(to domain.Currency, from value_obje
 	// какой-то код
 	//
 	return result, nil
-}
\ No newline at end of file
+}
+
+// ConvertMoneyBag переводит суммы во всех валютах в валюту to и складывает их
+func (s *DefaultExchangeRateService) ConvertMoneyBag(to domain.Currency, bag value_objects.MoneyBag) (value_objects.Money, error) {
+	currency, err := value_objects.CurrencyByCode(to.Code)
+	if err != nil {
+		return value_objects.Money{}, err
+	}
+
+	return bag.Total(currency, func(_ value_objects.Currency, from value_objects.Money) (value_objects.Money, error) {
+		return s.Convert(to, from)
+	})
+}
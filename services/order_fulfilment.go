@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/events"
+	"github.com/MaksimDzhangirov/PracticalDDD/saga"
+	"github.com/google/uuid"
+)
+
+// DeliveryDeadline - срок доставки, после которого заказ передаётся в поддержку
+const DeliveryDeadline = 5 * 24 * time.Hour
+
+const deliveryDeadlineTimeout = "delivery-deadline"
+
+// Команды процесса выполнения заказа
+
+// EscalateDelivery просит поддержку разобраться с заказом, который не доставлен вовремя
+type EscalateDelivery struct {
+	OrderID      uuid.UUID
+	DispatchedAt time.Time
+}
+
+func (c EscalateDelivery) Name() string {
+	return "command.order.escalate-delivery"
+}
+
+// ReleaseStock возвращает на склад товары заказа, который не удалось доставить
+type ReleaseStock struct {
+	OrderID uuid.UUID
+}
+
+func (c ReleaseStock) Name() string {
+	return "command.order.release-stock"
+}
+
+// RefundOrder возвращает клиенту оплату заказа, который не удалось доставить
+type RefundOrder struct {
+	OrderID uuid.UUID
+}
+
+func (c RefundOrder) Name() string {
+	return "command.order.refund"
+}
+
+// OrderFulfilment - состояние процесса выполнения заказа
+type OrderFulfilment struct {
+	Status       string    `json:"status"`
+	DispatchedAt time.Time `json:"dispatched_at"`
+	Escalated    bool      `json:"escalated"`
+}
+
+const (
+	FulfilmentInDelivery = "in-delivery"
+	FulfilmentDelivered  = "delivered"
+	FulfilmentFailed     = "failed"
+)
+
+// NewOrderFulfilmentSaga описывает процесс выполнения заказа: после отправки
+// ждёт доставки DeliveryDeadline, затем передаёт заказ в поддержку; если
+// доставка не удалась, возвращает товары на склад и деньги клиенту
+func NewOrderFulfilmentSaga() *saga.Saga[OrderFulfilment] {
+	fulfilment := saga.NewSaga[OrderFulfilment]("order-fulfilment")
+
+	saga.StartOn(fulfilment, func(ctx context.Context, c *saga.Context[OrderFulfilment], event events.OrderDispatched) error {
+		c.State.Status = FulfilmentInDelivery
+		// время отправки из конверта: событие могло быть доставлено с задержкой
+		c.State.DispatchedAt = c.Now()
+		if envelope, ok := events.EnvelopeOf(ctx, event); ok {
+			c.State.DispatchedAt = envelope.OccurredAt
+		}
+		// срок отсчитывается от отправки, а не от получения события
+		c.ScheduleAt(deliveryDeadlineTimeout, c.State.DispatchedAt.Add(DeliveryDeadline))
+		return nil
+	})
+
+	saga.On(fulfilment, func(ctx context.Context, c *saga.Context[OrderFulfilment], event events.OrderDelivered) error {
+		c.State.Status = FulfilmentDelivered
+		c.Complete()
+		return nil
+	})
+
+	saga.On(fulfilment, func(ctx context.Context, c *saga.Context[OrderFulfilment], event events.OrderDeliveryFailed) error {
+		c.State.Status = FulfilmentFailed
+		c.Send(ReleaseStock{OrderID: c.ID})
+		c.Send(RefundOrder{OrderID: c.ID})
+		c.Complete()
+		return nil
+	})
+
+	fulfilment.OnTimeout(deliveryDeadlineTimeout, func(ctx context.Context, c *saga.Context[OrderFulfilment]) error {
+		c.State.Escalated = true
+		c.Send(EscalateDelivery{
+			OrderID:      c.ID,
+			DispatchedAt: c.State.DispatchedAt,
+		})
+		return nil
+	})
+
+	return fulfilment
+}
@@ -0,0 +1,140 @@
+package services_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/clock"
+	"github.com/MaksimDzhangirov/PracticalDDD/events"
+	"github.com/MaksimDzhangirov/PracticalDDD/saga"
+	"github.com/MaksimDzhangirov/PracticalDDD/services"
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// fulfilment - процесс выполнения заказа на SQLite с записью отправленных команд
+type fulfilment struct {
+	manager  *saga.Manager[services.OrderFulfilment]
+	clock    *clock.Manual
+	commands []saga.Command
+}
+
+func newFulfilment(t *testing.T) *fulfilment {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "saga.db") + "?_busy_timeout=5000&_txlock=immediate"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := saga.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+
+	f := &fulfilment{clock: clock.NewManual(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))}
+	bus := saga.NewCommandBus()
+	record := func(command saga.Command) error {
+		f.commands = append(f.commands, command)
+		return nil
+	}
+	errs := []error{
+		saga.HandleCommand(bus, func(ctx context.Context, command services.EscalateDelivery) error {
+			return record(command)
+		}),
+		saga.HandleCommand(bus, func(ctx context.Context, command services.ReleaseStock) error {
+			return record(command)
+		}),
+		saga.HandleCommand(bus, func(ctx context.Context, command services.RefundOrder) error {
+			return record(command)
+		}),
+	}
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f.manager = saga.NewManager(services.NewOrderFulfilmentSaga(), saga.NewGormStore(db), bus, f.clock)
+	return f
+}
+
+// dispatched доставляет OrderDispatched, записанное в момент occurredAt
+func (f *fulfilment) dispatched(t *testing.T, orderID uuid.UUID, occurredAt time.Time) {
+	t.Helper()
+
+	event := events.NewOrderDispatched(orderID)
+	ctx := events.ContextWithEnvelope(context.Background(), events.NewEnvelope(event, clock.Fixed(occurredAt)))
+	if err := f.manager.Handle(ctx, event); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (f *fulfilment) fire(t *testing.T) int {
+	t.Helper()
+
+	count, err := f.manager.FireTimeouts(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestOrderFulfilmentEscalatesAfterDeliveryDeadline(t *testing.T) {
+	f := newFulfilment(t)
+	orderID := uuid.New()
+	// событие доставлено через сутки после отправки заказа
+	dispatchedAt := f.clock.Now().Add(-24 * time.Hour)
+	f.dispatched(t, orderID, dispatchedAt)
+
+	f.clock.Advance(services.DeliveryDeadline - 24*time.Hour - time.Second)
+	if count := f.fire(t); count != 0 {
+		t.Fatalf("fired %d timeouts before the deadline", count)
+	}
+
+	f.clock.Advance(time.Second)
+	if count := f.fire(t); count != 1 {
+		t.Fatalf("fired %d timeouts at the deadline, want 1", count)
+	}
+	if len(f.commands) != 1 {
+		t.Fatalf("sent %d commands, want 1", len(f.commands))
+	}
+	escalate, ok := f.commands[0].(services.EscalateDelivery)
+	if !ok || escalate.OrderID != orderID || !escalate.DispatchedAt.Equal(dispatchedAt) {
+		t.Fatalf("sent %#v", f.commands[0])
+	}
+
+	f.clock.Advance(services.DeliveryDeadline)
+	if count := f.fire(t); count != 0 {
+		t.Fatalf("fired %d timeouts after escalation", count)
+	}
+}
+
+func TestOrderFulfilmentCompensatesFailedDelivery(t *testing.T) {
+	f := newFulfilment(t)
+	orderID := uuid.New()
+	f.dispatched(t, orderID, f.clock.Now())
+
+	if err := f.manager.Handle(context.Background(), events.NewOrderDeliveryFailed(orderID)); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(f.commands) != 2 {
+		t.Fatalf("sent %d commands, want 2", len(f.commands))
+	}
+	if command, ok := f.commands[0].(services.ReleaseStock); !ok || command.OrderID != orderID {
+		t.Fatalf("first command %#v, want ReleaseStock", f.commands[0])
+	}
+	if command, ok := f.commands[1].(services.RefundOrder); !ok || command.OrderID != orderID {
+		t.Fatalf("second command %#v, want RefundOrder", f.commands[1])
+	}
+
+	// процесс завершён: срок доставки больше не отслеживается
+	f.clock.Advance(services.DeliveryDeadline)
+	if count := f.fire(t); count != 0 {
+		t.Fatalf("fired %d timeouts after the order failed", count)
+	}
+}
@@ -1,32 +1,64 @@
 package services
 
 import (
+	"context"
+
 	"github.com/MaksimDzhangirov/PracticalDDD/domain/order/entity"
 	"github.com/MaksimDzhangirov/PracticalDDD/domain/order/repository"
 	"github.com/MaksimDzhangirov/PracticalDDD/events"
 	value_objects "github.com/MaksimDzhangirov/PracticalDDD/value-objects"
 )
 
+// Transaction выполняет fn атомарно, например outbox.Transaction(db)
+type Transaction func(ctx context.Context, fn func(ctx context.Context) error) error
+
+// withoutTransaction используется, когда хранилище не поддерживает транзакции
+func withoutTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
 type OrderService struct {
-	repository repository.OrderRepository
-	publisher events.EventPublisher
+	repository  repository.OrderRepository
+	publisher   events.Notifier
+	transaction Transaction
+}
+
+// NewOrderService принимает EventPublisher или outbox.Outbox; с Outbox
+// заказ и событие OrderCreated сохраняются в одной транзакции
+func NewOrderService(repository repository.OrderRepository, publisher events.Notifier, transaction Transaction) *OrderService {
+	if transaction == nil {
+		transaction = withoutTransaction
+	}
+
+	return &OrderService{
+		repository:  repository,
+		publisher:   publisher,
+		transaction: transaction,
+	}
 }
 
-func (s *OrderService) Create(order entity.Order) (*entity.Order, error) {
-	result, err := s.repository.Create(order)
+func (s *OrderService) Create(ctx context.Context, order entity.Order) (*entity.Order, error) {
+	var result *entity.Order
+	err := s.transaction(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = s.repository.Create(ctx, order)
+		if err != nil {
+			return err
+		}
+		//
+		// обновляем адрес в базе данных
+		//
+		return s.publisher.Notify(ctx, events.NewOrderCreated(result.ID()))
+	})
 	if err != nil {
 		return nil, err
 	}
-	//
-	// обновляем адрес в базе данных
-	//
-	s.publisher.Notify(events.NewOrderCreated(result.ID()))
 
 	return result, nil
 }
 
-func (s *OrderService) ChangeAddress(order entity.Order, address value_objects.Address) {
+func (s *OrderService) ChangeAddress(ctx context.Context, order entity.Order, address value_objects.Address) error {
 	evt := order.ChangeAddress(address)
 
-	s.publisher.Notify(evt) // публикуем события только внутри объект, не хранящих состояние
-}
\ No newline at end of file
+	return s.publisher.Notify(ctx, evt) // публикуем события только внутри объект, не хранящих состояние
+}
@@ -0,0 +1,170 @@
+package value_objects
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/clock"
+)
+
+var ErrUnknownJurisdiction = errors.New("unknown jurisdiction")
+
+// DefaultAgeOfMajority - возраст совершеннолетия, используемый Birthday.IsAdult
+const DefaultAgeOfMajority = 18
+
+// Jurisdiction описывает правила совершеннолетия в стране или её регионе
+type Jurisdiction struct {
+	// Code - код ISO 3166-1 или ISO 3166-2, например "DE" или "US-AL"
+	Code          string
+	AgeOfMajority int
+	// LeapDayFebruary28 - родившиеся 29 февраля в невисокосный год становятся
+	// старше 28 февраля, иначе 1 марта
+	LeapDayFebruary28 bool
+}
+
+// JurisdictionTable - настраиваемая таблица возрастов совершеннолетия
+type JurisdictionTable struct {
+	mutex         sync.RWMutex
+	jurisdictions map[string]Jurisdiction
+}
+
+func NewJurisdictionTable(jurisdictions ...Jurisdiction) *JurisdictionTable {
+	table := &JurisdictionTable{
+		jurisdictions: make(map[string]Jurisdiction, len(jurisdictions)),
+	}
+	for _, jurisdiction := range jurisdictions {
+		table.Set(jurisdiction)
+	}
+
+	return table
+}
+
+// Set добавляет юрисдикцию или заменяет её правила
+func (t *JurisdictionTable) Set(jurisdiction Jurisdiction) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.jurisdictions[jurisdiction.Code] = jurisdiction
+}
+
+func (t *JurisdictionTable) Get(code string) (Jurisdiction, error) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	jurisdiction, ok := t.jurisdictions[code]
+	if !ok {
+		return Jurisdiction{}, fmt.Errorf("%w: %s", ErrUnknownJurisdiction, code)
+	}
+
+	return jurisdiction, nil
+}
+
+// Jurisdictions - таблица по умолчанию, используемая Birthday.IsAdultIn
+var Jurisdictions = NewJurisdictionTable(
+	Jurisdiction{Code: "AT", AgeOfMajority: 18},
+	Jurisdiction{Code: "BY", AgeOfMajority: 18},
+	Jurisdiction{Code: "CA", AgeOfMajority: 18},
+	Jurisdiction{Code: "CA-BC", AgeOfMajority: 19},
+	Jurisdiction{Code: "CA-NB", AgeOfMajority: 19},
+	Jurisdiction{Code: "CA-NL", AgeOfMajority: 19},
+	Jurisdiction{Code: "CA-NS", AgeOfMajority: 19},
+	Jurisdiction{Code: "CA-NT", AgeOfMajority: 19},
+	Jurisdiction{Code: "CA-NU", AgeOfMajority: 19},
+	Jurisdiction{Code: "CA-YT", AgeOfMajority: 19},
+	Jurisdiction{Code: "CH", AgeOfMajority: 18},
+	Jurisdiction{Code: "DE", AgeOfMajority: 18},
+	Jurisdiction{Code: "ES", AgeOfMajority: 18},
+	Jurisdiction{Code: "FR", AgeOfMajority: 18},
+	Jurisdiction{Code: "GB", AgeOfMajority: 18},
+	Jurisdiction{Code: "GB-SCT", AgeOfMajority: 16},
+	Jurisdiction{Code: "IT", AgeOfMajority: 18},
+	Jurisdiction{Code: "JP", AgeOfMajority: 18},
+	Jurisdiction{Code: "KR", AgeOfMajority: 19},
+	Jurisdiction{Code: "KZ", AgeOfMajority: 18},
+	Jurisdiction{Code: "NL", AgeOfMajority: 18},
+	Jurisdiction{Code: "NZ", AgeOfMajority: 20, LeapDayFebruary28: true},
+	Jurisdiction{Code: "PL", AgeOfMajority: 18},
+	Jurisdiction{Code: "RU", AgeOfMajority: 18},
+	Jurisdiction{Code: "SG", AgeOfMajority: 21},
+	Jurisdiction{Code: "TH", AgeOfMajority: 20},
+	Jurisdiction{Code: "UA", AgeOfMajority: 18},
+	Jurisdiction{Code: "US", AgeOfMajority: 18},
+	Jurisdiction{Code: "US-AL", AgeOfMajority: 19},
+	Jurisdiction{Code: "US-MS", AgeOfMajority: 21},
+	Jurisdiction{Code: "US-NE", AgeOfMajority: 19},
+)
+
+// Age возвращает полное количество лет на момент at. Родившиеся 29 февраля
+// в невисокосный год становятся старше 1 марта
+func (b Birthday) Age(at time.Time) int {
+	return b.age(at, false)
+}
+
+// AgeIn возвращает возраст по правилам юрисдикции
+func (b Birthday) AgeIn(jurisdiction Jurisdiction, at time.Time) int {
+	return b.age(at, jurisdiction.LeapDayFebruary28)
+}
+
+// IsAdultIn проверяет совершеннолетие по таблице Jurisdictions
+func (b Birthday) IsAdultIn(code string, clock clock.Clock) (bool, error) {
+	jurisdiction, err := Jurisdictions.Get(code)
+	if err != nil {
+		return false, err
+	}
+
+	return b.AgeIn(jurisdiction, clock.Now()) >= jurisdiction.AgeOfMajority, nil
+}
+
+// Anniversary возвращает день рождения в году year с учётом 29 февраля
+func (b Birthday) Anniversary(year int, leapDayFebruary28 bool) time.Time {
+	_, month, day := time.Time(b).Date()
+	if month == time.February && day == 29 && !isLeapYear(year) {
+		if leapDayFebruary28 {
+			day = 28
+		} else {
+			month, day = time.March, 1
+		}
+	}
+
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// NextAnniversary возвращает ближайший день рождения не раньше at
+func (b Birthday) NextAnniversary(at time.Time) time.Time {
+	today := dateOf(at)
+
+	next := b.Anniversary(today.Year(), false)
+	if next.Before(today) {
+		next = b.Anniversary(today.Year()+1, false)
+	}
+
+	return next
+}
+
+// IsAnniversary проверяет, что at - день рождения
+func (b Birthday) IsAnniversary(at time.Time) bool {
+	return b.NextAnniversary(at).Equal(dateOf(at))
+}
+
+func (b Birthday) age(at time.Time, leapDayFebruary28 bool) int {
+	today := dateOf(at)
+
+	years := today.Year() - time.Time(b).Year()
+	if today.Before(b.Anniversary(today.Year(), leapDayFebruary28)) {
+		years--
+	}
+
+	return years
+}
+
+// dateOf отбрасывает время, оставляя календарную дату в часовом поясе at
+func dateOf(at time.Time) time.Time {
+	year, month, day := at.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func isLeapYear(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
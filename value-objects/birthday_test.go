@@ -0,0 +1,164 @@
+package value_objects_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/clock"
+	value_objects "github.com/MaksimDzhangirov/PracticalDDD/value-objects"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestBirthdayAge(t *testing.T) {
+	born := birthday(t, 1990, time.June, 15)
+
+	tests := []struct {
+		at   time.Time
+		want int
+	}{
+		{date(1990, time.June, 15), 0},
+		{date(2024, time.June, 14), 33},
+		{date(2024, time.June, 15), 34},
+		// время суток не влияет на возраст
+		{time.Date(2024, time.June, 14, 23, 59, 59, 0, time.UTC), 33},
+	}
+	for _, test := range tests {
+		if got := born.Age(test.at); got != test.want {
+			t.Fatalf("Age(%v) = %d, want %d", test.at, got, test.want)
+		}
+	}
+}
+
+func TestBirthdayLeapDay(t *testing.T) {
+	born := birthday(t, 2004, time.February, 29)
+	nz, err := value_objects.Jurisdictions.Get("NZ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	de, err := value_objects.Jurisdictions.Get("DE")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// в невисокосный год день рождения 1 марта, в Новой Зеландии - 28 февраля
+	if got := born.Age(date(2022, time.February, 28)); got != 17 {
+		t.Fatalf("Age on February 28 = %d, want 17", got)
+	}
+	if got := born.AgeIn(de, date(2022, time.February, 28)); got != 17 {
+		t.Fatalf("AgeIn(DE) on February 28 = %d, want 17", got)
+	}
+	if got := born.AgeIn(nz, date(2022, time.February, 28)); got != 18 {
+		t.Fatalf("AgeIn(NZ) on February 28 = %d, want 18", got)
+	}
+	if got := born.Age(date(2022, time.March, 1)); got != 18 {
+		t.Fatalf("Age on March 1 = %d, want 18", got)
+	}
+	if got := born.Age(date(2024, time.February, 29)); got != 20 {
+		t.Fatalf("Age on February 29 = %d, want 20", got)
+	}
+
+	tests := []struct {
+		year     int
+		february bool
+		want     time.Time
+	}{
+		{2023, false, date(2023, time.March, 1)},
+		{2023, true, date(2023, time.February, 28)},
+		{2024, false, date(2024, time.February, 29)},
+		{2024, true, date(2024, time.February, 29)},
+		{2100, false, date(2100, time.March, 1)},
+	}
+	for _, test := range tests {
+		if got := born.Anniversary(test.year, test.february); !got.Equal(test.want) {
+			t.Fatalf("Anniversary(%d, %v) = %v, want %v", test.year, test.february, got, test.want)
+		}
+	}
+}
+
+func TestBirthdayNextAnniversary(t *testing.T) {
+	born := birthday(t, 1990, time.June, 15)
+
+	if got := born.NextAnniversary(time.Date(2024, time.June, 15, 10, 0, 0, 0, time.UTC)); !got.Equal(date(2024, time.June, 15)) {
+		t.Fatalf("NextAnniversary on the birthday = %v", got)
+	}
+	if got := born.NextAnniversary(date(2024, time.June, 16)); !got.Equal(date(2025, time.June, 15)) {
+		t.Fatalf("NextAnniversary after the birthday = %v", got)
+	}
+	if !born.IsAnniversary(time.Date(2024, time.June, 15, 18, 0, 0, 0, time.UTC)) || born.IsAnniversary(date(2024, time.June, 14)) {
+		t.Fatal("IsAnniversary is wrong")
+	}
+
+	leap := birthday(t, 2004, time.February, 29)
+	if got := leap.NextAnniversary(date(2023, time.March, 1)); !got.Equal(date(2023, time.March, 1)) {
+		t.Fatalf("leap day NextAnniversary = %v", got)
+	}
+}
+
+func TestBirthdayIsAdult(t *testing.T) {
+	born := birthday(t, 2006, time.March, 10)
+	now := clock.NewManual(time.Date(2024, time.March, 9, 12, 0, 0, 0, time.UTC))
+
+	if born.IsAdult(now) {
+		t.Fatal("adult a day before the 18th birthday")
+	}
+	now.Advance(24 * time.Hour)
+	if !born.IsAdult(now) {
+		t.Fatal("not adult on the 18th birthday")
+	}
+}
+
+func TestBirthdayIsAdultIn(t *testing.T) {
+	born := birthday(t, 2004, time.February, 29)
+	now := clock.NewManual(date(2023, time.February, 28))
+
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{"DE", true},
+		{"US-AL", false},
+		{"US-MS", false},
+		{"GB-SCT", true},
+	}
+	for _, test := range tests {
+		adult, err := born.IsAdultIn(test.code, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if adult != test.want {
+			t.Fatalf("IsAdultIn(%s) = %v, want %v", test.code, adult, test.want)
+		}
+	}
+
+	// в Алабаме совершеннолетие в 19 лет, день рождения наступает 1 марта
+	now.Advance(24 * time.Hour)
+	if adult, err := born.IsAdultIn("US-AL", now); err != nil || !adult {
+		t.Fatalf("IsAdultIn(US-AL) on March 1 = %v, %v", adult, err)
+	}
+
+	if _, err := born.IsAdultIn("XX", now); !errors.Is(err, value_objects.ErrUnknownJurisdiction) {
+		t.Fatalf("unknown jurisdiction returned %v", err)
+	}
+}
+
+func TestJurisdictionTable(t *testing.T) {
+	table := value_objects.NewJurisdictionTable(value_objects.Jurisdiction{Code: "XX", AgeOfMajority: 21})
+
+	jurisdiction, err := table.Get("XX")
+	if err != nil || jurisdiction.AgeOfMajority != 21 {
+		t.Fatalf("Get(XX) = %+v, %v", jurisdiction, err)
+	}
+
+	table.Set(value_objects.Jurisdiction{Code: "XX", AgeOfMajority: 20})
+	if jurisdiction, err := table.Get("XX"); err != nil || jurisdiction.AgeOfMajority != 20 {
+		t.Fatalf("Get(XX) after Set = %+v, %v", jurisdiction, err)
+	}
+
+	if _, err := table.Get("DE"); !errors.Is(err, value_objects.ErrUnknownJurisdiction) {
+		t.Fatalf("Get(DE) returned %v", err)
+	}
+}
@@ -0,0 +1,508 @@
+package value_objects
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// HSL - цвет в виде тона (0-360), насыщенности и светлоты (0-1)
+type HSL struct {
+	Hue        float64
+	Saturation float64
+	Lightness  float64
+}
+
+// HSV - цвет в виде тона (0-360), насыщенности и яркости (0-1)
+type HSV struct {
+	Hue        float64
+	Saturation float64
+	Value      float64
+}
+
+// BlendMode - режим наложения цветов, как в CSS mix-blend-mode
+type BlendMode int
+
+const (
+	BlendNormal BlendMode = iota
+	BlendMultiply
+	BlendScreen
+	BlendOverlay
+	BlendDarken
+	BlendLighten
+	BlendDifference
+	BlendAdd
+)
+
+// Минимальная контрастность текста по WCAG 2.1
+const (
+	ContrastAALarge = 3.0
+	ContrastAA      = 4.5
+	ContrastAAA     = 7.0
+)
+
+// ParseColor разбирает цвет в формате CSS: "#f00", "#ff000080",
+// "rgb(255, 0, 0)", "rgba(255 0 0 / 50%)", "hsl(0, 100%, 50%)" или "red"
+func ParseColor(value string) (Color, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+
+	switch {
+	case strings.HasPrefix(value, "#"):
+		return parseHexColor(value)
+	case strings.HasPrefix(value, "rgb"):
+		return parseFunctionalColor(value, "rgb", fromRGBArguments)
+	case strings.HasPrefix(value, "hsl"):
+		return parseFunctionalColor(value, "hsl", fromHSLArguments)
+	case value == "transparent":
+		return Color{Transparency: 255}, nil
+	}
+
+	if color, ok := NamedColor(value); ok {
+		return color, nil
+	}
+
+	return Color{}, fmt.Errorf("%w: %q", ErrInvalidColor, value)
+}
+
+func parseHexColor(value string) (Color, error) {
+	digits := strings.TrimPrefix(value, "#")
+	if len(digits) == 3 || len(digits) == 4 {
+		expanded := make([]byte, 0, 2*len(digits))
+		for i := 0; i < len(digits); i++ {
+			expanded = append(expanded, digits[i], digits[i])
+		}
+		digits = string(expanded)
+	}
+	if len(digits) == 6 {
+		digits += "ff"
+	}
+
+	parsed, err := strconv.ParseUint(digits, 16, 32)
+	if len(digits) != 8 || err != nil {
+		return Color{}, fmt.Errorf("%w: %q", ErrInvalidColor, value)
+	}
+
+	return Color{
+		Red:          byte(parsed >> 24),
+		Green:        byte(parsed >> 16),
+		Blue:         byte(parsed >> 8),
+		Transparency: 255 - byte(parsed),
+	}, nil
+}
+
+func parseFunctionalColor(value, name string, build func(arguments []string) (Color, error)) (Color, error) {
+	value = strings.TrimPrefix(strings.TrimPrefix(value, name), "a")
+	if !strings.HasPrefix(value, "(") || !strings.HasSuffix(value, ")") {
+		return Color{}, fmt.Errorf("%w: %q", ErrInvalidColor, value)
+	}
+
+	arguments := strings.FieldsFunc(value[1:len(value)-1], func(r rune) bool {
+		return r == ',' || r == '/' || r == ' '
+	})
+	if len(arguments) != 3 && len(arguments) != 4 {
+		return Color{}, fmt.Errorf("%w: %q", ErrInvalidColor, value)
+	}
+
+	color, err := build(arguments[:3])
+	if err != nil {
+		return Color{}, fmt.Errorf("%w: %q", ErrInvalidColor, value)
+	}
+	if len(arguments) == 4 {
+		alpha, err := parseCSSNumber(arguments[3], 1)
+		if err != nil || alpha < 0 || alpha > 1 {
+			return Color{}, fmt.Errorf("%w: %q", ErrInvalidColor, value)
+		}
+		color = color.WithAlpha(alpha)
+	}
+
+	return color, nil
+}
+
+func fromRGBArguments(arguments []string) (Color, error) {
+	var channels [3]byte
+	for i, argument := range arguments {
+		channel, err := parseCSSNumber(argument, 255)
+		if err != nil || channel < 0 || channel > 255 {
+			return Color{}, ErrInvalidColor
+		}
+		channels[i] = clampByte(channel)
+	}
+
+	return Color{Red: channels[0], Green: channels[1], Blue: channels[2]}, nil
+}
+
+func fromHSLArguments(arguments []string) (Color, error) {
+	hue, err := strconv.ParseFloat(strings.TrimSuffix(arguments[0], "deg"), 64)
+	if err != nil {
+		return Color{}, ErrInvalidColor
+	}
+	saturation, err := parseCSSNumber(arguments[1], 1)
+	if err != nil || !strings.HasSuffix(arguments[1], "%") {
+		return Color{}, ErrInvalidColor
+	}
+	lightness, err := parseCSSNumber(arguments[2], 1)
+	if err != nil || !strings.HasSuffix(arguments[2], "%") {
+		return Color{}, ErrInvalidColor
+	}
+
+	return FromHSL(HSL{Hue: hue, Saturation: saturation, Lightness: lightness}), nil
+}
+
+// parseCSSNumber разбирает число или процент; 100% соответствует max
+func parseCSSNumber(value string, max float64) (float64, error) {
+	if strings.HasSuffix(value, "%") {
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+		return percent / 100 * max, err
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// Alpha возвращает непрозрачность от 0 (прозрачный) до 1 (непрозрачный)
+func (c Color) Alpha() float64 {
+	return 1 - float64(c.Transparency)/255
+}
+
+// WithAlpha возвращает тот же цвет с непрозрачностью alpha от 0 до 1
+func (c Color) WithAlpha(alpha float64) Color {
+	c.Transparency = 255 - clampByte(alpha*255)
+	return c
+}
+
+// ToHex возвращает "#rrggbb", а для прозрачных цветов "#rrggbbaa"
+func (c Color) ToHex() string {
+	if c.Transparency == 0 {
+		return fmt.Sprintf("#%02x%02x%02x", c.Red, c.Green, c.Blue)
+	}
+	return fmt.Sprintf("#%02x%02x%02x%02x", c.Red, c.Green, c.Blue, 255-c.Transparency)
+}
+
+// ToCSSHSL возвращает "hsl(0, 100%, 50%)" или "hsla(...)" для прозрачных цветов
+func (c Color) ToCSSHSL() string {
+	hsl := c.ToHSL()
+	h, s, l := formatFloat(hsl.Hue), formatFloat(hsl.Saturation*100), formatFloat(hsl.Lightness*100)
+	if c.Transparency == 0 {
+		return fmt.Sprintf("hsl(%s, %s%%, %s%%)", h, s, l)
+	}
+	return fmt.Sprintf("hsla(%s, %s%%, %s%%, %s)", h, s, l, formatFloat(c.Alpha()))
+}
+
+func (c Color) ToHSL() HSL {
+	r, g, b := float64(c.Red)/255, float64(c.Green)/255, float64(c.Blue)/255
+	max, min := math.Max(r, math.Max(g, b)), math.Min(r, math.Min(g, b))
+
+	hsl := HSL{
+		Hue:       hue(r, g, b, max, min),
+		Lightness: (max + min) / 2,
+	}
+	if delta := max - min; delta != 0 {
+		hsl.Saturation = delta / (1 - math.Abs(2*hsl.Lightness-1))
+	}
+
+	return hsl
+}
+
+func (c Color) ToHSV() HSV {
+	r, g, b := float64(c.Red)/255, float64(c.Green)/255, float64(c.Blue)/255
+	max, min := math.Max(r, math.Max(g, b)), math.Min(r, math.Min(g, b))
+
+	hsv := HSV{
+		Hue:   hue(r, g, b, max, min),
+		Value: max,
+	}
+	if max != 0 {
+		hsv.Saturation = (max - min) / max
+	}
+
+	return hsv
+}
+
+func hue(r, g, b, max, min float64) float64 {
+	delta := max - min
+	if delta == 0 {
+		return 0
+	}
+
+	var h float64
+	switch max {
+	case r:
+		h = math.Mod((g-b)/delta, 6)
+	case g:
+		h = (b-r)/delta + 2
+	default:
+		h = (r-g)/delta + 4
+	}
+
+	h *= 60
+	if h < 0 {
+		h += 360
+	}
+	return h
+}
+
+// FromHSL создаёт непрозрачный цвет из HSL
+func FromHSL(hsl HSL) Color {
+	chroma := (1 - math.Abs(2*hsl.Lightness-1)) * hsl.Saturation
+	return fromHueChroma(hsl.Hue, chroma, hsl.Lightness-chroma/2)
+}
+
+// FromHSV создаёт непрозрачный цвет из HSV
+func FromHSV(hsv HSV) Color {
+	chroma := hsv.Value * hsv.Saturation
+	return fromHueChroma(hsv.Hue, chroma, hsv.Value-chroma)
+}
+
+func fromHueChroma(h, chroma, m float64) Color {
+	h = math.Mod(math.Mod(h, 360)+360, 360) / 60
+	x := chroma * (1 - math.Abs(math.Mod(h, 2)-1))
+
+	var r, g, b float64
+	switch {
+	case h < 1:
+		r, g, b = chroma, x, 0
+	case h < 2:
+		r, g, b = x, chroma, 0
+	case h < 3:
+		r, g, b = 0, chroma, x
+	case h < 4:
+		r, g, b = 0, x, chroma
+	case h < 5:
+		r, g, b = x, 0, chroma
+	default:
+		r, g, b = chroma, 0, x
+	}
+
+	return Color{
+		Red:   clampByte((r + m) * 255),
+		Green: clampByte((g + m) * 255),
+		Blue:  clampByte((b + m) * 255),
+	}
+}
+
+// Lighten повышает воспринимаемую светлоту (L* в CIELAB) на amount от 0 до 100,
+// поэтому жёлтый и синий светлеют для глаза одинаково
+func (c Color) Lighten(amount float64) Color {
+	l, a, b := c.toLab()
+	result := fromLab(math.Max(0, math.Min(100, l+amount)), a, b)
+	result.Transparency = c.Transparency
+	return result
+}
+
+// Darken понижает воспринимаемую светлоту на amount от 0 до 100
+func (c Color) Darken(amount float64) Color {
+	return c.Lighten(-amount)
+}
+
+// Blend накладывает other поверх c с учётом прозрачности other
+func (c Color) Blend(other Color, mode BlendMode) Color {
+	alpha := other.Alpha()
+	channel := func(backdrop, source byte) byte {
+		cb, cs := float64(backdrop)/255, float64(source)/255
+		blended := blendChannel(cb, cs, mode)
+		return clampByte(((1-alpha)*cb + alpha*blended) * 255)
+	}
+
+	return Color{
+		Red:          channel(c.Red, other.Red),
+		Green:        channel(c.Green, other.Green),
+		Blue:         channel(c.Blue, other.Blue),
+		Transparency: c.Transparency,
+	}
+}
+
+func blendChannel(cb, cs float64, mode BlendMode) float64 {
+	switch mode {
+	case BlendMultiply:
+		return cb * cs
+	case BlendScreen:
+		return cb + cs - cb*cs
+	case BlendOverlay:
+		if cb <= 0.5 {
+			return 2 * cb * cs
+		}
+		return 1 - 2*(1-cb)*(1-cs)
+	case BlendDarken:
+		return math.Min(cb, cs)
+	case BlendLighten:
+		return math.Max(cb, cs)
+	case BlendDifference:
+		return math.Abs(cb - cs)
+	case BlendAdd:
+		return math.Min(1, cb+cs)
+	default:
+		return cs
+	}
+}
+
+// Luminance возвращает относительную яркость по WCAG от 0 (чёрный) до 1 (белый)
+func (c Color) Luminance() float64 {
+	r, g, b := c.linear()
+	return 0.2126*r + 0.7152*g + 0.0722*b
+}
+
+// ContrastRatio возвращает контрастность по WCAG от 1 до 21
+func (c Color) ContrastRatio(other Color) float64 {
+	l1, l2 := c.Luminance(), other.Luminance()
+	if l1 < l2 {
+		l1, l2 = l2, l1
+	}
+	return (l1 + 0.05) / (l2 + 0.05)
+}
+
+// IsReadableOn проверяет, что текст цвета c читаем на фоне background
+func (c Color) IsReadableOn(background Color, minimum float64) bool {
+	return c.ContrastRatio(background) >= minimum
+}
+
+func (c Color) linear() (float64, float64, float64) {
+	toLinear := func(channel byte) float64 {
+		v := float64(channel) / 255
+		if v <= 0.04045 {
+			return v / 12.92
+		}
+		return math.Pow((v+0.055)/1.055, 2.4)
+	}
+	return toLinear(c.Red), toLinear(c.Green), toLinear(c.Blue)
+}
+
+// белая точка D65
+const (
+	whiteX = 0.95047
+	whiteY = 1.0
+	whiteZ = 1.08883
+	labE   = 6.0 / 29
+)
+
+func (c Color) toLab() (float64, float64, float64) {
+	r, g, b := c.linear()
+	x := (0.4124*r + 0.3576*g + 0.1805*b) / whiteX
+	y := (0.2126*r + 0.7152*g + 0.0722*b) / whiteY
+	z := (0.0193*r + 0.1192*g + 0.9505*b) / whiteZ
+
+	f := func(t float64) float64 {
+		if t > labE*labE*labE {
+			return math.Cbrt(t)
+		}
+		return t/(3*labE*labE) + 4.0/29
+	}
+
+	return 116*f(y) - 16, 500 * (f(x) - f(y)), 200 * (f(y) - f(z))
+}
+
+func fromLab(l, a, b float64) Color {
+	fy := (l + 16) / 116
+	finv := func(t float64) float64 {
+		if t > labE {
+			return t * t * t
+		}
+		return 3 * labE * labE * (t - 4.0/29)
+	}
+	x := whiteX * finv(fy+a/500)
+	y := whiteY * finv(fy)
+	z := whiteZ * finv(fy-b/200)
+
+	toSRGB := func(v float64) byte {
+		if v <= 0.0031308 {
+			return clampByte(12.92 * v * 255)
+		}
+		return clampByte((1.055*math.Pow(v, 1/2.4) - 0.055) * 255)
+	}
+
+	return Color{
+		Red:   toSRGB(3.2406*x - 1.5372*y - 0.4986*z),
+		Green: toSRGB(-0.9689*x + 1.8758*y + 0.0415*z),
+		Blue:  toSRGB(0.0557*x - 0.2040*y + 1.0570*z),
+	}
+}
+
+// NamedColor ищет цвет по имени CSS, например "rebeccapurple"
+func NamedColor(name string) (Color, bool) {
+	rgb, ok := namedColors[strings.ToLower(name)]
+	if !ok {
+		return Color{}, false
+	}
+
+	return Color{Red: byte(rgb >> 16), Green: byte(rgb >> 8), Blue: byte(rgb)}, true
+}
+
+// Is проверяет, что цвет совпадает с именованным цветом CSS: c.Is("red")
+func (c Color) Is(name string) bool {
+	named, ok := NamedColor(name)
+	return ok && c.EqualTo(named)
+}
+
+// Name возвращает имя цвета CSS; для синонимов (aqua и cyan) - первое по алфавиту
+func (c Color) Name() (string, bool) {
+	if c.Transparency != 0 {
+		return "", false
+	}
+
+	var names []string
+	for name := range namedColors {
+		if c.Is(name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "", false
+	}
+	sort.Strings(names)
+
+	return names[0], true
+}
+
+func clampByte(value float64) byte {
+	return byte(math.Round(math.Max(0, math.Min(255, value))))
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(math.Round(value*1000)/1000, 'f', -1, 64)
+}
+
+// namedColors - именованные цвета CSS Color Module Level 4
+var namedColors = map[string]uint32{
+	"aliceblue": 0xf0f8ff, "antiquewhite": 0xfaebd7, "aqua": 0x00ffff,
+	"aquamarine": 0x7fffd4, "azure": 0xf0ffff, "beige": 0xf5f5dc, "bisque": 0xffe4c4,
+	"black": 0x000000, "blanchedalmond": 0xffebcd, "blue": 0x0000ff, "blueviolet": 0x8a2be2,
+	"brown": 0xa52a2a, "burlywood": 0xdeb887, "cadetblue": 0x5f9ea0, "chartreuse": 0x7fff00,
+	"chocolate": 0xd2691e, "coral": 0xff7f50, "cornflowerblue": 0x6495ed,
+	"cornsilk": 0xfff8dc, "crimson": 0xdc143c, "cyan": 0x00ffff, "darkblue": 0x00008b,
+	"darkcyan": 0x008b8b, "darkgoldenrod": 0xb8860b, "darkgray": 0xa9a9a9,
+	"darkgreen": 0x006400, "darkgrey": 0xa9a9a9, "darkkhaki": 0xbdb76b,
+	"darkmagenta": 0x8b008b, "darkolivegreen": 0x556b2f, "darkorange": 0xff8c00,
+	"darkorchid": 0x9932cc, "darkred": 0x8b0000, "darksalmon": 0xe9967a,
+	"darkseagreen": 0x8fbc8f, "darkslateblue": 0x483d8b, "darkslategray": 0x2f4f4f,
+	"darkslategrey": 0x2f4f4f, "darkturquoise": 0x00ced1, "darkviolet": 0x9400d3,
+	"deeppink": 0xff1493, "deepskyblue": 0x00bfff, "dimgray": 0x696969, "dimgrey": 0x696969,
+	"dodgerblue": 0x1e90ff, "firebrick": 0xb22222, "floralwhite": 0xfffaf0,
+	"forestgreen": 0x228b22, "fuchsia": 0xff00ff, "gainsboro": 0xdcdcdc,
+	"ghostwhite": 0xf8f8ff, "gold": 0xffd700, "goldenrod": 0xdaa520, "gray": 0x808080,
+	"green": 0x008000, "greenyellow": 0xadff2f, "grey": 0x808080, "honeydew": 0xf0fff0,
+	"hotpink": 0xff69b4, "indianred": 0xcd5c5c, "indigo": 0x4b0082, "ivory": 0xfffff0,
+	"khaki": 0xf0e68c, "lavender": 0xe6e6fa, "lavenderblush": 0xfff0f5,
+	"lawngreen": 0x7cfc00, "lemonchiffon": 0xfffacd, "lightblue": 0xadd8e6,
+	"lightcoral": 0xf08080, "lightcyan": 0xe0ffff, "lightgoldenrodyellow": 0xfafad2,
+	"lightgray": 0xd3d3d3, "lightgreen": 0x90ee90, "lightgrey": 0xd3d3d3,
+	"lightpink": 0xffb6c1, "lightsalmon": 0xffa07a, "lightseagreen": 0x20b2aa,
+	"lightskyblue": 0x87cefa, "lightslategray": 0x778899, "lightslategrey": 0x778899,
+	"lightsteelblue": 0xb0c4de, "lightyellow": 0xffffe0, "lime": 0x00ff00,
+	"limegreen": 0x32cd32, "linen": 0xfaf0e6, "magenta": 0xff00ff, "maroon": 0x800000,
+	"mediumaquamarine": 0x66cdaa, "mediumblue": 0x0000cd, "mediumorchid": 0xba55d3,
+	"mediumpurple": 0x9370db, "mediumseagreen": 0x3cb371, "mediumslateblue": 0x7b68ee,
+	"mediumspringgreen": 0x00fa9a, "mediumturquoise": 0x48d1cc, "mediumvioletred": 0xc71585,
+	"midnightblue": 0x191970, "mintcream": 0xf5fffa, "mistyrose": 0xffe4e1,
+	"moccasin": 0xffe4b5, "navajowhite": 0xffdead, "navy": 0x000080, "oldlace": 0xfdf5e6,
+	"olive": 0x808000, "olivedrab": 0x6b8e23, "orange": 0xffa500, "orangered": 0xff4500,
+	"orchid": 0xda70d6, "palegoldenrod": 0xeee8aa, "palegreen": 0x98fb98,
+	"paleturquoise": 0xafeeee, "palevioletred": 0xdb7093, "papayawhip": 0xffefd5,
+	"peachpuff": 0xffdab9, "peru": 0xcd853f, "pink": 0xffc0cb, "plum": 0xdda0dd,
+	"powderblue": 0xb0e0e6, "purple": 0x800080, "rebeccapurple": 0x663399, "red": 0xff0000,
+	"rosybrown": 0xbc8f8f, "royalblue": 0x4169e1, "saddlebrown": 0x8b4513,
+	"salmon": 0xfa8072, "sandybrown": 0xf4a460, "seagreen": 0x2e8b57, "seashell": 0xfff5ee,
+	"sienna": 0xa0522d, "silver": 0xc0c0c0, "skyblue": 0x87ceeb, "slateblue": 0x6a5acd,
+	"slategray": 0x708090, "slategrey": 0x708090, "snow": 0xfffafa, "springgreen": 0x00ff7f,
+	"steelblue": 0x4682b4, "tan": 0xd2b48c, "teal": 0x008080, "thistle": 0xd8bfd8,
+	"tomato": 0xff6347, "turquoise": 0x40e0d0, "violet": 0xee82ee, "wheat": 0xf5deb3,
+	"white": 0xffffff, "whitesmoke": 0xf5f5f5, "yellow": 0xffff00, "yellowgreen": 0x9acd32,
+}
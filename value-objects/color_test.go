@@ -0,0 +1,210 @@
+package value_objects_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	value_objects "github.com/MaksimDzhangirov/PracticalDDD/value-objects"
+)
+
+var (
+	red   = value_objects.Color{Red: 255}
+	white = value_objects.Color{Red: 255, Green: 255, Blue: 255}
+	black = value_objects.Color{}
+)
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		input string
+		want  value_objects.Color
+	}{
+		{"#f00", red},
+		{"#FF0000", red},
+		{"#ff000080", value_objects.Color{Red: 255, Transparency: 127}},
+		{"#f008", value_objects.Color{Red: 255, Transparency: 119}},
+		{"rgb(255, 0, 0)", red},
+		{"rgb(100%, 0%, 0%)", red},
+		{"rgba(255 0 0 / 50%)", value_objects.Color{Red: 255, Transparency: 127}},
+		{"rgba(255, 0, 0, 0)", value_objects.Color{Red: 255, Transparency: 255}},
+		{"hsl(120, 100%, 50%)", value_objects.Color{Green: 255}},
+		{"hsla(240deg 100% 50% / 1)", value_objects.Color{Blue: 255}},
+		{" RebeccaPurple ", value_objects.Color{Red: 102, Green: 51, Blue: 153}},
+		{"transparent", value_objects.Color{Transparency: 255}},
+	}
+	for _, test := range tests {
+		color, err := value_objects.ParseColor(test.input)
+		if err != nil {
+			t.Fatalf("ParseColor(%q): %v", test.input, err)
+		}
+		if color != test.want {
+			t.Fatalf("ParseColor(%q) = %+v, want %+v", test.input, color, test.want)
+		}
+	}
+
+	for _, input := range []string{"", "#ff", "#gggggg", "rgb(256, 0, 0)", "rgb(1, 2)", "rgb(1, 2, 3", "rgba(1, 2, 3, 2)", "hsl(0, 1, 1)", "notacolor"} {
+		if _, err := value_objects.ParseColor(input); !errors.Is(err, value_objects.ErrInvalidColor) {
+			t.Fatalf("ParseColor(%q) returned %v", input, err)
+		}
+	}
+}
+
+func TestColorCSSOutput(t *testing.T) {
+	translucent := red.WithAlpha(0.5)
+
+	tests := []struct {
+		got, want string
+	}{
+		{red.ToCSS(), "rgb(255, 0, 0)"},
+		{translucent.ToCSS(), "rgba(255, 0, 0, 0.502)"},
+		{red.ToHex(), "#ff0000"},
+		{translucent.ToHex(), "#ff000080"},
+		{red.ToCSSHSL(), "hsl(0, 100%, 50%)"},
+		{value_objects.Color{Red: 102, Green: 51, Blue: 153}.ToCSSHSL(), "hsl(270, 50%, 40%)"},
+		{translucent.ToCSSHSL(), "hsla(0, 100%, 50%, 0.502)"},
+	}
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Fatalf("got %q, want %q", test.got, test.want)
+		}
+	}
+
+	// вывод разбирается обратно в тот же цвет
+	for _, output := range []string{translucent.ToCSS(), translucent.ToHex()} {
+		parsed, err := value_objects.ParseColor(output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed != translucent {
+			t.Fatalf("%q parsed as %+v, want %+v", output, parsed, translucent)
+		}
+	}
+}
+
+func TestColorHSLAndHSVRoundTrip(t *testing.T) {
+	for r := 0; r < 256; r += 15 {
+		for g := 0; g < 256; g += 15 {
+			for b := 0; b < 256; b += 15 {
+				color := value_objects.Color{Red: byte(r), Green: byte(g), Blue: byte(b)}
+				if back := value_objects.FromHSL(color.ToHSL()); back != color {
+					t.Fatalf("%+v -> %+v -> %+v", color, color.ToHSL(), back)
+				}
+				if back := value_objects.FromHSV(color.ToHSV()); back != color {
+					t.Fatalf("%+v -> %+v -> %+v", color, color.ToHSV(), back)
+				}
+			}
+		}
+	}
+
+	hsv := value_objects.Color{Red: 255, Green: 128}.ToHSV()
+	if math.Abs(hsv.Hue-30.1) > 0.1 || hsv.Saturation != 1 || hsv.Value != 1 {
+		t.Fatalf("orange HSV = %+v", hsv)
+	}
+}
+
+func TestColorBrighterAndDarkerDoNotOverflow(t *testing.T) {
+	light := value_objects.Color{Red: 250, Green: 100, Blue: 5, Transparency: 7}
+
+	if got := light.ToBrighter(); got != (value_objects.Color{Red: 255, Green: 110, Blue: 15, Transparency: 7}) {
+		t.Fatalf("ToBrighter() = %+v", got)
+	}
+	if got := light.ToDarker(); got != (value_objects.Color{Red: 240, Green: 90, Blue: 0, Transparency: 7}) {
+		t.Fatalf("ToDarker() = %+v", got)
+	}
+	opaque := value_objects.Color{Red: 250, Green: 100, Blue: 5}
+	if got := light.Combine(opaque); got != (value_objects.Color{Red: 255, Green: 200, Blue: 10, Transparency: 7}) {
+		t.Fatalf("Combine() = %+v", got)
+	}
+}
+
+func TestColorLightenAndDarken(t *testing.T) {
+	blue := value_objects.Color{Blue: 255}
+	gray := value_objects.Color{Red: 128, Green: 128, Blue: 128}
+
+	if got := blue.Lighten(0); got != blue {
+		t.Fatalf("Lighten(0) = %+v", got)
+	}
+	// у серого нет цветности, поэтому края шкалы светлоты - белый и чёрный
+	if got := gray.Lighten(100); got != white {
+		t.Fatalf("Lighten(100) = %+v, want white", got)
+	}
+	if got := gray.Darken(100); got != black {
+		t.Fatalf("Darken(100) = %+v, want black", got)
+	}
+	if blue.Lighten(20).Luminance() <= blue.Luminance() || blue.Darken(20).Luminance() >= blue.Luminance() {
+		t.Fatal("Lighten and Darken do not change luminance")
+	}
+	if got := red.WithAlpha(0.5).Darken(10); got.Transparency != red.WithAlpha(0.5).Transparency {
+		t.Fatalf("Darken changed transparency: %+v", got)
+	}
+}
+
+func TestColorBlend(t *testing.T) {
+	gray := value_objects.Color{Red: 128, Green: 128, Blue: 128}
+
+	tests := []struct {
+		mode value_objects.BlendMode
+		with value_objects.Color
+		want value_objects.Color
+	}{
+		{value_objects.BlendNormal, red, red},
+		{value_objects.BlendNormal, red.WithAlpha(0), gray},
+		{value_objects.BlendMultiply, white, gray},
+		{value_objects.BlendMultiply, black, black},
+		{value_objects.BlendScreen, black, gray},
+		{value_objects.BlendScreen, white, white},
+		{value_objects.BlendDarken, red, value_objects.Color{Red: 128}},
+		{value_objects.BlendLighten, red, value_objects.Color{Red: 255, Green: 128, Blue: 128}},
+		{value_objects.BlendDifference, gray, black},
+		{value_objects.BlendAdd, gray, white},
+	}
+	for _, test := range tests {
+		if got := gray.Blend(test.with, test.mode); got != test.want {
+			t.Fatalf("Blend(%+v, %d) = %+v, want %+v", test.with, test.mode, got, test.want)
+		}
+	}
+
+	// наполовину прозрачный чёрный поверх белого даёт серый
+	if got := white.Blend(black.WithAlpha(0.5), value_objects.BlendNormal); got.Red < 127 || got.Red > 128 {
+		t.Fatalf("half transparent black over white = %+v", got)
+	}
+}
+
+func TestColorContrast(t *testing.T) {
+	if ratio := black.ContrastRatio(white); math.Abs(ratio-21) > 1e-9 {
+		t.Fatalf("black on white = %v, want 21", ratio)
+	}
+	if ratio := red.ContrastRatio(red); ratio != 1 {
+		t.Fatalf("red on red = %v, want 1", ratio)
+	}
+	if ratio := white.ContrastRatio(black); math.Abs(ratio-black.ContrastRatio(white)) > 1e-9 {
+		t.Fatal("contrast ratio is not symmetric")
+	}
+
+	// #767676 - самый светлый серый с контрастом 4.5 на белом
+	gray := value_objects.Color{Red: 0x76, Green: 0x76, Blue: 0x76}
+	if !gray.IsReadableOn(white, value_objects.ContrastAA) {
+		t.Fatalf("#767676 on white = %v", gray.ContrastRatio(white))
+	}
+	lighter := value_objects.Color{Red: 0x77, Green: 0x77, Blue: 0x77}
+	if lighter.IsReadableOn(white, value_objects.ContrastAA) {
+		t.Fatalf("#777777 on white = %v", lighter.ContrastRatio(white))
+	}
+}
+
+func TestNamedColors(t *testing.T) {
+	if !red.Is("red") || !red.Is("Red") || red.Is("blue") || red.Is("unknown") {
+		t.Fatal("Is does not match named colors")
+	}
+
+	// у синонимов aqua и cyan возвращается первое имя по алфавиту
+	if name, ok := (value_objects.Color{Green: 255, Blue: 255}).Name(); !ok || name != "aqua" {
+		t.Fatalf("Name() = %q, %v", name, ok)
+	}
+	if _, ok := red.WithAlpha(0.5).Name(); ok {
+		t.Fatal("translucent color has a name")
+	}
+	if _, ok := (value_objects.Color{Red: 1, Green: 2, Blue: 3}).Name(); ok {
+		t.Fatal("arbitrary color has a name")
+	}
+}
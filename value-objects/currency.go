@@ -0,0 +1,336 @@
+package value_objects
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+var (
+	ErrUnknownCurrency = errors.New("unknown currency")
+	ErrCurrencyExists  = errors.New("currency is already registered")
+	ErrInvalidCurrency = errors.New("currency must have a three or more letter code and non-negative scale")
+)
+
+// CurrencyComparison определяет, как Currency.EqualTo сравнивает валюты
+type CurrencyComparison int32
+
+const (
+	// CompareByID сравнивает идентификаторы (если они заданы у обеих валют)
+	CompareByID CurrencyComparison = iota
+	// CompareByCode сравнивает коды, например "EUR"
+	CompareByCode
+)
+
+var comparison int32
+
+// SetCurrencyComparison задаёт способ сравнения валют для всего приложения
+func SetCurrencyComparison(mode CurrencyComparison) {
+	atomic.StoreInt32(&comparison, int32(mode))
+}
+
+func currencyComparison() CurrencyComparison {
+	return CurrencyComparison(atomic.LoadInt32(&comparison))
+}
+
+// CurrencyRegistry - справочник валют. Валюты ISO 4217 встроены и не могут
+// быть изменены, пользовательские (криптовалюты, бонусные баллы) добавляются через Register
+type CurrencyRegistry struct {
+	mutex     sync.RWMutex
+	byCode    map[string]Currency
+	byNumeric map[int]Currency
+}
+
+// NewCurrencyRegistry создаёт справочник со всеми валютами ISO 4217
+func NewCurrencyRegistry() *CurrencyRegistry {
+	registry := &CurrencyRegistry{
+		byCode:    make(map[string]Currency, len(iso4217)),
+		byNumeric: make(map[int]Currency, len(iso4217)),
+	}
+	for _, currency := range iso4217 {
+		currency = withHTML(currency)
+		registry.byCode[currency.Code] = currency
+		registry.byNumeric[currency.Numeric] = currency
+	}
+
+	return registry
+}
+
+// Register добавляет пользовательскую валюту. Numeric можно не указывать
+func (r *CurrencyRegistry) Register(currency Currency) error {
+	currency.Code = strings.ToUpper(currency.Code)
+	if len(currency.Code) < 3 || currency.Scale < 0 {
+		return ErrInvalidCurrency
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.byCode[currency.Code]; ok {
+		return fmt.Errorf("%w: %s", ErrCurrencyExists, currency.Code)
+	}
+	if _, ok := r.byNumeric[currency.Numeric]; ok && currency.Numeric != 0 {
+		return fmt.Errorf("%w: %03d", ErrCurrencyExists, currency.Numeric)
+	}
+
+	currency = withHTML(currency)
+	r.byCode[currency.Code] = currency
+	if currency.Numeric != 0 {
+		r.byNumeric[currency.Numeric] = currency
+	}
+
+	return nil
+}
+
+// ByCode ищет валюту по буквенному коду, например "EUR"
+func (r *CurrencyRegistry) ByCode(code string) (Currency, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	currency, ok := r.byCode[strings.ToUpper(code)]
+	if !ok {
+		return Currency{}, fmt.Errorf("%w: %s", ErrUnknownCurrency, code)
+	}
+
+	return currency, nil
+}
+
+// ByNumeric ищет валюту по цифровому коду, например 978 для EUR
+func (r *CurrencyRegistry) ByNumeric(numeric int) (Currency, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	currency, ok := r.byNumeric[numeric]
+	if !ok {
+		return Currency{}, fmt.Errorf("%w: %03d", ErrUnknownCurrency, numeric)
+	}
+
+	return currency, nil
+}
+
+// BySymbol ищет валюты по символу, например "$". Одному символу может
+// соответствовать несколько валют ("kr" - SEK, NOK, DKK и ISK)
+func (r *CurrencyRegistry) BySymbol(symbol string) []Currency {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var result []Currency
+	for _, currency := range r.byCode {
+		if currency.Symbol == symbol {
+			result = append(result, currency)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Code < result[j].Code
+	})
+
+	return result
+}
+
+// All возвращает все зарегистрированные валюты
+func (r *CurrencyRegistry) All() []Currency {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	result := make([]Currency, 0, len(r.byCode))
+	for _, currency := range r.byCode {
+		result = append(result, currency)
+	}
+
+	return result
+}
+
+// Currencies - справочник валют по умолчанию
+var Currencies = NewCurrencyRegistry()
+
+// CurrencyByCode ищет валюту в справочнике по умолчанию
+func CurrencyByCode(code string) (Currency, error) {
+	return Currencies.ByCode(code)
+}
+
+// CurrencyByNumeric ищет валюту в справочнике по умолчанию
+func CurrencyByNumeric(numeric int) (Currency, error) {
+	return Currencies.ByNumeric(numeric)
+}
+
+// RegisterCurrency добавляет пользовательскую валюту в справочник по умолчанию
+func RegisterCurrency(currency Currency) error {
+	return Currencies.Register(currency)
+}
+
+// withHTML заполняет символ и код HTML-сущности, если они не заданы
+func withHTML(currency Currency) Currency {
+	if currency.Symbol == "" {
+		currency.Symbol = currency.Code
+	}
+	if currency.HTML == 0 && utf8.RuneCountInString(currency.Symbol) == 1 {
+		symbol, _ := utf8.DecodeRuneInString(currency.Symbol)
+		currency.HTML = int(symbol)
+	}
+
+	return currency
+}
+
+// iso4217 - действующие валюты ISO 4217
+var iso4217 = []Currency{
+	{Code: "AED", Numeric: 784, Scale: 2, Name: "UAE Dirham"},
+	{Code: "AFN", Numeric: 971, Scale: 2, Name: "Afghani"},
+	{Code: "ALL", Numeric: 8, Scale: 2, Name: "Lek"},
+	{Code: "AMD", Numeric: 51, Scale: 2, Name: "Armenian Dram"},
+	{Code: "ANG", Numeric: 532, Scale: 2, Name: "Netherlands Antillean Guilder"},
+	{Code: "AOA", Numeric: 973, Scale: 2, Name: "Kwanza"},
+	{Code: "ARS", Numeric: 32, Scale: 2, Name: "Argentine Peso"},
+	{Code: "AUD", Numeric: 36, Scale: 2, Name: "Australian Dollar", Symbol: "A$"},
+	{Code: "AWG", Numeric: 533, Scale: 2, Name: "Aruban Florin"},
+	{Code: "AZN", Numeric: 944, Scale: 2, Name: "Azerbaijan Manat", Symbol: "₼"},
+	{Code: "BAM", Numeric: 977, Scale: 2, Name: "Convertible Mark"},
+	{Code: "BBD", Numeric: 52, Scale: 2, Name: "Barbados Dollar"},
+	{Code: "BDT", Numeric: 50, Scale: 2, Name: "Taka"},
+	{Code: "BGN", Numeric: 975, Scale: 2, Name: "Bulgarian Lev"},
+	{Code: "BHD", Numeric: 48, Scale: 3, Name: "Bahraini Dinar"},
+	{Code: "BIF", Numeric: 108, Scale: 0, Name: "Burundi Franc"},
+	{Code: "BMD", Numeric: 60, Scale: 2, Name: "Bermudian Dollar"},
+	{Code: "BND", Numeric: 96, Scale: 2, Name: "Brunei Dollar"},
+	{Code: "BOB", Numeric: 68, Scale: 2, Name: "Boliviano"},
+	{Code: "BRL", Numeric: 986, Scale: 2, Name: "Brazilian Real", Symbol: "R$"},
+	{Code: "BSD", Numeric: 44, Scale: 2, Name: "Bahamian Dollar"},
+	{Code: "BTN", Numeric: 64, Scale: 2, Name: "Ngultrum"},
+	{Code: "BWP", Numeric: 72, Scale: 2, Name: "Pula"},
+	{Code: "BYN", Numeric: 933, Scale: 2, Name: "Belarusian Ruble", Symbol: "Br"},
+	{Code: "BZD", Numeric: 84, Scale: 2, Name: "Belize Dollar"},
+	{Code: "CAD", Numeric: 124, Scale: 2, Name: "Canadian Dollar", Symbol: "CA$"},
+	{Code: "CDF", Numeric: 976, Scale: 2, Name: "Congolese Franc"},
+	{Code: "CHF", Numeric: 756, Scale: 2, Name: "Swiss Franc", Symbol: "CHF"},
+	{Code: "CLF", Numeric: 990, Scale: 4, Name: "Unidad de Fomento"},
+	{Code: "CLP", Numeric: 152, Scale: 0, Name: "Chilean Peso"},
+	{Code: "CNY", Numeric: 156, Scale: 2, Name: "Yuan Renminbi", Symbol: "¥"},
+	{Code: "COP", Numeric: 170, Scale: 2, Name: "Colombian Peso"},
+	{Code: "CRC", Numeric: 188, Scale: 2, Name: "Costa Rican Colon", Symbol: "₡"},
+	{Code: "CUP", Numeric: 192, Scale: 2, Name: "Cuban Peso"},
+	{Code: "CVE", Numeric: 132, Scale: 2, Name: "Cabo Verde Escudo"},
+	{Code: "CZK", Numeric: 203, Scale: 2, Name: "Czech Koruna", Symbol: "Kč"},
+	{Code: "DJF", Numeric: 262, Scale: 0, Name: "Djibouti Franc"},
+	{Code: "DKK", Numeric: 208, Scale: 2, Name: "Danish Krone", Symbol: "kr"},
+	{Code: "DOP", Numeric: 214, Scale: 2, Name: "Dominican Peso"},
+	{Code: "DZD", Numeric: 12, Scale: 2, Name: "Algerian Dinar"},
+	{Code: "EGP", Numeric: 818, Scale: 2, Name: "Egyptian Pound"},
+	{Code: "ERN", Numeric: 232, Scale: 2, Name: "Nakfa"},
+	{Code: "ETB", Numeric: 230, Scale: 2, Name: "Ethiopian Birr"},
+	{Code: "EUR", Numeric: 978, Scale: 2, Name: "Euro", Symbol: "€"},
+	{Code: "FJD", Numeric: 242, Scale: 2, Name: "Fiji Dollar"},
+	{Code: "FKP", Numeric: 238, Scale: 2, Name: "Falkland Islands Pound"},
+	{Code: "GBP", Numeric: 826, Scale: 2, Name: "Pound Sterling", Symbol: "£"},
+	{Code: "GEL", Numeric: 981, Scale: 2, Name: "Lari", Symbol: "₾"},
+	{Code: "GHS", Numeric: 936, Scale: 2, Name: "Ghana Cedi", Symbol: "₵"},
+	{Code: "GIP", Numeric: 292, Scale: 2, Name: "Gibraltar Pound"},
+	{Code: "GMD", Numeric: 270, Scale: 2, Name: "Dalasi"},
+	{Code: "GNF", Numeric: 324, Scale: 0, Name: "Guinean Franc"},
+	{Code: "GTQ", Numeric: 320, Scale: 2, Name: "Quetzal"},
+	{Code: "GYD", Numeric: 328, Scale: 2, Name: "Guyana Dollar"},
+	{Code: "HKD", Numeric: 344, Scale: 2, Name: "Hong Kong Dollar", Symbol: "HK$"},
+	{Code: "HNL", Numeric: 340, Scale: 2, Name: "Lempira"},
+	{Code: "HTG", Numeric: 332, Scale: 2, Name: "Gourde"},
+	{Code: "HUF", Numeric: 348, Scale: 2, Name: "Forint", Symbol: "Ft"},
+	{Code: "IDR", Numeric: 360, Scale: 2, Name: "Rupiah"},
+	{Code: "ILS", Numeric: 376, Scale: 2, Name: "New Israeli Sheqel", Symbol: "₪"},
+	{Code: "INR", Numeric: 356, Scale: 2, Name: "Indian Rupee", Symbol: "₹"},
+	{Code: "IQD", Numeric: 368, Scale: 3, Name: "Iraqi Dinar"},
+	{Code: "IRR", Numeric: 364, Scale: 2, Name: "Iranian Rial"},
+	{Code: "ISK", Numeric: 352, Scale: 0, Name: "Iceland Krona", Symbol: "kr"},
+	{Code: "JMD", Numeric: 388, Scale: 2, Name: "Jamaican Dollar"},
+	{Code: "JOD", Numeric: 400, Scale: 3, Name: "Jordanian Dinar"},
+	{Code: "JPY", Numeric: 392, Scale: 0, Name: "Yen", Symbol: "¥"},
+	{Code: "KES", Numeric: 404, Scale: 2, Name: "Kenyan Shilling"},
+	{Code: "KGS", Numeric: 417, Scale: 2, Name: "Som"},
+	{Code: "KHR", Numeric: 116, Scale: 2, Name: "Riel", Symbol: "៛"},
+	{Code: "KMF", Numeric: 174, Scale: 0, Name: "Comorian Franc"},
+	{Code: "KPW", Numeric: 408, Scale: 2, Name: "North Korean Won"},
+	{Code: "KRW", Numeric: 410, Scale: 0, Name: "Won", Symbol: "₩"},
+	{Code: "KWD", Numeric: 414, Scale: 3, Name: "Kuwaiti Dinar"},
+	{Code: "KYD", Numeric: 136, Scale: 2, Name: "Cayman Islands Dollar"},
+	{Code: "KZT", Numeric: 398, Scale: 2, Name: "Tenge", Symbol: "₸"},
+	{Code: "LAK", Numeric: 418, Scale: 2, Name: "Lao Kip", Symbol: "₭"},
+	{Code: "LBP", Numeric: 422, Scale: 2, Name: "Lebanese Pound"},
+	{Code: "LKR", Numeric: 144, Scale: 2, Name: "Sri Lanka Rupee"},
+	{Code: "LRD", Numeric: 430, Scale: 2, Name: "Liberian Dollar"},
+	{Code: "LSL", Numeric: 426, Scale: 2, Name: "Loti"},
+	{Code: "LYD", Numeric: 434, Scale: 3, Name: "Libyan Dinar"},
+	{Code: "MAD", Numeric: 504, Scale: 2, Name: "Moroccan Dirham"},
+	{Code: "MDL", Numeric: 498, Scale: 2, Name: "Moldovan Leu"},
+	{Code: "MGA", Numeric: 969, Scale: 2, Name: "Malagasy Ariary"},
+	{Code: "MKD", Numeric: 807, Scale: 2, Name: "Denar"},
+	{Code: "MMK", Numeric: 104, Scale: 2, Name: "Kyat"},
+	{Code: "MNT", Numeric: 496, Scale: 2, Name: "Tugrik", Symbol: "₮"},
+	{Code: "MOP", Numeric: 446, Scale: 2, Name: "Pataca"},
+	{Code: "MRU", Numeric: 929, Scale: 2, Name: "Ouguiya"},
+	{Code: "MUR", Numeric: 480, Scale: 2, Name: "Mauritius Rupee"},
+	{Code: "MVR", Numeric: 462, Scale: 2, Name: "Rufiyaa"},
+	{Code: "MWK", Numeric: 454, Scale: 2, Name: "Malawi Kwacha"},
+	{Code: "MXN", Numeric: 484, Scale: 2, Name: "Mexican Peso", Symbol: "MX$"},
+	{Code: "MYR", Numeric: 458, Scale: 2, Name: "Malaysian Ringgit"},
+	{Code: "MZN", Numeric: 943, Scale: 2, Name: "Mozambique Metical"},
+	{Code: "NAD", Numeric: 516, Scale: 2, Name: "Namibia Dollar"},
+	{Code: "NGN", Numeric: 566, Scale: 2, Name: "Naira", Symbol: "₦"},
+	{Code: "NIO", Numeric: 558, Scale: 2, Name: "Cordoba Oro"},
+	{Code: "NOK", Numeric: 578, Scale: 2, Name: "Norwegian Krone", Symbol: "kr"},
+	{Code: "NPR", Numeric: 524, Scale: 2, Name: "Nepalese Rupee"},
+	{Code: "NZD", Numeric: 554, Scale: 2, Name: "New Zealand Dollar", Symbol: "NZ$"},
+	{Code: "OMR", Numeric: 512, Scale: 3, Name: "Rial Omani"},
+	{Code: "PAB", Numeric: 590, Scale: 2, Name: "Balboa"},
+	{Code: "PEN", Numeric: 604, Scale: 2, Name: "Sol"},
+	{Code: "PGK", Numeric: 598, Scale: 2, Name: "Kina"},
+	{Code: "PHP", Numeric: 608, Scale: 2, Name: "Philippine Peso", Symbol: "₱"},
+	{Code: "PKR", Numeric: 586, Scale: 2, Name: "Pakistan Rupee"},
+	{Code: "PLN", Numeric: 985, Scale: 2, Name: "Zloty", Symbol: "zł"},
+	{Code: "PYG", Numeric: 600, Scale: 0, Name: "Guarani", Symbol: "₲"},
+	{Code: "QAR", Numeric: 634, Scale: 2, Name: "Qatari Rial"},
+	{Code: "RON", Numeric: 946, Scale: 2, Name: "Romanian Leu"},
+	{Code: "RSD", Numeric: 941, Scale: 2, Name: "Serbian Dinar"},
+	{Code: "RUB", Numeric: 643, Scale: 2, Name: "Russian Ruble", Symbol: "₽"},
+	{Code: "RWF", Numeric: 646, Scale: 0, Name: "Rwanda Franc"},
+	{Code: "SAR", Numeric: 682, Scale: 2, Name: "Saudi Riyal"},
+	{Code: "SBD", Numeric: 90, Scale: 2, Name: "Solomon Islands Dollar"},
+	{Code: "SCR", Numeric: 690, Scale: 2, Name: "Seychelles Rupee"},
+	{Code: "SDG", Numeric: 938, Scale: 2, Name: "Sudanese Pound"},
+	{Code: "SEK", Numeric: 752, Scale: 2, Name: "Swedish Krona", Symbol: "kr"},
+	{Code: "SGD", Numeric: 702, Scale: 2, Name: "Singapore Dollar", Symbol: "S$"},
+	{Code: "SHP", Numeric: 654, Scale: 2, Name: "Saint Helena Pound"},
+	{Code: "SLE", Numeric: 925, Scale: 2, Name: "Leone"},
+	{Code: "SOS", Numeric: 706, Scale: 2, Name: "Somali Shilling"},
+	{Code: "SRD", Numeric: 968, Scale: 2, Name: "Surinam Dollar"},
+	{Code: "SSP", Numeric: 728, Scale: 2, Name: "South Sudanese Pound"},
+	{Code: "STN", Numeric: 930, Scale: 2, Name: "Dobra"},
+	{Code: "SVC", Numeric: 222, Scale: 2, Name: "El Salvador Colon"},
+	{Code: "SYP", Numeric: 760, Scale: 2, Name: "Syrian Pound"},
+	{Code: "SZL", Numeric: 748, Scale: 2, Name: "Lilangeni"},
+	{Code: "THB", Numeric: 764, Scale: 2, Name: "Baht", Symbol: "฿"},
+	{Code: "TJS", Numeric: 972, Scale: 2, Name: "Somoni"},
+	{Code: "TMT", Numeric: 934, Scale: 2, Name: "Turkmenistan New Manat"},
+	{Code: "TND", Numeric: 788, Scale: 3, Name: "Tunisian Dinar"},
+	{Code: "TOP", Numeric: 776, Scale: 2, Name: "Pa'anga"},
+	{Code: "TRY", Numeric: 949, Scale: 2, Name: "Turkish Lira", Symbol: "₺"},
+	{Code: "TTD", Numeric: 780, Scale: 2, Name: "Trinidad and Tobago Dollar"},
+	{Code: "TWD", Numeric: 901, Scale: 2, Name: "New Taiwan Dollar"},
+	{Code: "TZS", Numeric: 834, Scale: 2, Name: "Tanzanian Shilling"},
+	{Code: "UAH", Numeric: 980, Scale: 2, Name: "Hryvnia", Symbol: "₴"},
+	{Code: "UGX", Numeric: 800, Scale: 0, Name: "Uganda Shilling"},
+	{Code: "USD", Numeric: 840, Scale: 2, Name: "US Dollar", Symbol: "$"},
+	{Code: "UYU", Numeric: 858, Scale: 2, Name: "Peso Uruguayo"},
+	{Code: "UYW", Numeric: 927, Scale: 4, Name: "Unidad Previsional"},
+	{Code: "UZS", Numeric: 860, Scale: 2, Name: "Uzbekistan Sum"},
+	{Code: "VES", Numeric: 928, Scale: 2, Name: "Bolivar Soberano"},
+	{Code: "VND", Numeric: 704, Scale: 0, Name: "Dong", Symbol: "₫"},
+	{Code: "VUV", Numeric: 548, Scale: 0, Name: "Vatu"},
+	{Code: "WST", Numeric: 882, Scale: 2, Name: "Tala"},
+	{Code: "XAF", Numeric: 950, Scale: 0, Name: "CFA Franc BEAC"},
+	{Code: "XCD", Numeric: 951, Scale: 2, Name: "East Caribbean Dollar"},
+	{Code: "XOF", Numeric: 952, Scale: 0, Name: "CFA Franc BCEAO"},
+	{Code: "XPF", Numeric: 953, Scale: 0, Name: "CFP Franc"},
+	{Code: "YER", Numeric: 886, Scale: 2, Name: "Yemeni Rial"},
+	{Code: "ZAR", Numeric: 710, Scale: 2, Name: "Rand", Symbol: "R"},
+	{Code: "ZMW", Numeric: 967, Scale: 2, Name: "Zambian Kwacha"},
+	{Code: "ZWG", Numeric: 924, Scale: 2, Name: "Zimbabwe Gold"}}
@@ -0,0 +1,112 @@
+package value_objects_test
+
+import (
+	"errors"
+	"testing"
+
+	value_objects "github.com/MaksimDzhangirov/PracticalDDD/value-objects"
+	"github.com/google/uuid"
+)
+
+func TestCurrencyRegistryLookups(t *testing.T) {
+	registry := value_objects.NewCurrencyRegistry()
+
+	euro, err := registry.ByCode("eur")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if euro.Code != "EUR" || euro.Numeric != 978 || euro.Scale != 2 || euro.Symbol != "€" || euro.HTML != 8364 {
+		t.Fatalf("EUR = %+v", euro)
+	}
+
+	yen, err := registry.ByNumeric(392)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if yen.Code != "JPY" || yen.Scale != 0 {
+		t.Fatalf("392 = %+v", yen)
+	}
+
+	if _, err := registry.ByCode("XYZ"); !errors.Is(err, value_objects.ErrUnknownCurrency) {
+		t.Fatalf("unknown code returned %v", err)
+	}
+	if _, err := registry.ByNumeric(1); !errors.Is(err, value_objects.ErrUnknownCurrency) {
+		t.Fatalf("unknown numeric code returned %v", err)
+	}
+}
+
+func TestCurrencyRegistryRegistersCustomCurrencies(t *testing.T) {
+	registry := value_objects.NewCurrencyRegistry()
+
+	if err := registry.Register(value_objects.Currency{Code: "btc", Scale: 8, Symbol: "₿"}); err != nil {
+		t.Fatal(err)
+	}
+	bitcoin, err := registry.ByCode("BTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bitcoin.Scale != 8 || bitcoin.HTML != int('₿') {
+		t.Fatalf("BTC = %+v", bitcoin)
+	}
+
+	// валюты ISO 4217 нельзя переопределить
+	if err := registry.Register(value_objects.Currency{Code: "EUR", Scale: 3}); !errors.Is(err, value_objects.ErrCurrencyExists) {
+		t.Fatalf("overriding EUR returned %v", err)
+	}
+	if err := registry.Register(value_objects.Currency{Code: "PTS", Numeric: 978}); !errors.Is(err, value_objects.ErrCurrencyExists) {
+		t.Fatalf("reusing numeric code 978 returned %v", err)
+	}
+	if err := registry.Register(value_objects.Currency{Code: "P", Scale: 0}); !errors.Is(err, value_objects.ErrInvalidCurrency) {
+		t.Fatalf("one letter code returned %v", err)
+	}
+	if err := registry.Register(value_objects.Currency{Code: "PTS", Scale: -1}); !errors.Is(err, value_objects.ErrInvalidCurrency) {
+		t.Fatalf("negative scale returned %v", err)
+	}
+
+	// справочник по умолчанию не изменился
+	if _, err := value_objects.CurrencyByCode("BTC"); !errors.Is(err, value_objects.ErrUnknownCurrency) {
+		t.Fatalf("BTC leaked into the default registry: %v", err)
+	}
+}
+
+func TestCurrencyRegistryBySymbol(t *testing.T) {
+	currencies := value_objects.NewCurrencyRegistry().BySymbol("kr")
+	codes := make([]string, len(currencies))
+	for i, currency := range currencies {
+		codes[i] = currency.Code
+	}
+
+	want := []string{"DKK", "ISK", "NOK", "SEK"}
+	if len(codes) != len(want) {
+		t.Fatalf("kr = %v, want %v", codes, want)
+	}
+	for i := range want {
+		if codes[i] != want[i] {
+			t.Fatalf("kr = %v, want %v", codes, want)
+		}
+	}
+}
+
+func TestCurrencyEqualTo(t *testing.T) {
+	defer value_objects.SetCurrencyComparison(value_objects.CompareByID)
+
+	first := value_objects.Currency{ID: uuid.New(), Code: "EUR"}
+	second := value_objects.Currency{ID: uuid.New(), Code: "EUR"}
+
+	value_objects.SetCurrencyComparison(value_objects.CompareByID)
+	if first.EqualTo(second) {
+		t.Fatal("currencies with different IDs are equal by ID")
+	}
+	// без идентификатора сравниваются коды
+	if !first.EqualTo(value_objects.Currency{Code: "EUR"}) {
+		t.Fatal("currency without ID is not compared by code")
+	}
+
+	value_objects.SetCurrencyComparison(value_objects.CompareByCode)
+	if !first.EqualTo(second) {
+		t.Fatal("currencies with the same code are not equal by code")
+	}
+	if first.EqualTo(value_objects.Currency{ID: first.ID, Code: "USD"}) {
+		t.Fatal("currencies with different codes are equal by code")
+	}
+}
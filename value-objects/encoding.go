@@ -0,0 +1,416 @@
+package value_objects
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/clock"
+	"github.com/google/uuid"
+)
+
+// Канонические представления объектов-значений для JSON, текста и колонок БД.
+// Декодирование всегда проверяет значение, поэтому DTO могут хранить
+// объекты-значения напрямую, не раскладывая их на примитивы. Нулевое
+// значение кодируется пустой строкой, null в JSON и NULL в БД и
+// декодируется обратно в нулевое значение.
+//
+// Money хранится в двух колонках (сумма в минимальных единицах и код валюты):
+//
+//	Price value_objects.Money `gorm:"embedded;embeddedPrefix:price_"`
+
+var (
+	ErrInvalidColor      = errors.New("invalid color")
+	ErrInvalidLegalForm  = errors.New("invalid legal form")
+	ErrInvalidSalutation = errors.New("invalid salutation")
+	ErrUnsupportedType   = errors.New("unsupported type for scan")
+)
+
+const birthdayLayout = "2006-01-02"
+
+// Currency - код ISO 4217 или код пользовательской валюты, например "EUR".
+// Валюта, известная только по идентификатору (см. NewMoney), кодируется UUID
+
+func (c Currency) MarshalText() ([]byte, error) {
+	switch {
+	case c.Code != "":
+		return []byte(c.Code), nil
+	case c.ID != uuid.Nil:
+		return []byte(c.ID.String()), nil
+	default:
+		return []byte{}, nil
+	}
+}
+
+func (c *Currency) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*c = Currency{}
+		return nil
+	}
+	if id, err := uuid.Parse(string(text)); err == nil {
+		*c = Currency{ID: id}
+		return nil
+	}
+
+	currency, err := CurrencyByCode(string(text))
+	if err != nil {
+		return err
+	}
+	*c = currency
+	return nil
+}
+
+// Value не возвращает NULL для нулевой валюты: по значению gorm определяет
+// тип колонки
+func (c Currency) Value() (driver.Value, error) {
+	text, err := c.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return string(text), nil
+}
+
+func (c *Currency) Scan(src interface{}) error {
+	return scanText(src, c.UnmarshalText)
+}
+
+// Money - {"amount":"12.34","currency":"EUR"} в JSON и "12.34 EUR" в тексте
+
+type moneyJSON struct {
+	Amount   string   `json:"amount"`
+	Currency Currency `json:"currency"`
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	if m == (Money{}) {
+		return []byte("null"), nil
+	}
+	return json.Marshal(moneyJSON{
+		Amount:   m.Decimal(),
+		Currency: m.Currency,
+	})
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*m = Money{}
+		return nil
+	}
+
+	var row moneyJSON
+	if err := json.Unmarshal(data, &row); err != nil {
+		return err
+	}
+
+	money, err := newMoneyFromDecimal(row.Amount, row.Currency)
+	if err != nil {
+		return err
+	}
+	*m = money
+	return nil
+}
+
+func (m Money) MarshalText() ([]byte, error) {
+	if m == (Money{}) {
+		return []byte{}, nil
+	}
+	code, err := m.Currency.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(m.Decimal() + " " + string(code)), nil
+}
+
+func (m *Money) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*m = Money{}
+		return nil
+	}
+
+	parts := strings.Fields(string(text))
+	if len(parts) != 2 {
+		return fmt.Errorf("%w: %q", ErrInvalidAmount, text)
+	}
+
+	var currency Currency
+	if err := currency.UnmarshalText([]byte(parts[1])); err != nil {
+		return err
+	}
+
+	money, err := newMoneyFromDecimal(parts[0], currency)
+	if err != nil {
+		return err
+	}
+	*m = money
+	return nil
+}
+
+// newMoneyFromDecimal разбирает десятичную запись "-12.34" без округления
+func newMoneyFromDecimal(value string, currency Currency) (Money, error) {
+	negative := strings.HasPrefix(value, "-")
+
+	amount, err := parseAmount(strings.TrimPrefix(value, "-"), Locale{Decimal: "."}, currency.Scale)
+	if err != nil {
+		return Money{}, &MoneyParseError{Input: value, Err: err}
+	}
+	if negative {
+		amount = -amount
+	}
+
+	return NewMoneyFromMinor(amount, currency), nil
+}
+
+// Color - "#rrggbb", для прозрачных цветов "#rrggbbaa"
+
+func (c Color) MarshalText() ([]byte, error) {
+	return []byte(c.ToHex()), nil
+}
+
+func (c *Color) UnmarshalText(text []byte) error {
+	if len(text) != 7 && len(text) != 9 {
+		return fmt.Errorf("%w: %q", ErrInvalidColor, text)
+	}
+
+	color, err := parseHexColor(string(text))
+	if err != nil {
+		return err
+	}
+	*c = color
+	return nil
+}
+
+func (c Color) Value() (driver.Value, error) {
+	text, err := c.MarshalText()
+	return string(text), err
+}
+
+func (c *Color) Scan(src interface{}) error {
+	return scanText(src, c.UnmarshalText)
+}
+
+// Address - JSON-объект, в БД хранится в одной колонке в виде JSON.
+// Ошибки проверки возвращаются как *ValidationError
+
+type addressJSON struct {
+	Street   string `json:"street"`
+	Number   int    `json:"number"`
+	Suffix   string `json:"suffix,omitempty"`
+	Postcode string `json:"postcode"`
+	Country  string `json:"country"`
+}
+
+func (a Address) MarshalJSON() ([]byte, error) {
+	if a == (Address{}) {
+		return []byte("null"), nil
+	}
+	return json.Marshal(addressJSON(a))
+}
+
+func (a *Address) UnmarshalJSON(data []byte) error {
+	if len(data) == 0 || string(data) == "null" {
+		*a = Address{}
+		return nil
+	}
+
+	var row addressJSON
+	if err := json.Unmarshal(data, &row); err != nil {
+		return err
+	}
+
+	address, err := NewAddress(row.Country, row.Street, fmt.Sprintf("%d%s", row.Number, row.Suffix), row.Postcode)
+	if err != nil {
+		return err
+	}
+	*a = address
+	return nil
+}
+
+func (a Address) Value() (driver.Value, error) {
+	if a == (Address{}) {
+		return nil, nil
+	}
+	data, err := a.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (a *Address) Scan(src interface{}) error {
+	return scanText(src, a.UnmarshalJSON)
+}
+
+// Phone - "+49 30 1234567", код города может отсутствовать
+
+func (p Phone) MarshalText() ([]byte, error) {
+	if p == (Phone{}) {
+		return []byte{}, nil
+	}
+	parts := make([]string, 0, 3)
+	for _, part := range []string{"+" + p.CountryPrefix, p.AreaCode, p.Number} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return []byte(strings.Join(parts, " ")), nil
+}
+
+func (p *Phone) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*p = Phone{}
+		return nil
+	}
+
+	parts := strings.Fields(string(text))
+	if len(parts) < 2 || len(parts) > 3 || !strings.HasPrefix(parts[0], "+") {
+		return invalid("phone", string(text), ErrInvalidFormat)
+	}
+
+	phone := Phone{
+		CountryPrefix: strings.TrimPrefix(parts[0], "+"),
+		Number:        parts[len(parts)-1],
+	}
+	if len(parts) == 3 {
+		phone.AreaCode = parts[1]
+	}
+	if err := phone.validate(); err != nil {
+		return err
+	}
+
+	*p = phone
+	return nil
+}
+
+func (p Phone) Value() (driver.Value, error) {
+	if p == (Phone{}) {
+		return nil, nil
+	}
+	text, err := p.MarshalText()
+	return string(text), err
+}
+
+func (p *Phone) Scan(src interface{}) error {
+	return scanText(src, p.UnmarshalText)
+}
+
+// Birthday - дата без времени "2006-01-02"
+
+func (b Birthday) MarshalText() ([]byte, error) {
+	if time.Time(b).IsZero() {
+		return []byte{}, nil
+	}
+	return []byte(time.Time(b).Format(birthdayLayout)), nil
+}
+
+func (b *Birthday) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*b = Birthday{}
+		return nil
+	}
+
+	date, err := time.Parse(birthdayLayout, string(text))
+	if err != nil {
+		return invalid("birthday", string(text), ErrInvalidFormat)
+	}
+
+	birthday, err := NewBirthday(date, clock.System)
+	if err != nil {
+		return err
+	}
+	*b = birthday
+	return nil
+}
+
+func (b Birthday) Value() (driver.Value, error) {
+	if time.Time(b).IsZero() {
+		return nil, nil
+	}
+	return time.Time(b), nil
+}
+
+func (b *Birthday) Scan(src interface{}) error {
+	if date, ok := src.(time.Time); ok {
+		*b = Birthday(date)
+		return nil
+	}
+	return scanText(src, b.UnmarshalText)
+}
+
+// LegalForm - ключ формы из каталога, например "gmbh"; при чтении
+// принимается и сокращение ("GmbH")
+
+func (s LegalForm) MarshalText() ([]byte, error) {
+	definition, ok := s.Definition()
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidLegalForm, s)
+	}
+	return []byte(definition.Key), nil
+}
+
+func (s *LegalForm) UnmarshalText(text []byte) error {
+	form, err := ParseLegalForm(string(text))
+	if err != nil {
+		return err
+	}
+	*s = form
+	return nil
+}
+
+func (s LegalForm) Value() (driver.Value, error) {
+	text, err := s.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return string(text), nil
+}
+
+func (s *LegalForm) Scan(src interface{}) error {
+	return scanText(src, s.UnmarshalText)
+}
+
+// Salutation - непустая строка, состоящая не только из пробелов
+
+func (s Salutation) MarshalText() ([]byte, error) {
+	return []byte(s), nil
+}
+
+func (s *Salutation) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*s = ""
+		return nil
+	}
+	if strings.TrimSpace(string(text)) == "" {
+		return ErrInvalidSalutation
+	}
+	*s = Salutation(text)
+	return nil
+}
+
+func (s Salutation) Value() (driver.Value, error) {
+	if s == "" {
+		return nil, nil
+	}
+	return string(s), nil
+}
+
+func (s *Salutation) Scan(src interface{}) error {
+	return scanText(src, s.UnmarshalText)
+}
+
+// scanText передаёт значение текстовой колонки в функцию декодирования,
+// NULL декодируется как пустая строка
+func scanText(src interface{}, decode func(text []byte) error) error {
+	switch value := src.(type) {
+	case nil:
+		return decode(nil)
+	case string:
+		return decode([]byte(value))
+	case []byte:
+		return decode(value)
+	default:
+		return fmt.Errorf("%w: %T", ErrUnsupportedType, src)
+	}
+}
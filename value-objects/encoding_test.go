@@ -0,0 +1,246 @@
+package value_objects_test
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/clock"
+	value_objects "github.com/MaksimDzhangirov/PracticalDDD/value-objects"
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func birthday(t *testing.T, year int, month time.Month, day int) value_objects.Birthday {
+	t.Helper()
+
+	result, err := value_objects.NewBirthday(time.Date(year, month, day, 0, 0, 0, 0, time.UTC), clock.System)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return result
+}
+
+func TestMoneyJSON(t *testing.T) {
+	money := eurCents(-1234)
+	money.Currency = currency(t, "EUR")
+
+	data, err := json.Marshal(money)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"amount":"-12.34","currency":"EUR"}` {
+		t.Fatalf("JSON = %s", data)
+	}
+
+	var decoded value_objects.Money
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded != money {
+		t.Fatalf("decoded %+v, want %+v", decoded, money)
+	}
+
+	// у валюты из NewMoney есть только идентификатор
+	byID := value_objects.NewMoney(100, uuid.New())
+	data, err = json.Marshal(byID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded = value_objects.Money{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded != byID {
+		t.Fatalf("decoded %+v, want %+v", decoded, byID)
+	}
+
+	if err := json.Unmarshal([]byte(`{"amount":"1.234","currency":"EUR"}`), &decoded); !errors.Is(err, value_objects.ErrTooManyDecimals) {
+		t.Fatalf("three decimals for EUR returned %v", err)
+	}
+	if err := json.Unmarshal([]byte(`{"amount":"1","currency":"XYZ"}`), &decoded); !errors.Is(err, value_objects.ErrUnknownCurrency) {
+		t.Fatalf("unknown currency returned %v", err)
+	}
+}
+
+func TestZeroValuesEncodeAsEmpty(t *testing.T) {
+	type document struct {
+		Price    value_objects.Money    `json:"price"`
+		Address  value_objects.Address  `json:"address"`
+		Phone    value_objects.Phone    `json:"phone"`
+		Birthday value_objects.Birthday `json:"birthday"`
+	}
+
+	data, err := json.Marshal(document{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"price":null,"address":null,"phone":"","birthday":""}` {
+		t.Fatalf("JSON = %s", data)
+	}
+
+	var decoded document
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, document{}) {
+		t.Fatalf("decoded %+v, want zero values", decoded)
+	}
+}
+
+func TestTextRoundTrip(t *testing.T) {
+	phone, err := value_objects.ParsePhone("+49 30 1234567")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := []encoding.TextMarshaler{
+		currency(t, "JPY"),
+		value_objects.Currency{},
+		value_objects.NewMoneyFromMinor(-5, currency(t, "BHD")),
+		value_objects.Money{},
+		value_objects.Color{Red: 255, Green: 128, Blue: 1},
+		value_objects.Color{Red: 1, Transparency: 128},
+		phone,
+		value_objects.Phone{},
+		birthday(t, 1990, time.February, 28),
+		value_objects.Birthday{},
+		value_objects.GmbH,
+		value_objects.Freelancer,
+		value_objects.Salutation("Dr."),
+		value_objects.Salutation(""),
+	}
+	for _, value := range values {
+		text, err := value.MarshalText()
+		if err != nil {
+			t.Fatalf("%T %+v: %v", value, value, err)
+		}
+
+		decoded := reflect.New(reflect.TypeOf(value))
+		if err := decoded.Interface().(encoding.TextUnmarshaler).UnmarshalText(text); err != nil {
+			t.Fatalf("%T %q: %v", value, text, err)
+		}
+		if !reflect.DeepEqual(decoded.Elem().Interface(), value) {
+			t.Fatalf("%q decoded as %+v, want %+v", text, decoded.Elem().Interface(), value)
+		}
+	}
+}
+
+func TestTextDecodingValidates(t *testing.T) {
+	var phone value_objects.Phone
+	var validation *value_objects.ValidationError
+	if err := phone.UnmarshalText([]byte("+")); !errors.As(err, &validation) {
+		t.Fatalf("phone %q returned %v", "+", err)
+	}
+	var color value_objects.Color
+	if err := color.UnmarshalText([]byte("#12345")); !errors.Is(err, value_objects.ErrInvalidColor) {
+		t.Fatalf("color returned %v", err)
+	}
+	var form value_objects.LegalForm
+	if err := form.UnmarshalText([]byte("S.A.R.L.")); err != nil || form != value_objects.SARL {
+		t.Fatalf("S.A.R.L. decoded as %v, %v", form, err)
+	}
+	if err := form.UnmarshalText([]byte("kg")); !errors.Is(err, value_objects.ErrInvalidLegalForm) {
+		t.Fatalf("unknown legal form returned %v", err)
+	}
+	var salutation value_objects.Salutation
+	if err := salutation.UnmarshalText([]byte("  ")); !errors.Is(err, value_objects.ErrInvalidSalutation) {
+		t.Fatalf("blank salutation returned %v", err)
+	}
+	var date value_objects.Birthday
+	if err := date.UnmarshalText([]byte(time.Now().AddDate(1, 0, 0).Format("2006-01-02"))); !errors.Is(err, value_objects.ErrInFuture) {
+		t.Fatalf("birthday in the future returned %v", err)
+	}
+}
+
+func TestAddressJSON(t *testing.T) {
+	address, err := value_objects.NewAddress("DE", "Unter den Linden", "77a", "10117")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded value_objects.Address
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded != address {
+		t.Fatalf("decoded %+v, want %+v", decoded, address)
+	}
+
+	var validation *value_objects.ValidationError
+	err = json.Unmarshal([]byte(`{"street":"Unter den Linden","number":77,"postcode":"1011","country":"DE"}`), &decoded)
+	if !errors.As(err, &validation) {
+		t.Fatalf("invalid postcode returned %v", err)
+	}
+}
+
+// customerRow - DTO, хранящий объекты-значения напрямую
+type customerRow struct {
+	ID         uint                `gorm:"primaryKey"`
+	Price      value_objects.Money `gorm:"embedded;embeddedPrefix:price_"`
+	Address    value_objects.Address
+	Phone      value_objects.Phone
+	Birthday   value_objects.Birthday
+	Color      value_objects.Color
+	LegalForm  value_objects.LegalForm
+	Salutation value_objects.Salutation
+}
+
+func TestGormStoresValueObjects(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "values.db") + "?_busy_timeout=5000"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&customerRow{}); err != nil {
+		t.Fatal(err)
+	}
+
+	address, err := value_objects.NewAddress("FR", "Rue de Rivoli", "1", "75001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	phone, err := value_objects.ParsePhone("+33 1 23456789")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := []customerRow{
+		{
+			Price:      value_objects.NewMoneyFromMinor(250000, currency(t, "EUR")),
+			Address:    address,
+			Phone:      phone,
+			Birthday:   birthday(t, 1980, time.May, 17),
+			Color:      value_objects.Color{Red: 10, Green: 20, Blue: 30, Transparency: 40},
+			LegalForm:  value_objects.SAS,
+			Salutation: "Mme",
+		},
+		{Price: value_objects.NewMoney(7, uuid.New())},
+		// нулевые значения сохраняются как NULL и читаются обратно
+		{},
+	}
+	for i := range rows {
+		if err := db.Create(&rows[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		var loaded customerRow
+		if err := db.First(&loaded, rows[i].ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		loaded.Birthday = value_objects.Birthday(time.Time(loaded.Birthday).UTC())
+		if !reflect.DeepEqual(loaded, rows[i]) {
+			t.Fatalf("loaded %+v, want %+v", loaded, rows[i])
+		}
+	}
+}
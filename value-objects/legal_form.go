@@ -0,0 +1,162 @@
+package value_objects
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Liability - ответственность владельцев по обязательствам организации
+type Liability int
+
+const (
+	UnlimitedLiability Liability = iota
+	LimitedLiability
+)
+
+// LegalFormDefinition описывает организационно-правовую форму в каталоге
+type LegalFormDefinition struct {
+	// Key - идентификатор для JSON и БД, например "gmbh"
+	Key          string
+	Abbreviation string
+	Name         string
+	// Country - код страны ISO 3166-1, пустой для форм, не привязанных к стране
+	Country        string
+	Liability      Liability
+	MinimumCapital Money
+	// RegistrationNumber - формат регистрационного номера, nil если номер не нужен
+	RegistrationNumber *regexp.Regexp
+	// Individual - Customer с этой формой превращается в Person, иначе в Company
+	Individual bool
+}
+
+var (
+	handelsregister = regexp.MustCompile(`^HRB \d{1,6}( [A-Z]{1,2})?$`)
+	siren           = regexp.MustCompile(`^\d{3} ?\d{3} ?\d{3}$`)
+	companiesHouse  = regexp.MustCompile(`^(\d{8}|(SC|NI|OC)\d{6})$`)
+	ein             = regexp.MustCompile(`^\d{2}-\d{7}$`)
+	ogrn            = regexp.MustCompile(`^\d{13}$`)
+	ogrnip          = regexp.MustCompile(`^\d{15}$`)
+)
+
+// legalForms - каталог организационно-правовых форм
+var legalForms = map[LegalForm]LegalFormDefinition{
+	Freelancer:  {Key: "freelancer", Abbreviation: "Freelancer", Name: "Freelancer", Individual: true},
+	Partnership: {Key: "partnership", Abbreviation: "Partnership", Name: "General partnership"},
+	LLC: {Key: "llc", Abbreviation: "LLC", Name: "Limited liability company", Country: "US",
+		Liability: LimitedLiability, MinimumCapital: minimumCapital(0, "USD"), RegistrationNumber: ein},
+	Corporation: {Key: "corporation", Abbreviation: "Inc.", Name: "Corporation", Country: "US",
+		Liability: LimitedLiability, MinimumCapital: minimumCapital(0, "USD"), RegistrationNumber: ein},
+	GmbH: {Key: "gmbh", Abbreviation: "GmbH", Name: "Gesellschaft mit beschränkter Haftung", Country: "DE",
+		Liability: LimitedLiability, MinimumCapital: minimumCapital(25000, "EUR"), RegistrationNumber: handelsregister},
+	UG: {Key: "ug", Abbreviation: "UG (haftungsbeschränkt)", Name: "Unternehmergesellschaft", Country: "DE",
+		Liability: LimitedLiability, MinimumCapital: minimumCapital(1, "EUR"), RegistrationNumber: handelsregister},
+	AG: {Key: "ag", Abbreviation: "AG", Name: "Aktiengesellschaft", Country: "DE",
+		Liability: LimitedLiability, MinimumCapital: minimumCapital(50000, "EUR"), RegistrationNumber: handelsregister},
+	SARL: {Key: "sarl", Abbreviation: "SARL", Name: "Société à responsabilité limitée", Country: "FR",
+		Liability: LimitedLiability, MinimumCapital: minimumCapital(1, "EUR"), RegistrationNumber: siren},
+	SAS: {Key: "sas", Abbreviation: "SAS", Name: "Société par actions simplifiée", Country: "FR",
+		Liability: LimitedLiability, MinimumCapital: minimumCapital(1, "EUR"), RegistrationNumber: siren},
+	SA: {Key: "sa", Abbreviation: "SA", Name: "Société anonyme", Country: "FR",
+		Liability: LimitedLiability, MinimumCapital: minimumCapital(37000, "EUR"), RegistrationNumber: siren},
+	Ltd: {Key: "ltd", Abbreviation: "Ltd", Name: "Private limited company", Country: "GB",
+		Liability: LimitedLiability, MinimumCapital: minimumCapital(0, "GBP"), RegistrationNumber: companiesHouse},
+	PLC: {Key: "plc", Abbreviation: "PLC", Name: "Public limited company", Country: "GB",
+		Liability: LimitedLiability, MinimumCapital: minimumCapital(50000, "GBP"), RegistrationNumber: companiesHouse},
+	OOO: {Key: "ooo", Abbreviation: "ООО", Name: "Общество с ограниченной ответственностью", Country: "RU",
+		Liability: LimitedLiability, MinimumCapital: minimumCapital(10000, "RUB"), RegistrationNumber: ogrn},
+	AO: {Key: "ao", Abbreviation: "АО", Name: "Акционерное общество", Country: "RU",
+		Liability: LimitedLiability, MinimumCapital: minimumCapital(100000, "RUB"), RegistrationNumber: ogrn},
+	IP: {Key: "ip", Abbreviation: "ИП", Name: "Индивидуальный предприниматель", Country: "RU",
+		RegistrationNumber: ogrnip, Individual: true},
+}
+
+// minimumCapital переводит сумму в целых единицах валюты в Money
+func minimumCapital(amount int64, code string) Money {
+	currency, err := CurrencyByCode(code)
+	if err != nil {
+		panic(err)
+	}
+
+	for i := 0; i < currency.Scale; i++ {
+		amount *= 10
+	}
+
+	return NewMoneyFromMinor(amount, currency)
+}
+
+// ParseLegalForm находит форму по ключу ("gmbh") или сокращению ("GmbH", "S.A.R.L.", "ООО")
+func ParseLegalForm(value string) (LegalForm, error) {
+	normalized := normalizeLegalForm(value)
+	for form, definition := range legalForms {
+		if normalized == definition.Key || normalized == normalizeLegalForm(definition.Abbreviation) {
+			return form, nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: %q", ErrInvalidLegalForm, value)
+}
+
+func normalizeLegalForm(value string) string {
+	return strings.ToLower(strings.NewReplacer(".", "", " ", "").Replace(value))
+}
+
+// LegalFormsIn возвращает формы, доступные в стране, включая формы без привязки к стране
+func LegalFormsIn(country string) []LegalForm {
+	var result []LegalForm
+	for form, definition := range legalForms {
+		if definition.Country == "" || definition.Country == strings.ToUpper(country) {
+			result = append(result, form)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i] < result[j]
+	})
+
+	return result
+}
+
+// Definition возвращает описание формы из каталога
+func (s LegalForm) Definition() (LegalFormDefinition, bool) {
+	definition, ok := legalForms[s]
+	return definition, ok
+}
+
+func (s LegalForm) String() string {
+	definition, ok := s.Definition()
+	if !ok {
+		return fmt.Sprintf("LegalForm(%d)", int(s))
+	}
+	return definition.Abbreviation
+}
+
+func (s LegalForm) IsIndividual() bool {
+	definition, ok := s.Definition()
+	return ok && definition.Individual
+}
+
+func (s LegalForm) HasLimitedResponsibility() bool {
+	definition, ok := s.Definition()
+	return ok && definition.Liability == LimitedLiability
+}
+
+// ValidateRegistrationNumber проверяет регистрационный номер по формату страны
+func (s LegalForm) ValidateRegistrationNumber(number string) error {
+	definition, ok := s.Definition()
+	if !ok {
+		return fmt.Errorf("%w: %d", ErrInvalidLegalForm, s)
+	}
+
+	number = strings.TrimSpace(number)
+	switch {
+	case definition.RegistrationNumber == nil:
+		return nil
+	case number == "":
+		return invalid("registration_number", number, ErrRequired)
+	case !definition.RegistrationNumber.MatchString(number):
+		return invalid("registration_number", number, ErrInvalidFormat)
+	}
+
+	return nil
+}
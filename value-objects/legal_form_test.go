@@ -0,0 +1,169 @@
+package value_objects_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	value_objects "github.com/MaksimDzhangirov/PracticalDDD/value-objects"
+	"github.com/google/uuid"
+)
+
+func TestParseLegalForm(t *testing.T) {
+	tests := []struct {
+		input string
+		want  value_objects.LegalForm
+	}{
+		{"gmbh", value_objects.GmbH},
+		{"GmbH", value_objects.GmbH},
+		{"S.A.R.L.", value_objects.SARL},
+		{"s.a.", value_objects.SA},
+		{"UG (haftungsbeschränkt)", value_objects.UG},
+		{"Inc.", value_objects.Corporation},
+		{"ООО", value_objects.OOO},
+		{"ип", value_objects.IP},
+		{"freelancer", value_objects.Freelancer},
+	}
+	for _, test := range tests {
+		form, err := value_objects.ParseLegalForm(test.input)
+		if err != nil {
+			t.Fatalf("ParseLegalForm(%q): %v", test.input, err)
+		}
+		if form != test.want {
+			t.Fatalf("ParseLegalForm(%q) = %v, want %v", test.input, form, test.want)
+		}
+	}
+
+	if _, err := value_objects.ParseLegalForm("KG"); !errors.Is(err, value_objects.ErrInvalidLegalForm) {
+		t.Fatalf("unknown legal form returned %v", err)
+	}
+}
+
+func TestLegalFormStringAndJSON(t *testing.T) {
+	if got := value_objects.OOO.String(); got != "ООО" {
+		t.Fatalf("String() = %q", got)
+	}
+	if got := value_objects.LegalForm(100).String(); got != "LegalForm(100)" {
+		t.Fatalf("unknown String() = %q", got)
+	}
+
+	// каждая форма каталога кодируется ключом и читается обратно
+	for form := value_objects.Freelancer; form <= value_objects.IP; form++ {
+		data, err := json.Marshal(form)
+		if err != nil {
+			t.Fatal(err)
+		}
+		definition, _ := form.Definition()
+		if string(data) != `"`+definition.Key+`"` {
+			t.Fatalf("%v encoded as %s", form, data)
+		}
+		var decoded value_objects.LegalForm
+		if err := json.Unmarshal(data, &decoded); err != nil || decoded != form {
+			t.Fatalf("%s decoded as %v, %v", data, decoded, err)
+		}
+	}
+
+	if _, err := json.Marshal(value_objects.LegalForm(100)); !errors.Is(err, value_objects.ErrInvalidLegalForm) {
+		t.Fatalf("unknown form encoded with %v", err)
+	}
+}
+
+func TestLegalFormDefinition(t *testing.T) {
+	gmbh, ok := value_objects.GmbH.Definition()
+	if !ok {
+		t.Fatal("GmbH is not in the catalogue")
+	}
+	if gmbh.Country != "DE" || gmbh.Liability != value_objects.LimitedLiability {
+		t.Fatalf("GmbH = %+v", gmbh)
+	}
+	if gmbh.MinimumCapital.Amount != 2500000 || gmbh.MinimumCapital.Currency.Code != "EUR" {
+		t.Fatalf("GmbH minimum capital = %+v, want 25000.00 EUR", gmbh.MinimumCapital)
+	}
+	if ooo, _ := value_objects.OOO.Definition(); ooo.MinimumCapital.Amount != 1000000 || ooo.MinimumCapital.Currency.Code != "RUB" {
+		t.Fatalf("ООО minimum capital = %+v, want 10000.00 RUB", ooo.MinimumCapital)
+	}
+	if _, ok := value_objects.LegalForm(100).Definition(); ok {
+		t.Fatal("unknown form has a definition")
+	}
+
+	if !value_objects.IP.IsIndividual() || value_objects.IP.HasLimitedResponsibility() {
+		t.Fatal("ИП must be an individual with unlimited liability")
+	}
+	if value_objects.Partnership.IsIndividual() || value_objects.Partnership.HasLimitedResponsibility() {
+		t.Fatal("partnership must be a company with unlimited liability")
+	}
+	if !value_objects.Ltd.HasLimitedResponsibility() {
+		t.Fatal("Ltd must have limited liability")
+	}
+}
+
+func TestLegalFormsIn(t *testing.T) {
+	want := []value_objects.LegalForm{
+		value_objects.Freelancer,
+		value_objects.Partnership,
+		value_objects.OOO,
+		value_objects.AO,
+		value_objects.IP,
+	}
+	if got := value_objects.LegalFormsIn("ru"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("LegalFormsIn(ru) = %v, want %v", got, want)
+	}
+}
+
+func TestValidateRegistrationNumber(t *testing.T) {
+	tests := []struct {
+		form   value_objects.LegalForm
+		number string
+		err    error
+	}{
+		{value_objects.GmbH, "HRB 12345", nil},
+		{value_objects.GmbH, "HRB 12345 B", nil},
+		{value_objects.GmbH, "12345", value_objects.ErrInvalidFormat},
+		{value_objects.SARL, "732 829 320", nil},
+		{value_objects.Ltd, "SC123456", nil},
+		{value_objects.Ltd, "1234567", value_objects.ErrInvalidFormat},
+		{value_objects.LLC, "12-3456789", nil},
+		{value_objects.OOO, "1027700132195", nil},
+		{value_objects.IP, "1027700132195", value_objects.ErrInvalidFormat},
+		{value_objects.IP, "304500116000157", nil},
+		{value_objects.AG, " ", value_objects.ErrRequired},
+		// для фрилансера номер не нужен
+		{value_objects.Freelancer, "", nil},
+		{value_objects.LegalForm(100), "HRB 1", value_objects.ErrInvalidLegalForm},
+	}
+	for _, test := range tests {
+		err := test.form.ValidateRegistrationNumber(test.number)
+		if !errors.Is(err, test.err) || (test.err == nil) != (err == nil) {
+			t.Fatalf("%v.ValidateRegistrationNumber(%q) returned %v, want %v", test.form, test.number, err, test.err)
+		}
+	}
+}
+
+func TestCustomerConversionFollowsLegalForm(t *testing.T) {
+	date := time.Date(1985, time.April, 1, 0, 0, 0, 0, time.UTC)
+
+	for form := value_objects.Freelancer; form <= value_objects.IP; form++ {
+		customer := value_objects.Customer{ID: uuid.New(), Name: "Иванов", LegalForm: form, Date: date}
+
+		person, personErr := customer.ToPerson()
+		company, companyErr := customer.ToCompany()
+		if form.IsIndividual() {
+			if personErr != nil || person.FullName != "Иванов" || !time.Time(person.Birthday).Equal(date) {
+				t.Fatalf("%v.ToPerson() = %+v, %v", form, person, personErr)
+			}
+			if !errors.Is(companyErr, value_objects.ErrInvalidLegalForm) {
+				t.Fatalf("%v.ToCompany() returned %v", form, companyErr)
+			}
+			continue
+		}
+
+		if companyErr != nil || company.Name != "Иванов" || company.LegalForm != form || !company.CreationDate.Equal(date) {
+			t.Fatalf("%v.ToCompany() = %+v, %v", form, company, companyErr)
+		}
+		if !errors.Is(personErr, value_objects.ErrInvalidLegalForm) {
+			t.Fatalf("%v.ToPerson() returned %v", form, personErr)
+		}
+	}
+}
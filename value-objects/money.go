@@ -0,0 +1,165 @@
+package value_objects
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+var (
+	ErrMoneyOverflow  = errors.New("amount does not fit into 64 bits")
+	ErrDivisionByZero = errors.New("division by zero")
+	ErrInvalidRatios  = errors.New("ratios must be non-negative and their sum must be positive")
+	ErrInvalidParts   = errors.New("number of parts must be positive")
+)
+
+// RoundingMode определяет, как округлять результат до минимальной единицы валюты
+type RoundingMode int
+
+const (
+	// RoundHalfUp округляет половину от нуля: 0.5 -> 1, -0.5 -> -1
+	RoundHalfUp RoundingMode = iota
+	// RoundHalfEven (банковское округление) округляет половину до чётного: 0.5 -> 0, 1.5 -> 2
+	RoundHalfEven
+)
+
+// Decimal возвращает сумму в виде десятичной строки с учётом Scale валюты, например "-12.34"
+func (m Money) Decimal() string {
+	digits := new(big.Int).Abs(big.NewInt(m.Amount)).String()
+
+	sign := ""
+	if m.Amount < 0 {
+		sign = "-"
+	}
+
+	scale := m.Currency.Scale
+	if scale <= 0 {
+		return sign + digits
+	}
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+
+	return fmt.Sprintf("%s%s.%s", sign, digits[:len(digits)-scale], digits[len(digits)-scale:])
+}
+
+// Multiply умножает сумму на точный коэффициент, например big.NewRat(119, 100) для НДС 19%
+func (m Money) Multiply(factor *big.Rat, mode RoundingMode) (Money, error) {
+	numerator := new(big.Int).Mul(big.NewInt(m.Amount), factor.Num())
+
+	amount, err := divideRounded(numerator, factor.Denom(), mode)
+	if err != nil {
+		return Money{}, err
+	}
+
+	return Money{
+		Amount:   amount,
+		Currency: m.Currency,
+	}, nil
+}
+
+// Divide делит сумму на точный делитель с округлением до минимальной единицы валюты
+func (m Money) Divide(divisor *big.Rat, mode RoundingMode) (Money, error) {
+	if divisor.Sign() == 0 {
+		return Money{}, ErrDivisionByZero
+	}
+
+	numerator := new(big.Int).Mul(big.NewInt(m.Amount), divisor.Denom())
+
+	amount, err := divideRounded(numerator, divisor.Num(), mode)
+	if err != nil {
+		return Money{}, err
+	}
+
+	return Money{
+		Amount:   amount,
+		Currency: m.Currency,
+	}, nil
+}
+
+// Allocate делит сумму пропорционально ratios так, что сумма частей всегда
+// равна исходной: остаток по одной минимальной единице получают первые части
+func (m Money) Allocate(ratios ...int) ([]Money, error) {
+	var total int64
+	for _, ratio := range ratios {
+		if ratio < 0 {
+			return nil, ErrInvalidRatios
+		}
+		total += int64(ratio)
+	}
+	if total == 0 {
+		return nil, ErrInvalidRatios
+	}
+
+	result := make([]Money, len(ratios))
+	remainder := m.Amount
+	for i, ratio := range ratios {
+		share := new(big.Int).Mul(big.NewInt(m.Amount), big.NewInt(int64(ratio)))
+		share.Quo(share, big.NewInt(total))
+
+		result[i] = Money{
+			Amount:   share.Int64(),
+			Currency: m.Currency,
+		}
+		remainder -= share.Int64()
+	}
+
+	step := int64(1)
+	if remainder < 0 {
+		step = -1
+	}
+	for i := 0; remainder != 0; i++ {
+		if ratios[i] == 0 {
+			continue
+		}
+		result[i].Amount += step
+		remainder -= step
+	}
+
+	return result, nil
+}
+
+// Split делит сумму на n равных частей, разница в одну минимальную единицу
+// достаётся первым частям: 100 / 3 = [34, 33, 33]
+func (m Money) Split(n int) ([]Money, error) {
+	if n <= 0 {
+		return nil, ErrInvalidParts
+	}
+
+	ratios := make([]int, n)
+	for i := range ratios {
+		ratios[i] = 1
+	}
+
+	return m.Allocate(ratios...)
+}
+
+// divideRounded делит numerator на denominator, округляя частное согласно mode
+func divideRounded(numerator, denominator *big.Int, mode RoundingMode) (int64, error) {
+	if denominator.Sign() == 0 {
+		return 0, ErrDivisionByZero
+	}
+
+	quotient, remainder := new(big.Int).QuoRem(numerator, denominator, new(big.Int))
+
+	if remainder.Sign() != 0 {
+		twice := new(big.Int).Abs(remainder)
+		twice.Lsh(twice, 1)
+
+		cmp := twice.Cmp(new(big.Int).Abs(denominator))
+		if cmp > 0 || (cmp == 0 && (mode == RoundHalfUp || quotient.Bit(0) == 1)) {
+			if numerator.Sign()*denominator.Sign() < 0 {
+				quotient.Sub(quotient, big.NewInt(1))
+			} else {
+				quotient.Add(quotient, big.NewInt(1))
+			}
+		}
+	}
+
+	if !quotient.IsInt64() {
+		return 0, ErrMoneyOverflow
+	}
+
+	return quotient.Int64(), nil
+}
@@ -0,0 +1,112 @@
+package value_objects
+
+import (
+	"errors"
+	"sort"
+)
+
+// MoneyBag - объект-значение, хранящий суммы в нескольких валютах одновременно
+type MoneyBag struct {
+	amounts map[string]Money
+}
+
+// NewMoneyBag складывает суммы по валютам
+func NewMoneyBag(amounts ...Money) (MoneyBag, error) {
+	bag := MoneyBag{
+		amounts: make(map[string]Money, len(amounts)),
+	}
+
+	return bag.Add(amounts...)
+}
+
+// Add возвращает новый MoneyBag, к которому прибавлены суммы
+func (b MoneyBag) Add(amounts ...Money) (MoneyBag, error) {
+	result := MoneyBag{
+		amounts: make(map[string]Money, len(b.amounts)+len(amounts)),
+	}
+	for key, money := range b.amounts {
+		result.amounts[key] = money
+	}
+
+	for _, money := range amounts {
+		key := currencyKey(money.Currency)
+
+		current, ok := result.amounts[key]
+		if !ok {
+			result.amounts[key] = money
+			continue
+		}
+
+		// ключ уже совпал, поэтому валюты сравниваются по ключу, а не EqualTo
+		money.Currency = current.Currency
+		sum, err := current.Add(money)
+		if err != nil {
+			return MoneyBag{}, err
+		}
+		result.amounts[key] = sum
+	}
+
+	return result, nil
+}
+
+// Amount возвращает сумму в указанной валюте
+func (b MoneyBag) Amount(currency Currency) (Money, bool) {
+	money, ok := b.amounts[currencyKey(currency)]
+	return money, ok
+}
+
+// Amounts возвращает суммы, упорядоченные по коду валюты
+func (b MoneyBag) Amounts() []Money {
+	result := make([]Money, 0, len(b.amounts))
+	for _, money := range b.amounts {
+		result = append(result, money)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return currencyKey(result[i].Currency) < currencyKey(result[j].Currency)
+	})
+
+	return result
+}
+
+func (b MoneyBag) IsEmpty() bool {
+	return len(b.amounts) == 0
+}
+
+// Total переводит все суммы в валюту to с помощью convert и складывает их.
+// Суммы в валюте to не конвертируются; валюты сравниваются по тому же
+// ключу, что и в MoneyBag
+func (b MoneyBag) Total(to Currency, convert func(to Currency, from Money) (Money, error)) (Money, error) {
+	total := Money{
+		Currency: to,
+	}
+
+	for _, money := range b.Amounts() {
+		if currencyKey(money.Currency) != currencyKey(to) {
+			converted, err := convert(to, money)
+			if err != nil {
+				return Money{}, err
+			}
+			if currencyKey(converted.Currency) != currencyKey(to) {
+				return Money{}, errors.New("currencies must be identical")
+			}
+			money = converted
+		}
+		money.Currency = to
+
+		var err error
+		total, err = total.Add(money)
+		if err != nil {
+			return Money{}, err
+		}
+	}
+
+	return total, nil
+}
+
+// currencyKey - ключ валюты в MoneyBag: код, а если он не задан - идентификатор
+func currencyKey(currency Currency) string {
+	if currency.Code != "" {
+		return currency.Code
+	}
+	return currency.ID.String()
+}
@@ -0,0 +1,108 @@
+package value_objects_test
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	value_objects "github.com/MaksimDzhangirov/PracticalDDD/value-objects"
+)
+
+var usd = value_objects.Currency{Code: "USD", Scale: 2}
+
+func TestMoneySplit(t *testing.T) {
+	parts, err := eurCents(100).Split(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []int64{34, 33, 33}
+	for i, part := range parts {
+		if part.Amount != want[i] || part.Currency != eur {
+			t.Fatalf("part %d = %+v, want %d EUR cents", i, part, want[i])
+		}
+	}
+
+	// одинаковый вход всегда даёт одинаковые части
+	again, err := eurCents(100).Split(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range parts {
+		if parts[i] != again[i] {
+			t.Fatalf("Split is not deterministic: %v and %v", parts, again)
+		}
+	}
+
+	if _, err := eurCents(100).Split(0); !errors.Is(err, value_objects.ErrInvalidParts) {
+		t.Fatalf("Split(0) returned %v", err)
+	}
+}
+
+func TestMoneyBagSumsByCurrency(t *testing.T) {
+	bag, err := value_objects.NewMoneyBag(
+		eurCents(150),
+		value_objects.NewMoneyFromMinor(200, usd),
+		eurCents(50),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	euros, ok := bag.Amount(eur)
+	if !ok || euros.Amount != 200 {
+		t.Fatalf("EUR = %+v, %v, want 200 cents", euros, ok)
+	}
+
+	amounts := bag.Amounts()
+	if len(amounts) != 2 || amounts[0].Currency.Code != "EUR" || amounts[1].Currency.Code != "USD" {
+		t.Fatalf("Amounts() = %+v", amounts)
+	}
+
+	// Add возвращает новый MoneyBag и не меняет исходный
+	more, err := bag.Add(value_objects.NewMoneyFromMinor(5, jpy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := bag.Amount(jpy); ok {
+		t.Fatal("Add changed the original bag")
+	}
+	if yen, ok := more.Amount(jpy); !ok || yen.Amount != 5 {
+		t.Fatalf("JPY = %+v, %v", yen, ok)
+	}
+	if !(value_objects.MoneyBag{}).IsEmpty() || bag.IsEmpty() {
+		t.Fatal("IsEmpty is wrong")
+	}
+}
+
+func TestMoneyBagTotalConvertsOtherCurrencies(t *testing.T) {
+	bag, err := value_objects.NewMoneyBag(eurCents(1000), value_objects.NewMoneyFromMinor(500, usd))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var converted []string
+	// 1 USD = 0.9 EUR
+	total, err := bag.Total(eur, func(to value_objects.Currency, from value_objects.Money) (value_objects.Money, error) {
+		converted = append(converted, from.Currency.Code)
+		result, err := from.Multiply(big.NewRat(9, 10), value_objects.RoundHalfEven)
+		result.Currency = to
+		return result, err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total.Amount != 1450 || total.Currency != eur {
+		t.Fatalf("total = %+v, want 1450 EUR cents", total)
+	}
+	if len(converted) != 1 || converted[0] != "USD" {
+		t.Fatalf("converted %v, want only USD", converted)
+	}
+
+	// конвертер обязан вернуть сумму в целевой валюте
+	_, err = bag.Total(eur, func(to value_objects.Currency, from value_objects.Money) (value_objects.Money, error) {
+		return from, nil
+	})
+	if err == nil {
+		t.Fatal("Total accepted a conversion into the wrong currency")
+	}
+}
@@ -0,0 +1,273 @@
+package value_objects
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"math/big"
+	"strings"
+	"unicode"
+)
+
+var (
+	ErrUnknownLocale     = errors.New("unknown locale")
+	ErrInvalidAmount     = errors.New("invalid amount")
+	ErrTooManyDecimals   = errors.New("too many decimal places for currency")
+	ErrAmbiguousCurrency = errors.New("currency symbol is ambiguous")
+)
+
+// FormatStyle определяет, как выводить сумму
+type FormatStyle int
+
+const (
+	// FormatText - обычный текст: "-€1,234.56"
+	FormatText FormatStyle = iota
+	// FormatHTML - символ валюты в виде HTML-сущности: "-&#8364;1,234.56"
+	FormatHTML
+	// FormatAccounting - отрицательные суммы в скобках: "(€1,234.56)"
+	FormatAccounting
+)
+
+// Locale описывает правила записи денежных сумм в конкретной стране
+type Locale struct {
+	Tag         string
+	Decimal     string
+	Group       string
+	SymbolFirst bool
+	SymbolSpace bool
+	// Currency - код валюты для сумм, записанных без символа
+	Currency string
+}
+
+var (
+	LocaleEnUS = Locale{Tag: "en-US", Decimal: ".", Group: ",", SymbolFirst: true, Currency: "USD"}
+	LocaleEnGB = Locale{Tag: "en-GB", Decimal: ".", Group: ",", SymbolFirst: true, Currency: "GBP"}
+	LocaleDeDE = Locale{Tag: "de-DE", Decimal: ",", Group: ".", SymbolSpace: true, Currency: "EUR"}
+	LocaleFrFR = Locale{Tag: "fr-FR", Decimal: ",", Group: "\u202f", SymbolSpace: true, Currency: "EUR"}
+	LocaleRuRU = Locale{Tag: "ru-RU", Decimal: ",", Group: "\u00a0", SymbolSpace: true, Currency: "RUB"}
+	LocaleJaJP = Locale{Tag: "ja-JP", Decimal: ".", Group: ",", SymbolFirst: true, Currency: "JPY"}
+)
+
+var locales = map[string]Locale{
+	LocaleEnUS.Tag: LocaleEnUS,
+	LocaleEnGB.Tag: LocaleEnGB,
+	LocaleDeDE.Tag: LocaleDeDE,
+	LocaleFrFR.Tag: LocaleFrFR,
+	LocaleRuRU.Tag: LocaleRuRU,
+	LocaleJaJP.Tag: LocaleJaJP,
+}
+
+// LocaleByTag ищет локаль по тегу, например "de-DE"
+func LocaleByTag(tag string) (Locale, error) {
+	locale, ok := locales[tag]
+	if !ok {
+		return Locale{}, fmt.Errorf("%w: %s", ErrUnknownLocale, tag)
+	}
+
+	return locale, nil
+}
+
+// MoneyParseError описывает, почему строку не удалось разобрать как сумму
+type MoneyParseError struct {
+	Input string
+	Err   error
+}
+
+func (e *MoneyParseError) Error() string {
+	return fmt.Sprintf("cannot parse %q as money: %s", e.Input, e.Err)
+}
+
+func (e *MoneyParseError) Unwrap() error {
+	return e.Err
+}
+
+// Format выводит сумму по правилам локали
+func (m Money) Format(locale Locale, style FormatStyle) string {
+	decimal := m.Decimal()
+	negative := strings.HasPrefix(decimal, "-")
+	decimal = strings.TrimPrefix(decimal, "-")
+
+	integer, fraction := decimal, ""
+	if i := strings.Index(decimal, "."); i >= 0 {
+		integer, fraction = decimal[:i], decimal[i+1:]
+	}
+
+	number := groupDigits(integer, locale.Group)
+	if fraction != "" {
+		number += locale.Decimal + fraction
+	}
+
+	symbol := m.Currency.Symbol
+	if symbol == "" {
+		symbol = m.Currency.Code
+	}
+	space := " "
+	if style == FormatHTML {
+		space = "&nbsp;"
+		if m.Currency.HTML != 0 {
+			symbol = fmt.Sprintf("&#%d;", m.Currency.HTML)
+		} else {
+			symbol = html.EscapeString(symbol)
+		}
+	}
+	if !locale.SymbolSpace {
+		space = ""
+	}
+
+	result := number + space + symbol
+	if locale.SymbolFirst {
+		result = symbol + space + number
+	}
+
+	switch {
+	case !negative:
+		return result
+	case style == FormatAccounting:
+		return "(" + result + ")"
+	default:
+		return "-" + result
+	}
+}
+
+// ParseMoney разбирает ввод пользователя, например "1 234,56 €" или "-$1,234.56".
+// Если символ валюты не указан, используется валюта локали
+func ParseMoney(input string, locale Locale) (Money, error) {
+	money, err := parseMoney(strings.TrimSpace(input), locale)
+	if err != nil {
+		return Money{}, &MoneyParseError{Input: input, Err: err}
+	}
+
+	return money, nil
+}
+
+func parseMoney(input string, locale Locale) (Money, error) {
+	negative := false
+	if strings.HasPrefix(input, "(") && strings.HasSuffix(input, ")") {
+		negative = true
+		input = strings.TrimSpace(input[1 : len(input)-1])
+	}
+
+	first := strings.IndexFunc(input, unicode.IsDigit)
+	last := strings.LastIndexFunc(input, unicode.IsDigit)
+	if first < 0 {
+		return Money{}, ErrInvalidAmount
+	}
+
+	prefix := strings.TrimSpace(input[:first])
+	if strings.HasPrefix(prefix, "-") || strings.HasSuffix(prefix, "-") {
+		if negative {
+			return Money{}, ErrInvalidAmount
+		}
+		negative = true
+		prefix = strings.TrimSpace(strings.Trim(prefix, "-"))
+	}
+	suffix := strings.TrimSpace(input[last+1:])
+	if prefix != "" && suffix != "" {
+		return Money{}, ErrInvalidAmount
+	}
+
+	currency, err := resolveCurrency(prefix+suffix, locale)
+	if err != nil {
+		return Money{}, err
+	}
+
+	amount, err := parseAmount(input[first:last+1], locale, currency.Scale)
+	if err != nil {
+		return Money{}, err
+	}
+	if negative {
+		amount = -amount
+	}
+
+	return Money{
+		Amount:   amount,
+		Currency: currency,
+	}, nil
+}
+
+// resolveCurrency ищет валюту по коду или символу, неоднозначные символы
+// ("kr", "¥") разрешаются в пользу валюты локали
+func resolveCurrency(token string, locale Locale) (Currency, error) {
+	if token == "" {
+		return CurrencyByCode(locale.Currency)
+	}
+	if currency, err := CurrencyByCode(token); err == nil {
+		return currency, nil
+	}
+
+	candidates := Currencies.BySymbol(token)
+	switch len(candidates) {
+	case 0:
+		return Currency{}, fmt.Errorf("%w: %s", ErrUnknownCurrency, token)
+	case 1:
+		return candidates[0], nil
+	}
+
+	for _, candidate := range candidates {
+		if candidate.Code == locale.Currency {
+			return candidate, nil
+		}
+	}
+
+	return Currency{}, fmt.Errorf("%w: %s", ErrAmbiguousCurrency, token)
+}
+
+// parseAmount переводит запись числа в минимальные единицы валюты без округления
+func parseAmount(number string, locale Locale, scale int) (int64, error) {
+	integer, fraction := number, ""
+	if i := strings.Index(number, locale.Decimal); i >= 0 {
+		integer, fraction = number[:i], number[i+len(locale.Decimal):]
+	}
+	if len(fraction) > scale {
+		return 0, ErrTooManyDecimals
+	}
+
+	digits := strings.Builder{}
+	for _, r := range integer {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case string(r) == locale.Group || unicode.IsSpace(r):
+			// пробелы допустимы как разделители разрядов в любой локали
+		default:
+			return 0, ErrInvalidAmount
+		}
+	}
+	for _, r := range fraction {
+		if r < '0' || r > '9' {
+			return 0, ErrInvalidAmount
+		}
+	}
+	// "", "-" и "." не содержат ни одной цифры и суммой не являются
+	if digits.Len() == 0 && fraction == "" {
+		return 0, ErrInvalidAmount
+	}
+	digits.WriteString(fraction + strings.Repeat("0", scale-len(fraction)))
+
+	amount, ok := new(big.Int).SetString(digits.String(), 10)
+	if !ok {
+		return 0, ErrInvalidAmount
+	}
+	if !amount.IsInt64() {
+		return 0, ErrMoneyOverflow
+	}
+
+	return amount.Int64(), nil
+}
+
+func groupDigits(integer string, separator string) string {
+	if len(integer) <= 3 {
+		return integer
+	}
+
+	var groups []string
+	head := len(integer) % 3
+	if head > 0 {
+		groups = append(groups, integer[:head])
+	}
+	for i := head; i < len(integer); i += 3 {
+		groups = append(groups, integer[i:i+3])
+	}
+
+	return strings.Join(groups, separator)
+}
@@ -0,0 +1,150 @@
+package value_objects_test
+
+import (
+	"errors"
+	"testing"
+
+	value_objects "github.com/MaksimDzhangirov/PracticalDDD/value-objects"
+)
+
+func currency(t *testing.T, code string) value_objects.Currency {
+	t.Helper()
+
+	result, err := value_objects.CurrencyByCode(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return result
+}
+
+func TestMoneyFormat(t *testing.T) {
+	euro := value_objects.NewMoneyFromMinor(-123456, currency(t, "EUR"))
+	dollar := value_objects.NewMoneyFromMinor(123456, currency(t, "USD"))
+	yen := value_objects.NewMoneyFromMinor(1234567, currency(t, "JPY"))
+
+	tests := []struct {
+		money  value_objects.Money
+		locale value_objects.Locale
+		style  value_objects.FormatStyle
+		want   string
+	}{
+		{dollar, value_objects.LocaleEnUS, value_objects.FormatText, "$1,234.56"},
+		{dollar, value_objects.LocaleEnUS, value_objects.FormatHTML, "&#36;1,234.56"},
+		{euro, value_objects.LocaleDeDE, value_objects.FormatText, "-1.234,56 €"},
+		{euro, value_objects.LocaleDeDE, value_objects.FormatHTML, "-1.234,56&nbsp;&#8364;"},
+		{euro, value_objects.LocaleDeDE, value_objects.FormatAccounting, "(1.234,56 €)"},
+		{euro, value_objects.LocaleFrFR, value_objects.FormatText, "-1 234,56 €"},
+		{yen, value_objects.LocaleJaJP, value_objects.FormatText, "¥1,234,567"},
+	}
+	for _, test := range tests {
+		if got := test.money.Format(test.locale, test.style); got != test.want {
+			t.Fatalf("Format(%s, %d) = %q, want %q", test.locale.Tag, test.style, got, test.want)
+		}
+	}
+
+	if got := dollar.ToHTML(); got != "&#36;1,234.56" {
+		t.Fatalf("ToHTML() = %q", got)
+	}
+}
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		input  string
+		locale value_objects.Locale
+		amount int64
+		code   string
+	}{
+		{"1 234,56 €", value_objects.LocaleDeDE, 123456, "EUR"},
+		{"1.234,5", value_objects.LocaleDeDE, 123450, "EUR"},
+		{"-$1,234.56", value_objects.LocaleEnUS, -123456, "USD"},
+		{"($12)", value_objects.LocaleEnUS, -1200, "USD"},
+		{"GBP 7.05", value_objects.LocaleEnUS, 705, "GBP"},
+		// "¥" означает и JPY, и CNY: выбирается валюта локали
+		{"¥1,000", value_objects.LocaleJaJP, 1000, "JPY"},
+		{"10 kr", value_objects.Locale{Decimal: ",", Group: ".", Currency: "SEK"}, 1000, "SEK"},
+	}
+	for _, test := range tests {
+		money, err := value_objects.ParseMoney(test.input, test.locale)
+		if err != nil {
+			t.Fatalf("ParseMoney(%q): %v", test.input, err)
+		}
+		if money.Amount != test.amount || money.Currency.Code != test.code {
+			t.Fatalf("ParseMoney(%q) = %d %s, want %d %s", test.input, money.Amount, money.Currency.Code, test.amount, test.code)
+		}
+	}
+}
+
+func TestParseMoneyErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		locale value_objects.Locale
+		err    error
+	}{
+		{"", value_objects.LocaleEnUS, value_objects.ErrInvalidAmount},
+		{"-", value_objects.LocaleEnUS, value_objects.ErrInvalidAmount},
+		{".", value_objects.LocaleEnUS, value_objects.ErrInvalidAmount},
+		{"$", value_objects.LocaleEnUS, value_objects.ErrInvalidAmount},
+		{"$1.a5", value_objects.LocaleEnUS, value_objects.ErrInvalidAmount},
+		{"$1.234", value_objects.LocaleEnUS, value_objects.ErrTooManyDecimals},
+		{"¥1.5", value_objects.LocaleJaJP, value_objects.ErrTooManyDecimals},
+		{"10 kr", value_objects.LocaleEnUS, value_objects.ErrAmbiguousCurrency},
+		{"10 XYZ", value_objects.LocaleEnUS, value_objects.ErrUnknownCurrency},
+		{"$99999999999999999999", value_objects.LocaleEnUS, value_objects.ErrMoneyOverflow},
+	}
+	for _, test := range tests {
+		_, err := value_objects.ParseMoney(test.input, test.locale)
+		var parseErr *value_objects.MoneyParseError
+		if !errors.As(err, &parseErr) || parseErr.Input != test.input {
+			t.Fatalf("ParseMoney(%q) returned %v, want MoneyParseError", test.input, err)
+		}
+		if !errors.Is(err, test.err) {
+			t.Fatalf("ParseMoney(%q) returned %v, want %v", test.input, err, test.err)
+		}
+	}
+}
+
+func TestFormatAndParseRoundTrip(t *testing.T) {
+	locales := []value_objects.Locale{
+		value_objects.LocaleEnUS,
+		value_objects.LocaleEnGB,
+		value_objects.LocaleDeDE,
+		value_objects.LocaleFrFR,
+		value_objects.LocaleRuRU,
+		value_objects.LocaleJaJP,
+	}
+	codes := []string{"EUR", "USD", "GBP", "JPY", "RUB", "BHD"}
+	amounts := []int64{0, 5, -5, 1234567, -987654321}
+
+	for _, locale := range locales {
+		for _, code := range codes {
+			// "¥" вне японской локали неоднозначен (JPY и CNY)
+			if code == "JPY" && locale.Currency != "JPY" {
+				continue
+			}
+			for _, amount := range amounts {
+				money := value_objects.NewMoneyFromMinor(amount, currency(t, code))
+				for _, style := range []value_objects.FormatStyle{value_objects.FormatText, value_objects.FormatAccounting} {
+					text := money.Format(locale, style)
+					parsed, err := value_objects.ParseMoney(text, locale)
+					if err != nil {
+						t.Fatalf("ParseMoney(%q, %s): %v", text, locale.Tag, err)
+					}
+					if parsed.Amount != amount || parsed.Currency.Code != code {
+						t.Fatalf("%q (%s) parsed as %d %s, want %d %s", text, locale.Tag, parsed.Amount, parsed.Currency.Code, amount, code)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestLocaleByTag(t *testing.T) {
+	locale, err := value_objects.LocaleByTag("de-DE")
+	if err != nil || locale != value_objects.LocaleDeDE {
+		t.Fatalf("LocaleByTag(de-DE) = %+v, %v", locale, err)
+	}
+	if _, err := value_objects.LocaleByTag("xx-XX"); !errors.Is(err, value_objects.ErrUnknownLocale) {
+		t.Fatalf("unknown locale returned %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package value_objects_test
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+
+	value_objects "github.com/MaksimDzhangirov/PracticalDDD/value-objects"
+	"github.com/google/uuid"
+)
+
+var (
+	eur = value_objects.Currency{Code: "EUR", Scale: 2}
+	jpy = value_objects.Currency{Code: "JPY", Scale: 0}
+)
+
+func eurCents(amount int64) value_objects.Money {
+	return value_objects.NewMoneyFromMinor(amount, eur)
+}
+
+func TestMoneyAddAndDeduct(t *testing.T) {
+	// 0.1 + 0.2 во float64 не равно 0.3, в центах ошибки нет
+	sum, err := eurCents(10).Add(eurCents(20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sum.EqualTo(eurCents(30)) {
+		t.Fatalf("0.10 + 0.20 = %s, want 0.30", sum.Decimal())
+	}
+
+	rest, err := sum.Deduct(eurCents(30))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rest.Amount != 0 {
+		t.Fatalf("0.30 - 0.30 = %s, want 0.00", rest.Decimal())
+	}
+
+	if _, err := eurCents(10).Deduct(eurCents(11)); err == nil {
+		t.Fatal("deducting more than the amount succeeded")
+	}
+	if _, err := eurCents(10).Add(value_objects.NewMoneyFromMinor(10, jpy)); err == nil {
+		t.Fatal("adding different currencies succeeded")
+	}
+}
+
+func TestMoneyAddAndDeductDetectOverflow(t *testing.T) {
+	if _, err := eurCents(math.MaxInt64).Add(eurCents(1)); !errors.Is(err, value_objects.ErrMoneyOverflow) {
+		t.Fatalf("MaxInt64 + 1 returned %v, want ErrMoneyOverflow", err)
+	}
+	if _, err := eurCents(math.MinInt64).Add(eurCents(-1)); !errors.Is(err, value_objects.ErrMoneyOverflow) {
+		t.Fatalf("MinInt64 - 1 returned %v, want ErrMoneyOverflow", err)
+	}
+	if _, err := eurCents(0).Deduct(eurCents(math.MinInt64)); !errors.Is(err, value_objects.ErrMoneyOverflow) {
+		t.Fatalf("0 - MinInt64 returned %v, want ErrMoneyOverflow", err)
+	}
+}
+
+func TestMoneyMultiplyRounding(t *testing.T) {
+	tests := []struct {
+		amount int64
+		factor *big.Rat
+		mode   value_objects.RoundingMode
+		want   int64
+	}{
+		// 0.05 * 0.5 = 0.025
+		{5, big.NewRat(1, 2), value_objects.RoundHalfUp, 3},
+		{5, big.NewRat(1, 2), value_objects.RoundHalfEven, 2},
+		// 0.07 * 0.5 = 0.035
+		{7, big.NewRat(1, 2), value_objects.RoundHalfUp, 4},
+		{7, big.NewRat(1, 2), value_objects.RoundHalfEven, 4},
+		// половина у отрицательных сумм округляется от нуля и до чётного
+		{-5, big.NewRat(1, 2), value_objects.RoundHalfUp, -3},
+		{-5, big.NewRat(1, 2), value_objects.RoundHalfEven, -2},
+		// 19.99 с НДС 19% = 23.7881
+		{1999, big.NewRat(119, 100), value_objects.RoundHalfUp, 2379},
+	}
+	for _, test := range tests {
+		result, err := eurCents(test.amount).Multiply(test.factor, test.mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Amount != test.want {
+			t.Fatalf("%d * %s (mode %d) = %d, want %d", test.amount, test.factor, test.mode, result.Amount, test.want)
+		}
+	}
+
+	if _, err := eurCents(math.MaxInt64).Multiply(big.NewRat(2, 1), value_objects.RoundHalfUp); !errors.Is(err, value_objects.ErrMoneyOverflow) {
+		t.Fatalf("MaxInt64 * 2 returned %v, want ErrMoneyOverflow", err)
+	}
+}
+
+func TestMoneyDivide(t *testing.T) {
+	// 1.00 / 3 = 0.333...
+	third, err := eurCents(100).Divide(big.NewRat(3, 1), value_objects.RoundHalfEven)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third.Amount != 33 {
+		t.Fatalf("1.00 / 3 = %s, want 0.33", third.Decimal())
+	}
+
+	// 0.25 / 10 = 0.025
+	half, err := eurCents(25).Divide(big.NewRat(10, 1), value_objects.RoundHalfEven)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if half.Amount != 2 {
+		t.Fatalf("0.25 / 10 = %s, want 0.02", half.Decimal())
+	}
+
+	if _, err := eurCents(100).Divide(new(big.Rat), value_objects.RoundHalfUp); !errors.Is(err, value_objects.ErrDivisionByZero) {
+		t.Fatalf("division by zero returned %v", err)
+	}
+}
+
+func TestMoneyAllocateKeepsEveryCent(t *testing.T) {
+	parts, err := eurCents(100).Allocate(1, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []int64{34, 33, 33}
+	for i, part := range parts {
+		if part.Amount != want[i] {
+			t.Fatalf("part %d = %d, want %d", i, part.Amount, want[i])
+		}
+	}
+
+	for _, amount := range []int64{1, 99, 1001, -1001} {
+		parts, err := eurCents(amount).Allocate(70, 0, 20, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var total int64
+		for _, part := range parts {
+			total += part.Amount
+		}
+		if total != amount {
+			t.Fatalf("parts of %d sum to %d", amount, total)
+		}
+		if parts[1].Amount != 0 {
+			t.Fatalf("zero ratio received %d", parts[1].Amount)
+		}
+	}
+
+	if _, err := eurCents(100).Allocate(0, 0); !errors.Is(err, value_objects.ErrInvalidRatios) {
+		t.Fatalf("zero ratios returned %v", err)
+	}
+	if _, err := eurCents(100).Allocate(1, -1); !errors.Is(err, value_objects.ErrInvalidRatios) {
+		t.Fatalf("negative ratio returned %v", err)
+	}
+}
+
+func TestNewMoneyKeepsMajorUnits(t *testing.T) {
+	currencyID := uuid.New()
+	money := value_objects.NewMoney(100, currencyID)
+	if money.Amount != 100 || money.Currency.Scale != 0 || money.Currency.ID != currencyID {
+		t.Fatalf("NewMoney(100) = %+v", money)
+	}
+
+	money, err := value_objects.NewMoneyFromMajor(12, eur)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if money.Amount != 1200 || money.Decimal() != "12.00" {
+		t.Fatalf("12 EUR stored as %d (%s)", money.Amount, money.Decimal())
+	}
+
+	if _, err := value_objects.NewMoneyFromMajor(math.MaxInt64/10, eur); !errors.Is(err, value_objects.ErrMoneyOverflow) {
+		t.Fatalf("overflowing major amount returned %v", err)
+	}
+}
@@ -0,0 +1,258 @@
+package value_objects
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/clock"
+)
+
+var (
+	ErrRequired      = errors.New("value is required")
+	ErrInvalidFormat = errors.New("invalid format")
+	ErrInFuture      = errors.New("date is in the future")
+	ErrTooOld        = errors.New("date is implausibly old")
+)
+
+// ValidationError сообщает, какое поле не прошло проверку, чтобы обработчики API
+// могли показать сообщение рядом с этим полем
+type ValidationError struct {
+	Field string
+	Value string
+	Err   error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s %q: %s", e.Field, e.Value, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+func invalid(field, value string, err error) error {
+	return &ValidationError{Field: field, Value: value, Err: err}
+}
+
+// postcodeFormat - допустимый формат почтового индекса и его каноническая запись
+type postcodeFormat struct {
+	pattern   *regexp.Regexp
+	normalize func(postcode string) string
+}
+
+var (
+	compact = func(postcode string) string {
+		return strings.NewReplacer(" ", "", "-", "").Replace(postcode)
+	}
+	splitAt = func(i int, separator string) func(string) string {
+		return func(postcode string) string {
+			postcode = compact(postcode)
+			return postcode[:i] + separator + postcode[i:]
+		}
+	}
+	genericPostcode = postcodeFormat{
+		pattern:   regexp.MustCompile(`^[A-Z0-9][A-Z0-9 -]{1,9}$`),
+		normalize: strings.TrimSpace,
+	}
+)
+
+// postcodeFormats - форматы почтовых индексов по кодам стран ISO 3166-1
+var postcodeFormats = map[string]postcodeFormat{
+	"AT": {regexp.MustCompile(`^\d{4}$`), compact},
+	"BE": {regexp.MustCompile(`^\d{4}$`), compact},
+	"CA": {regexp.MustCompile(`^[A-Z]\d[A-Z] ?\d[A-Z]\d$`), splitAt(3, " ")},
+	"CH": {regexp.MustCompile(`^\d{4}$`), compact},
+	"DE": {regexp.MustCompile(`^\d{5}$`), compact},
+	"DK": {regexp.MustCompile(`^\d{4}$`), compact},
+	"ES": {regexp.MustCompile(`^\d{5}$`), compact},
+	"FR": {regexp.MustCompile(`^\d{5}$`), compact},
+	"GB": {regexp.MustCompile(`^[A-Z]{1,2}\d[A-Z\d]? ?\d[A-Z]{2}$`), func(postcode string) string {
+		postcode = compact(postcode)
+		return postcode[:len(postcode)-3] + " " + postcode[len(postcode)-3:]
+	}},
+	"IT": {regexp.MustCompile(`^\d{5}$`), compact},
+	"JP": {regexp.MustCompile(`^\d{3}-?\d{4}$`), splitAt(3, "-")},
+	"NL": {regexp.MustCompile(`^\d{4} ?[A-Z]{2}$`), splitAt(4, " ")},
+	"NO": {regexp.MustCompile(`^\d{4}$`), compact},
+	"PL": {regexp.MustCompile(`^\d{2}-?\d{3}$`), splitAt(2, "-")},
+	"RU": {regexp.MustCompile(`^\d{6}$`), compact},
+	"SE": {regexp.MustCompile(`^\d{3} ?\d{2}$`), splitAt(3, " ")},
+	"US": {regexp.MustCompile(`^\d{5}(-\d{4})?$`), strings.TrimSpace},
+}
+
+var streetNumber = regexp.MustCompile(`^(\d+)\s*([A-Za-z]?(?:[/-]\d+)?)$`)
+
+// NewAddress проверяет и нормализует адрес: номер дома "12a" разбирается
+// на номер 12 и суффикс "A", индекс приводится к формату страны
+func NewAddress(country, street, number, postcode string) (Address, error) {
+	country = strings.ToUpper(strings.TrimSpace(country))
+	if len(country) != 2 {
+		return Address{}, invalid("country", country, ErrInvalidFormat)
+	}
+
+	street = strings.Join(strings.Fields(street), " ")
+	if street == "" {
+		return Address{}, invalid("street", street, ErrRequired)
+	}
+
+	parsedNumber, suffix, err := parseStreetNumber(number)
+	if err != nil {
+		return Address{}, err
+	}
+
+	normalized, err := normalizePostcode(country, postcode)
+	if err != nil {
+		return Address{}, err
+	}
+
+	return Address{
+		Street:   street,
+		Number:   parsedNumber,
+		Suffix:   suffix,
+		Postcode: normalized,
+		Country:  country,
+	}, nil
+}
+
+func parseStreetNumber(number string) (int, string, error) {
+	number = strings.TrimSpace(number)
+	if number == "" {
+		return 0, "", invalid("number", number, ErrRequired)
+	}
+
+	matches := streetNumber.FindStringSubmatch(number)
+	if matches == nil {
+		return 0, "", invalid("number", number, ErrInvalidFormat)
+	}
+
+	parsed, err := strconv.Atoi(matches[1])
+	if err != nil || parsed <= 0 {
+		return 0, "", invalid("number", number, ErrInvalidFormat)
+	}
+
+	return parsed, strings.ToUpper(matches[2]), nil
+}
+
+func normalizePostcode(country, postcode string) (string, error) {
+	postcode = strings.ToUpper(strings.TrimSpace(postcode))
+	if postcode == "" {
+		return "", invalid("postcode", postcode, ErrRequired)
+	}
+
+	format, ok := postcodeFormats[country]
+	if !ok {
+		format = genericPostcode
+	}
+	if !format.pattern.MatchString(postcode) {
+		return "", invalid("postcode", postcode, ErrInvalidFormat)
+	}
+
+	return format.normalize(postcode), nil
+}
+
+// callingCodes - двузначные коды стран; коды 1 и 7 однозначные, остальные трёхзначные
+var callingCodes = map[string]bool{
+	"20": true, "27": true, "30": true, "31": true, "32": true, "33": true, "34": true,
+	"36": true, "39": true, "40": true, "41": true, "43": true, "44": true, "45": true,
+	"46": true, "47": true, "48": true, "49": true, "51": true, "52": true, "53": true,
+	"54": true, "55": true, "56": true, "57": true, "58": true, "60": true, "61": true,
+	"62": true, "63": true, "64": true, "65": true, "66": true, "81": true, "82": true,
+	"84": true, "86": true, "90": true, "91": true, "92": true, "93": true, "94": true,
+	"95": true, "98": true,
+}
+
+// areaCodeLengths - длина кода города для стран с фиксированной длиной
+var areaCodeLengths = map[string]int{
+	"1":  3,
+	"7":  3,
+	"33": 1,
+	"55": 2,
+	"61": 1,
+}
+
+// ParsePhone разбирает номер в формате E.164 ("+49 (0)30 123-456", "0049...")
+// и выделяет код страны и, если он известен для страны, код города
+func ParsePhone(input string) (Phone, error) {
+	value := strings.TrimSpace(input)
+	value = strings.Replace(value, "(0)", "", 1)
+	if strings.HasPrefix(value, "00") {
+		value = "+" + value[2:]
+	}
+	if !strings.HasPrefix(value, "+") {
+		return Phone{}, invalid("phone", input, ErrInvalidFormat)
+	}
+
+	digits := strings.Builder{}
+	for _, r := range value[1:] {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case strings.ContainsRune(" -.()/", r):
+		default:
+			return Phone{}, invalid("phone", input, ErrInvalidFormat)
+		}
+	}
+
+	number := digits.String()
+	if len(number) < 7 || len(number) > 15 || number[0] == '0' {
+		return Phone{}, invalid("phone", input, ErrInvalidFormat)
+	}
+
+	prefixLength := 3
+	switch {
+	case number[0] == '1' || number[0] == '7':
+		prefixLength = 1
+	case callingCodes[number[:2]]:
+		prefixLength = 2
+	}
+
+	phone := Phone{
+		CountryPrefix: number[:prefixLength],
+		Number:        number[prefixLength:],
+	}
+	if length, ok := areaCodeLengths[phone.CountryPrefix]; ok && len(phone.Number) > length {
+		phone.AreaCode, phone.Number = phone.Number[:length], phone.Number[length:]
+	}
+
+	return phone, nil
+}
+
+// validate проверяет номер, полученный не через ParsePhone
+func (p Phone) validate() error {
+	text, _ := p.MarshalText()
+	if p.CountryPrefix == "" || p.Number == "" {
+		return invalid("phone", string(text), ErrRequired)
+	}
+	for _, part := range []string{p.CountryPrefix, p.AreaCode, p.Number} {
+		if strings.Trim(part, "0123456789") != "" {
+			return invalid("phone", string(text), ErrInvalidFormat)
+		}
+	}
+	if len(p.CountryPrefix+p.AreaCode+p.Number) > 15 {
+		return invalid("phone", string(text), ErrInvalidFormat)
+	}
+	return nil
+}
+
+// maxAge - возраст, старше которого дата рождения считается ошибкой ввода
+const maxAge = 130
+
+// NewBirthday отбрасывает время и проверяет, что дата не в будущем и правдоподобна
+func NewBirthday(date time.Time, clock clock.Clock) (Birthday, error) {
+	date = dateOf(date)
+
+	// сравниваем даты, а не моменты времени: родившийся сегодня не в будущем
+	now := dateOf(clock.Now())
+	if date.After(now) {
+		return Birthday{}, invalid("birthday", date.Format(birthdayLayout), ErrInFuture)
+	}
+	if date.Before(now.AddDate(-maxAge, 0, 0)) {
+		return Birthday{}, invalid("birthday", date.Format(birthdayLayout), ErrTooOld)
+	}
+
+	return Birthday(date), nil
+}
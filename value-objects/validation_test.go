@@ -0,0 +1,118 @@
+package value_objects_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/MaksimDzhangirov/PracticalDDD/clock"
+	value_objects "github.com/MaksimDzhangirov/PracticalDDD/value-objects"
+)
+
+func TestNewAddressNormalizes(t *testing.T) {
+	tests := []struct {
+		country, street, number, postcode string
+		want                              value_objects.Address
+	}{
+		{"de", "  Unter   den Linden ", " 12 a", "10117",
+			value_objects.Address{Street: "Unter den Linden", Number: 12, Suffix: "A", Postcode: "10117", Country: "DE"}},
+		{"GB", "Baker Street", "221b", "nw16xe",
+			value_objects.Address{Street: "Baker Street", Number: 221, Suffix: "B", Postcode: "NW1 6XE", Country: "GB"}},
+		{"NL", "Damrak", "1-3", "1012lg",
+			value_objects.Address{Street: "Damrak", Number: 1, Suffix: "-3", Postcode: "1012 LG", Country: "NL"}},
+		{"JP", "Chiyoda", "1", "1000001",
+			value_objects.Address{Street: "Chiyoda", Number: 1, Postcode: "100-0001", Country: "JP"}},
+		{"US", "Main Street", "5", "12345-6789",
+			value_objects.Address{Street: "Main Street", Number: 5, Postcode: "12345-6789", Country: "US"}},
+		// страны без известного формата проверяются общим шаблоном
+		{"BR", "Avenida Paulista", "1578", "01310-200",
+			value_objects.Address{Street: "Avenida Paulista", Number: 1578, Postcode: "01310-200", Country: "BR"}},
+	}
+	for _, test := range tests {
+		address, err := value_objects.NewAddress(test.country, test.street, test.number, test.postcode)
+		if err != nil {
+			t.Fatalf("NewAddress(%s, %q): %v", test.country, test.postcode, err)
+		}
+		if address != test.want {
+			t.Fatalf("NewAddress(%s) = %+v, want %+v", test.country, address, test.want)
+		}
+	}
+}
+
+func TestNewAddressReportsField(t *testing.T) {
+	tests := []struct {
+		country, street, number, postcode string
+		field                             string
+		err                               error
+	}{
+		{"DEU", "Street", "1", "10117", "country", value_objects.ErrInvalidFormat},
+		{"DE", "  ", "1", "10117", "street", value_objects.ErrRequired},
+		{"DE", "Street", "", "10117", "number", value_objects.ErrRequired},
+		{"DE", "Street", "0", "10117", "number", value_objects.ErrInvalidFormat},
+		{"DE", "Street", "twelve", "10117", "number", value_objects.ErrInvalidFormat},
+		{"DE", "Street", "1", "", "postcode", value_objects.ErrRequired},
+		{"DE", "Street", "1", "1011", "postcode", value_objects.ErrInvalidFormat},
+		{"GB", "Street", "1", "12345", "postcode", value_objects.ErrInvalidFormat},
+	}
+	for _, test := range tests {
+		_, err := value_objects.NewAddress(test.country, test.street, test.number, test.postcode)
+		var validation *value_objects.ValidationError
+		if !errors.As(err, &validation) || validation.Field != test.field || !errors.Is(err, test.err) {
+			t.Fatalf("NewAddress(%q, %q, %q, %q) returned %v, want %s: %v",
+				test.country, test.street, test.number, test.postcode, err, test.field, test.err)
+		}
+	}
+}
+
+func TestParsePhone(t *testing.T) {
+	tests := []struct {
+		input string
+		want  value_objects.Phone
+	}{
+		{"+49 30 1234567", value_objects.Phone{CountryPrefix: "49", Number: "301234567"}},
+		{"0049 (0)30 123-4567", value_objects.Phone{CountryPrefix: "49", Number: "301234567"}},
+		{"+1 (212) 555-0100", value_objects.Phone{CountryPrefix: "1", AreaCode: "212", Number: "5550100"}},
+		{"+7 495 123 45 67", value_objects.Phone{CountryPrefix: "7", AreaCode: "495", Number: "1234567"}},
+		{"+33 1 23 45 67 89", value_objects.Phone{CountryPrefix: "33", AreaCode: "1", Number: "23456789"}},
+		{"+353 1 234 5678", value_objects.Phone{CountryPrefix: "353", Number: "12345678"}},
+	}
+	for _, test := range tests {
+		phone, err := value_objects.ParsePhone(test.input)
+		if err != nil {
+			t.Fatalf("ParsePhone(%q): %v", test.input, err)
+		}
+		if phone != test.want {
+			t.Fatalf("ParsePhone(%q) = %+v, want %+v", test.input, phone, test.want)
+		}
+	}
+
+	for _, input := range []string{"", "030 1234567", "+49 30 12x4567", "+123", "+0 123456789", "+49 1234567890123456"} {
+		var validation *value_objects.ValidationError
+		if _, err := value_objects.ParsePhone(input); !errors.As(err, &validation) || !errors.Is(err, value_objects.ErrInvalidFormat) {
+			t.Fatalf("ParsePhone(%q) returned %v", input, err)
+		}
+	}
+}
+
+func TestNewBirthday(t *testing.T) {
+	now := clock.Fixed(time.Date(2024, time.March, 10, 23, 30, 0, 0, time.UTC))
+
+	// время отбрасывается, родившийся сегодня не в будущем
+	birthday, err := value_objects.NewBirthday(time.Date(2024, time.March, 10, 23, 59, 0, 0, time.UTC), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !time.Time(birthday).Equal(time.Date(2024, time.March, 10, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("birthday = %v, want the date without time", time.Time(birthday))
+	}
+
+	if _, err := value_objects.NewBirthday(time.Date(2024, time.March, 11, 0, 0, 0, 0, time.UTC), now); !errors.Is(err, value_objects.ErrInFuture) {
+		t.Fatalf("tomorrow returned %v", err)
+	}
+	if _, err := value_objects.NewBirthday(time.Date(1894, time.March, 10, 0, 0, 0, 0, time.UTC), now); err != nil {
+		t.Fatalf("130 years ago returned %v", err)
+	}
+	if _, err := value_objects.NewBirthday(time.Date(1894, time.March, 9, 0, 0, 0, 0, time.UTC), now); !errors.Is(err, value_objects.ErrTooOld) {
+		t.Fatalf("more than 130 years ago returned %v", err)
+	}
+}
@@ -3,8 +3,9 @@ package value_objects
 import (
 	"errors"
 	"fmt"
+	"github.com/MaksimDzhangirov/PracticalDDD/clock"
 	"github.com/google/uuid"
-	"math"
+	"math/big"
 	"time"
 )
 
@@ -16,18 +17,25 @@ import (
 
 // Сущность в сервисе Payment
 type Currency struct {
-	ID   uuid.UUID
-	Code string
-	HTML int
-}
-
+	ID      uuid.UUID
+	Code    string
+	Numeric int
+	Name    string
+	Symbol  string
+	HTML    int
+	// Scale - количество знаков после запятой (2 для EUR, 0 для JPY, 8 для BTC)
+	Scale int
+}
+
+// Money хранит сумму в минимальных единицах валюты (центах, сатоши),
+// поэтому арифметические операции не накапливают ошибки округления
 type Money struct {
-	Value    float64
+	Amount   int64
 	Currency Currency
 }
 
 func (m Money) ToHTML() string {
-	return fmt.Sprintf(`%.2f %d`, m.Value, m.Currency.HTML)
+	return m.Format(LocaleEnUS, FormatHTML)
 }
 
 type Salutation string
@@ -40,32 +48,42 @@ type Color struct {
 	Red   byte
 	Green byte
 	Blue  byte
+	// Transparency - прозрачность, 0 означает непрозрачный цвет
+	Transparency byte
 }
 
 func (c Color) ToCSS() string {
-	return fmt.Sprintf(`rgb(%d, %d, %d`, c.Red, c.Green, c.Blue)
+	if c.Transparency == 0 {
+		return fmt.Sprintf(`rgb(%d, %d, %d)`, c.Red, c.Green, c.Blue)
+	}
+	return fmt.Sprintf(`rgba(%d, %d, %d, %s)`, c.Red, c.Green, c.Blue, formatFloat(c.Alpha()))
 }
 
 // проверяем на равенство Объекты-значения
 func (c Color) EqualTo(other Color) bool {
-	return c.Red == other.Red && c.Green == other.Green && c.Blue == other.Blue
+	return c == other
 }
 
 // проверяем на равенство Объекты-значения
 func (m Money) EqualTo(other Money) bool {
-	return m.Value == other.Value && m.Currency.EqualTo(other.Currency)
+	return m.Amount == other.Amount && m.Currency.EqualTo(other.Currency)
 }
 
-// проверяем на равенство Сущности
+// проверяем на равенство Сущности, способ сравнения задаётся SetCurrencyComparison
 func (c Currency) EqualTo(other Currency) bool {
-	return c.ID.String() == other.ID.String()
+	if currencyComparison() == CompareByCode || c.ID == uuid.Nil || other.ID == uuid.Nil {
+		return c.Code == other.Code
+	}
+	return c.ID == other.ID
 }
 
 type Address struct {
 	Street   string
 	Number   int
 	Suffix   string
-	Postcode int
+	Postcode string
+	// Country - код страны ISO 3166-1, например "DE"
+	Country string
 }
 
 type Phone struct {
@@ -87,27 +105,31 @@ func (b Birthday) IsYoungerThen(other time.Time) bool {
 	return time.Time(b).After(other)
 }
 
-func (b Birthday) IsAdult() bool {
-	return time.Time(b).AddDate(18, 0, 0).Before(time.Now())
+func (b Birthday) IsAdult(clock clock.Clock) bool {
+	return b.Age(clock.Now()) >= DefaultAgeOfMajority
 }
 
 const (
-	Freelancer = iota
+	Freelancer LegalForm = iota
 	Partnership
 	LLC
 	Corporation
+	GmbH
+	UG
+	AG
+	SARL
+	SAS
+	SA
+	Ltd
+	PLC
+	OOO
+	AO
+	IP
 )
 
+// LegalForm - организационно-правовая форма, её свойства описаны в каталоге legalForms
 type LegalForm int
 
-func (s LegalForm) IsIndividual() bool {
-	return s == Freelancer
-}
-
-func (s LegalForm) HasLimitedResponsibility() bool {
-	return s == LLC || s == Corporation
-}
-
 type Customer struct {
 	ID        uuid.UUID
 	Name      string
@@ -115,18 +137,27 @@ type Customer struct {
 	Date      time.Time
 }
 
-func (c Customer) ToPerson() Person {
+func (c Customer) ToPerson() (Person, error) {
+	if !c.LegalForm.IsIndividual() {
+		return Person{}, fmt.Errorf("%w: %s customer is a company", ErrInvalidLegalForm, c.LegalForm)
+	}
+
 	return Person{
 		FullName: c.Name,
 		Birthday: Birthday(c.Date),
-	}
+	}, nil
 }
 
-func (c Customer) ToCompany() Company {
+func (c Customer) ToCompany() (Company, error) {
+	if c.LegalForm.IsIndividual() {
+		return Company{}, fmt.Errorf("%w: %s customer is a person", ErrInvalidLegalForm, c.LegalForm)
+	}
+
 	return Company{
 		Name:         c.Name,
+		LegalForm:    c.LegalForm,
 		CreationDate: c.Date,
-	}
+	}, nil
 }
 
 type Person struct {
@@ -136,31 +167,32 @@ type Person struct {
 
 type Company struct {
 	Name         string
+	LegalForm    LegalForm
 	CreationDate time.Time
 }
 
 // Неправильно. Состояние изменяется внутри объекта-значения
-func (m Money) AddAmount(amount float64) {
-	m.Value += amount
+func (m Money) AddAmount(amount int64) {
+	m.Amount += amount
 }
 
 // Правильно. Возвращаем новый объект-значение с новым состоянием
-func (m Money) WithAmount(amount float64) Money {
+func (m Money) WithAmount(amount int64) Money {
 	return Money{
-		Value:    m.Value + amount,
+		Amount:   m.Amount + amount,
 		Currency: m.Currency,
 	}
 }
 
 // Неправильно. Состояние изменяется внутри объекта-значения
 //func (m Money) Deduct(other Money) {
-//	m.Value -= other.Value
+//	m.Amount -= other.Amount
 //}
 
 // Правильно. Возвращаем новый объект-значение с новым состоянием
 func (m Money) DeductedWith(other Money) Money {
 	return Money{
-		Value:    m.Value - other.Value,
+		Amount:   m.Amount - other.Amount,
 		Currency: m.Currency,
 	}
 }
@@ -174,28 +206,59 @@ func (c *Color) KeepOnlyGreen() {
 // Правильно. Возвращаем новый объект-значение с новым состоянием
 func (c Color) WithOnlyGreen() Color {
 	return Color{
-		Red:   0,
-		Green: c.Green,
-		Blue:  0,
+		Red:          0,
+		Green:        c.Green,
+		Blue:         0,
+		Transparency: c.Transparency,
 	}
 }
 
+// NewMoney создаёт сумму в основных единицах валюты, для которой известен
+// только идентификатор. Масштаб такой валюты неизвестен и равен 0, поэтому
+// сумма хранится как есть; если валюта известна, используйте NewMoneyFromMajor
 func NewMoney(amount int, currencyID uuid.UUID) Money {
 	return Money{
-		Value:    float64(amount),
+		Amount: int64(amount),
 		Currency: Currency{
 			ID: currencyID,
 		},
 	}
 }
 
+// NewMoneyFromMinor создаёт сумму в минимальных единицах валюты
+func NewMoneyFromMinor(amount int64, currency Currency) Money {
+	return Money{
+		Amount:   amount,
+		Currency: currency,
+	}
+}
+
+// NewMoneyFromMajor создаёт сумму в основных единицах валюты, переводя её
+// в минимальные согласно Scale: 12 EUR хранятся как 1200
+func NewMoneyFromMajor(amount int64, currency Currency) (Money, error) {
+	minor := new(big.Int).Mul(big.NewInt(amount), new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(currency.Scale)), nil))
+	if !minor.IsInt64() {
+		return Money{}, ErrMoneyOverflow
+	}
+
+	return Money{
+		Amount:   minor.Int64(),
+		Currency: currency,
+	}, nil
+}
+
 func (m Money) Add(other Money) (Money, error) {
 	if !m.Currency.EqualTo(other.Currency) {
 		return Money{}, errors.New("currencies must be identical")
 	}
 
+	sum := m.Amount + other.Amount
+	if (other.Amount > 0 && sum < m.Amount) || (other.Amount < 0 && sum > m.Amount) {
+		return Money{}, ErrMoneyOverflow
+	}
+
 	return Money{
-		Value:    m.Value + other.Value,
+		Amount:   sum,
 		Currency: m.Currency,
 	}, nil
 }
@@ -205,47 +268,40 @@ func (m Money) Deduct(other Money) (Money, error) {
 		return Money{}, errors.New("currencies must be identical")
 	}
 
-	if other.Value > m.Value {
+	if other.Amount > m.Amount {
 		return Money{}, errors.New("there is not enough amount to deduct")
 	}
+
+	diff := m.Amount - other.Amount
+	if (other.Amount > 0 && diff > m.Amount) || (other.Amount < 0 && diff < m.Amount) {
+		return Money{}, ErrMoneyOverflow
+	}
+
 	return Money{
-		Value:    m.Value - other.Value,
+		Amount:   diff,
 		Currency: m.Currency,
 	}, nil
 }
 
+// считаем во float64, чтобы byte не переполнялся до ограничения диапазона
 func (c Color) ToBrighter() Color {
 	return Color{
-		Red:   byte(math.Min(255, float64(c.Red+10))),
-		Green: byte(math.Min(255, float64(c.Green+10))),
-		Blue:  byte(math.Min(255, float64(c.Blue+10))),
+		Red:          clampByte(float64(c.Red) + 10),
+		Green:        clampByte(float64(c.Green) + 10),
+		Blue:         clampByte(float64(c.Blue) + 10),
+		Transparency: c.Transparency,
 	}
 }
 
 func (c Color) ToDarker() Color {
 	return Color{
-		Red:   byte(math.Max(255, float64(c.Red-10))),
-		Green: byte(math.Max(255, float64(c.Green-10))),
-		Blue:  byte(math.Max(255, float64(c.Blue-10))),
+		Red:          clampByte(float64(c.Red) - 10),
+		Green:        clampByte(float64(c.Green) - 10),
+		Blue:         clampByte(float64(c.Blue) - 10),
+		Transparency: c.Transparency,
 	}
 }
 
 func (c Color) Combine(other Color) Color {
-	return Color{
-		Red:   byte(math.Min(255, float64(c.Red+other.Red))),
-		Green: byte(math.Min(255, float64(c.Green+other.Green))),
-		Blue:  byte(math.Min(255, float64(c.Blue+other.Blue))),
-	}
+	return c.Blend(other, BlendAdd)
 }
-
-func (c Color) IsRed() bool {
-	return c.Red == 255 && c.Green == 0 && c.Blue == 0
-}
-
-func (c Color) IsYellow() bool {
-	return c.Red == 255 && c.Green == 255 && c.Blue == 0
-}
-
-func (c Color) IsMagenta() bool {
-	return c.Red == 255 && c.Green == 0 && c.Blue == 255
-}
\ No newline at end of file